@@ -0,0 +1,97 @@
+// Package awattar is a client of the EPEX Spot day-ahead prices of Germany
+// and Austria published by aWATTar, https://www.awattar.de/services/api.
+package awattar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/buger/jsonparser"
+)
+
+// Hosts are the API hosts of the markets.
+var Hosts = map[string]string{
+	"DE": "api.awattar.de",
+	"AT": "api.awattar.at",
+}
+
+// Upstream is the name of the API of the market given to the Transport,
+// awattar-de or awattar-at.
+func Upstream(market string) string {
+	return "awattar-" + strings.ToLower(market)
+}
+
+// ErrNoPrices is returned when the prices for a day are not published yet,
+// the next day's prices are published around 14:00 CET.
+var ErrNoPrices = errors.New("prices not published")
+
+// Transport gets the URL from the named upstream and returns the status
+// and body, how is up to the program, e.g. Fastly backends with retries.
+type Transport interface {
+	Get(ctx context.Context, upstream, url string) (int, []byte, error)
+}
+
+// Client fetches prices through the Transport.
+type Client struct {
+	Transport Transport
+}
+
+// Price is the price of the hour starting at Start.
+type Price struct {
+	Start     time.Time
+	EURPerMWh float64
+}
+
+// URL returns the URL of the prices of the market from start to end, e.g.
+// https://api.awattar.de/v1/marketdata?start=1676415600000&end=1676502000000.
+func URL(market string, start, end time.Time) string {
+	return fmt.Sprintf("https://%s/v1/marketdata?start=%d&end=%d", Hosts[market], start.UnixMilli(), end.UnixMilli())
+}
+
+// Day fetches the prices of the market on the day, from midnight to
+// midnight in the location of the day.
+func (c *Client) Day(ctx context.Context, market string, day time.Time) ([]Price, error) {
+	if _, ok := Hosts[market]; !ok {
+		return nil, fmt.Errorf("unknown market %q", market)
+	}
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	status, body, err := c.Transport.Get(ctx, Upstream(market), URL(market, start, start.AddDate(0, 0, 1)))
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		return nil, fmt.Errorf("awattar responded with status %d", status)
+	}
+	prices := Parse(body)
+	if len(prices) == 0 {
+		return nil, ErrNoPrices
+	}
+	return prices, nil
+}
+
+// Parse parses a marketdata response into hourly prices, the start times
+// are in UTC. Prices of shorter periods are averaged over the hour.
+func Parse(body []byte) []Price {
+	sums := map[time.Time]float64{}
+	counts := map[time.Time]int{}
+	jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		ms, err1 := jsonparser.GetInt(value, "start_timestamp")
+		price, err2 := jsonparser.GetFloat(value, "marketprice")
+		if err1 != nil || err2 != nil {
+			return
+		}
+		h := time.UnixMilli(ms).UTC().Truncate(time.Hour)
+		sums[h] += price
+		counts[h]++
+	}, "data")
+	prices := make([]Price, 0, len(sums))
+	for h, s := range sums {
+		prices = append(prices, Price{Start: h, EURPerMWh: s / float64(counts[h])})
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Start.Before(prices[j].Start) })
+	return prices
+}
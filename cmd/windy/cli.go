@@ -0,0 +1,36 @@
+//go:build !tinygo
+
+// Command windy is the Fastly Compute service serving the wind forecasts
+// and electricity prices, built with TinyGo. Built with Go it is a command
+// line tool instead, printing the forecast in the terminal or, built with
+// -tags nethttp, serving the handlers as a plain Go server.
+//
+//	windy forecast [-lat 55.67 -long 13.06] [-spark]
+//	windy server [-addr :8080]
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+const usage = "usage: windy forecast [flags] | windy server [flags]"
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+	var err error
+	switch os.Args[1] {
+	case "forecast":
+		err = forecast(os.Stdout, os.Args[2:])
+	case "server":
+		err = server(os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown subcommand %q, %s", os.Args[1], usage)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
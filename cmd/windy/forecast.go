@@ -0,0 +1,168 @@
+//go:build !tinygo
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"text/tabwriter"
+	"time"
+
+	"compute-starter-kit-go/elpris"
+	"compute-starter-kit-go/merge"
+	"compute-starter-kit-go/openmeteo"
+	"compute-starter-kit-go/render"
+)
+
+// httpTransport gets the URLs with net/http.
+type httpTransport struct{}
+
+func (httpTransport) Get(ctx context.Context, upstream, url string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("User-Agent", "windy")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, body, err
+}
+
+// units are the wind speed units, converted from m/s.
+var units = map[string]float64{"ms": 1, "kn": 1.943844, "kmh": 3.6, "mph": 2.236936}
+
+// row is an hour of the forecast with the price, if published.
+type row struct {
+	hour     openmeteo.Hour
+	price    float64
+	hasPrice bool
+}
+
+// forecast prints the forecast of the coordinates as a table, or as
+// sparklines with -spark.
+func forecast(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("forecast", flag.ContinueOnError)
+	lat := fs.String("lat", "55.67", "latitude")
+	long := fs.String("long", "13.06", "longitude")
+	region := fs.String("region", "SE4", "electricity price area, SE1-SE4")
+	hours := fs.Int("hours", 24, "number of hours, 1-384")
+	height := fs.Int("height", 10, "height of the wind in meters, 10, 80, 120 or 180")
+	unit := fs.String("unit", "ms", "wind speed unit, ms, kn, kmh or mph")
+	spark := fs.Bool("spark", false, "print sparklines instead of a table")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	factor, ok := units[*unit]
+	if !ok {
+		return fmt.Errorf("unknown unit %q", *unit)
+	}
+	if *hours < 1 || *hours > 16*24 {
+		return fmt.Errorf("hours must be 1-384, got %d", *hours)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	rows, err := fetchRows(ctx, *lat, *long, *region, *height, *hours)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return errors.New("no forecast")
+	}
+	label := *unit
+	if label == "ms" {
+		label = "m/s"
+	}
+	if *spark {
+		printSparklines(w, rows, factor, label)
+		return nil
+	}
+	return printTable(w, rows, factor, label)
+}
+
+// fetchRows fetches the forecast and the prices of today and tomorrow and
+// returns the hours from now.
+func fetchRows(ctx context.Context, lat, long, region string, height, hours int) ([]*row, error) {
+	days := hours/24 + 2
+	if days > 16 {
+		days = 16
+	}
+	f, err := (&openmeteo.Client{Transport: httpTransport{}}).Forecast(ctx, openmeteo.Query{
+		Latitude: lat, Longitude: long, Days: days, Height: height,
+	})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Truncate(time.Hour)
+	rows := []*row{}
+	for _, h := range f.Hours {
+		if !h.Time.Before(now) && len(rows) < hours {
+			h.Time = h.Time.In(f.Timezone)
+			rows = append(rows, &row{hour: h})
+		}
+	}
+	prices := []elpris.Price{}
+	client := &elpris.Client{Transport: httpTransport{}}
+	for _, day := range []time.Time{now, now.AddDate(0, 0, 1)} {
+		p, err := client.Day(ctx, region, day)
+		if errors.Is(err, elpris.ErrNoPrices) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, p...)
+	}
+	merge.ByHour(rows, prices, func(r *row) time.Time { return r.hour.Time }, func(p elpris.Price) time.Time { return p.Start }, func(r *row, p elpris.Price) {
+		r.price = p.SEKPerKWh
+		r.hasPrice = true
+	})
+	return rows, nil
+}
+
+// printTable prints the hours aligned in columns.
+func printTable(w io.Writer, rows []*row, factor float64, unit string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "hour\twind %s\tgust %s\tdir\ttemp °C\tprice SEK/kWh\t\n", unit, unit)
+	for _, r := range rows {
+		price := "-"
+		if r.hasPrice {
+			price = fmt.Sprintf("%.2f", r.price)
+		}
+		fmt.Fprintf(tw, "%s\t%.1f\t%.1f\t%s %s\t%.1f\t%s\t\n",
+			r.hour.Time.Format("Mon 15:04"), r.hour.Speed*factor, r.hour.Gust*factor,
+			render.Compass(r.hour.Direction), render.Arrow(r.hour.Direction), r.hour.Temperature, price)
+	}
+	return tw.Flush()
+}
+
+// printSparklines prints the wind, the gusts and the prices as a line each.
+func printSparklines(w io.Writer, rows []*row, factor float64, unit string) {
+	speeds, gusts, prices := []float64{}, []float64{}, []float64{}
+	for _, r := range rows {
+		speeds = append(speeds, r.hour.Speed*factor)
+		gusts = append(gusts, r.hour.Gust*factor)
+		if r.hasPrice {
+			prices = append(prices, r.price)
+		}
+	}
+	start, end := rows[0].hour.Time, rows[len(rows)-1].hour.Time
+	fmt.Fprintf(w, "%s - %s\n", start.Format("Mon 15:04"), end.Format("Mon 15:04"))
+	fmt.Fprintf(w, "wind   %s  %s %s\n", render.Sparkline(speeds), valueRange(speeds), unit)
+	fmt.Fprintf(w, "gust   %s  %s %s\n", render.Sparkline(gusts), valueRange(gusts), unit)
+	if len(prices) > 0 {
+		fmt.Fprintf(w, "price  %s  %s SEK/kWh\n", render.Sparkline(prices), valueRange(prices))
+	}
+}
+
+func valueRange(values []float64) string {
+	lo, hi := render.MinMax(values)
+	return fmt.Sprintf("%.1f-%.1f", lo, hi)
+}
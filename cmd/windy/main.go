@@ -0,0 +1,11 @@
+//go:build tinygo
+
+package main
+
+import "compute-starter-kit-go/httpapi"
+
+// main serves the requests on Fastly Compute, the service is built with
+// TinyGo.
+func main() {
+	httpapi.Serve()
+}
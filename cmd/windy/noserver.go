@@ -0,0 +1,10 @@
+//go:build !tinygo && !nethttp
+
+package main
+
+import "errors"
+
+// server needs the net/http handlers of httpapi, built with -tags nethttp.
+func server(args []string) error {
+	return errors.New("the server subcommand needs a build with -tags nethttp")
+}
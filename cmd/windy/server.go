@@ -0,0 +1,27 @@
+//go:build !tinygo && nethttp
+
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"compute-starter-kit-go/httpapi"
+)
+
+// server serves the handlers on :8080, $PORT or -addr.
+func server(args []string) error {
+	addr := ":8080"
+	if port := os.Getenv("PORT"); port != "" {
+		addr = ":" + port
+	}
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.StringVar(&addr, "addr", addr, "address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	log.Printf("listening on %s", addr)
+	return http.ListenAndServe(addr, httpapi.Handler())
+}
@@ -0,0 +1,91 @@
+// Package elpris is a client of the Swedish electricity prices published by
+// https://www.elprisetjustnu.se/.
+package elpris
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/buger/jsonparser"
+)
+
+// Upstream is the name of the price API given to the Transport.
+const Upstream = "elpris"
+
+// Regions are the Swedish electricity price areas, elområden.
+var Regions = []string{"SE1", "SE2", "SE3", "SE4"}
+
+// ErrNoPrices is returned when the prices for a day are not published yet,
+// the next day's prices are published around 13:00 CET.
+var ErrNoPrices = errors.New("prices not published")
+
+// Transport gets the URL from the named upstream and returns the status
+// and body, how is up to the program, e.g. Fastly backends with retries.
+type Transport interface {
+	Get(ctx context.Context, upstream, url string) (int, []byte, error)
+}
+
+// Client fetches prices through the Transport.
+type Client struct {
+	Transport Transport
+}
+
+// Price is the price of the hour starting at Start.
+type Price struct {
+	Start     time.Time
+	SEKPerKWh float64
+}
+
+// URL returns the URL of the prices of the region on the day, e.g.
+// https://www.elprisetjustnu.se/api/v1/prices/2023/02-15_SE4.json.
+func URL(region string, day time.Time) string {
+	return fmt.Sprintf("https://www.elprisetjustnu.se/api/v1/prices/%d/%02d-%02d_%s.json", day.Year(), day.Month(), day.Day(), region)
+}
+
+// Day fetches the prices of the region on the day, the day is in Swedish
+// time.
+func (c *Client) Day(ctx context.Context, region string, day time.Time) ([]Price, error) {
+	status, body, err := c.Transport.Get(ctx, Upstream, URL(region, day))
+	if err != nil {
+		return nil, err
+	}
+	if status == 404 {
+		return nil, ErrNoPrices
+	}
+	if status != 200 {
+		return nil, fmt.Errorf("elpris responded with status %d", status)
+	}
+	if !json.Valid(body) {
+		return nil, errors.New("elpris responded with invalid JSON")
+	}
+	return Parse(body), nil
+}
+
+// Parse parses a price response into hourly prices, the start times are in
+// UTC. The 15-minute prices published since the day-ahead market moved to
+// quarter hours are averaged over the hour.
+func Parse(body []byte) []Price {
+	sums := map[time.Time]float64{}
+	counts := map[time.Time]int{}
+	jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		s, _ := jsonparser.GetString(value, "time_start")
+		f, _ := jsonparser.GetFloat(value, "SEK_per_kWh")
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return
+		}
+		h := t.UTC().Truncate(time.Hour)
+		sums[h] += f
+		counts[h]++
+	})
+	items := make([]Price, 0, len(sums))
+	for h, s := range sums {
+		items = append(items, Price{Start: h, SEKPerKWh: s / float64(counts[h])})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Start.Before(items[j].Start) })
+	return items
+}
@@ -0,0 +1,115 @@
+package elpris
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTransport responds with the status and body, or the error, and
+// keeps the URL requested.
+type fakeTransport struct {
+	status int
+	body   string
+	err    error
+	url    string
+}
+
+func (t *fakeTransport) Get(ctx context.Context, upstream, url string) (int, []byte, error) {
+	t.url = url
+	return t.status, []byte(t.body), t.err
+}
+
+const pricesBody = `[
+	{"SEK_per_kWh": 0.41, "EUR_per_kWh": 0.037, "EXR": 11.1, "time_start": "2023-02-15T00:00:00+01:00", "time_end": "2023-02-15T01:00:00+01:00"},
+	{"SEK_per_kWh": 0.38, "EUR_per_kWh": 0.034, "EXR": 11.1, "time_start": "2023-02-15T01:00:00+01:00", "time_end": "2023-02-15T02:00:00+01:00"},
+	{"SEK_per_kWh": 1.25, "EUR_per_kWh": 0.113, "EXR": 11.1, "time_start": "2023-02-15T02:00:00+01:00", "time_end": "2023-02-15T03:00:00+01:00"}
+]`
+
+var day = time.Date(2023, 2, 15, 0, 0, 0, 0, time.UTC)
+
+func TestDay(t *testing.T) {
+	tr := &fakeTransport{status: 200, body: pricesBody}
+	prices, err := (&Client{Transport: tr}).Day(context.Background(), "SE4", day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.url != "https://www.elprisetjustnu.se/api/v1/prices/2023/02-15_SE4.json" {
+		t.Errorf("got URL %s", tr.url)
+	}
+	want := []Price{
+		{Start: time.Date(2023, 2, 14, 23, 0, 0, 0, time.UTC), SEKPerKWh: 0.41},
+		{Start: time.Date(2023, 2, 15, 0, 0, 0, 0, time.UTC), SEKPerKWh: 0.38},
+		{Start: time.Date(2023, 2, 15, 1, 0, 0, 0, time.UTC), SEKPerKWh: 1.25},
+	}
+	if len(prices) != len(want) {
+		t.Fatalf("got %d prices, want %d", len(prices), len(want))
+	}
+	for i, p := range prices {
+		if !p.Start.Equal(want[i].Start) || p.SEKPerKWh != want[i].SEKPerKWh {
+			t.Errorf("price %d is %+v, want %+v", i, p, want[i])
+		}
+	}
+}
+
+func TestDayErrors(t *testing.T) {
+	failed := errors.New("connection refused")
+	tests := []struct {
+		name string
+		tr   *fakeTransport
+		want error
+		msg  string
+	}{
+		{"transport error", &fakeTransport{err: failed}, failed, ""},
+		{"not published", &fakeTransport{status: 404, body: "Not Found"}, ErrNoPrices, ""},
+		{"non-2xx", &fakeTransport{status: 503, body: "Service Unavailable"}, nil, "status 503"},
+		{"truncated JSON", &fakeTransport{status: 200, body: pricesBody[:len(pricesBody)/2]}, nil, "invalid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&Client{Transport: tt.tr}).Day(context.Background(), "SE4", day)
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("got error %v, want %q", err, tt.msg)
+			}
+		})
+	}
+}
+
+func TestParseSkipsInvalidTimes(t *testing.T) {
+	prices := Parse([]byte(`[{"SEK_per_kWh": 0.41, "time_start": "yesterday"}, {"SEK_per_kWh": 0.38, "time_start": "2023-02-15T01:00:00+01:00"}]`))
+	if len(prices) != 1 || prices[0].SEKPerKWh != 0.38 {
+		t.Errorf("got %+v, want the price with a valid time", prices)
+	}
+}
+
+func TestParseAveragesQuarterHours(t *testing.T) {
+	prices := Parse([]byte(`[
+	{"SEK_per_kWh": 0.40, "time_start": "2023-02-15T00:00:00+01:00", "time_end": "2023-02-15T00:15:00+01:00"},
+	{"SEK_per_kWh": 0.50, "time_start": "2023-02-15T00:15:00+01:00", "time_end": "2023-02-15T00:30:00+01:00"},
+	{"SEK_per_kWh": 0.60, "time_start": "2023-02-15T00:30:00+01:00", "time_end": "2023-02-15T00:45:00+01:00"},
+	{"SEK_per_kWh": 0.70, "time_start": "2023-02-15T00:45:00+01:00", "time_end": "2023-02-15T01:00:00+01:00"},
+	{"SEK_per_kWh": 1.00, "time_start": "2023-02-15T01:00:00+01:00", "time_end": "2023-02-15T01:15:00+01:00"},
+	{"SEK_per_kWh": 2.00, "time_start": "2023-02-15T01:15:00+01:00", "time_end": "2023-02-15T01:30:00+01:00"}
+]`))
+	want := []Price{
+		{Start: time.Date(2023, 2, 14, 23, 0, 0, 0, time.UTC), SEKPerKWh: 0.55},
+		{Start: time.Date(2023, 2, 15, 0, 0, 0, 0, time.UTC), SEKPerKWh: 1.5},
+	}
+	if len(prices) != len(want) {
+		t.Fatalf("got %d prices, want %d", len(prices), len(want))
+	}
+	for i, p := range prices {
+		if !p.Start.Equal(want[i].Start) || math.Abs(p.SEKPerKWh-want[i].SEKPerKWh) > 1e-9 {
+			t.Errorf("price %d is %+v, want %+v", i, p, want[i])
+		}
+	}
+}
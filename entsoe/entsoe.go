@@ -0,0 +1,182 @@
+// Package entsoe is a client of the day-ahead prices of the bidding zones
+// published by the ENTSO-E Transparency Platform,
+// https://transparency.entsoe.eu/.
+package entsoe
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Upstream is the name of the ENTSO-E API given to the Transport.
+const Upstream = "entsoe"
+
+// Zones are the EIC codes of the bidding zones by name.
+var Zones = map[string]string{
+	"AT":    "10YAT-APG------L",
+	"BE":    "10YBE----------2",
+	"DE-LU": "10Y1001A1001A82H",
+	"DK1":   "10YDK-1--------W",
+	"DK2":   "10YDK-2--------M",
+	"EE":    "10Y1001A1001A39I",
+	"FI":    "10YFI-1--------U",
+	"FR":    "10YFR-RTE------C",
+	"LT":    "10YLT-1001A0008Q",
+	"LV":    "10YLV-1001A00074",
+	"NL":    "10YNL----------L",
+	"NO1":   "10YNO-1--------2",
+	"NO2":   "10YNO-2--------T",
+	"NO3":   "10YNO-3--------J",
+	"NO4":   "10YNO-4--------9",
+	"NO5":   "10Y1001A1001A48H",
+	"PL":    "10YPL-AREA-----S",
+	"SE1":   "10Y1001A1001A44P",
+	"SE2":   "10Y1001A1001A45N",
+	"SE3":   "10Y1001A1001A46L",
+	"SE4":   "10Y1001A1001A47J",
+}
+
+// ZoneNames returns the names of the zones, sorted.
+func ZoneNames() []string {
+	names := make([]string, 0, len(Zones))
+	for n := range Zones {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ErrNoPrices is returned when the prices for a day are not published yet,
+// the day-ahead auction closes at 12:00 CET.
+var ErrNoPrices = errors.New("prices not published")
+
+// Transport gets the URL from the named upstream and returns the status
+// and body, how is up to the program, e.g. Fastly backends with retries.
+type Transport interface {
+	Get(ctx context.Context, upstream, url string) (int, []byte, error)
+}
+
+// Client fetches prices through the Transport with the security token of
+// the Transparency Platform.
+type Client struct {
+	Transport Transport
+	Token     string
+}
+
+// Price is the price of the hour starting at Start.
+type Price struct {
+	Start     time.Time
+	EURPerMWh float64
+}
+
+// URL returns the URL of the day-ahead prices, document type A44, of the
+// zone from start to end.
+func URL(token, zone string, start, end time.Time) string {
+	eic := Zones[zone]
+	q := url.Values{
+		"securityToken": {token},
+		"documentType":  {"A44"},
+		"in_Domain":     {eic},
+		"out_Domain":    {eic},
+		"periodStart":   {start.UTC().Format("200601021504")},
+		"periodEnd":     {end.UTC().Format("200601021504")},
+	}
+	return "https://web-api.tp.entsoe.eu/api?" + q.Encode()
+}
+
+// Day fetches the prices of the zone on the day, from midnight to midnight
+// in the location of the day.
+func (c *Client) Day(ctx context.Context, zone string, day time.Time) ([]Price, error) {
+	if _, ok := Zones[zone]; !ok {
+		return nil, fmt.Errorf("unknown zone %q", zone)
+	}
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	status, body, err := c.Transport.Get(ctx, Upstream, URL(c.Token, zone, start, start.AddDate(0, 0, 1)))
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		return nil, fmt.Errorf("entsoe responded with status %d", status)
+	}
+	return Parse(body)
+}
+
+// document is the part of a Publication_MarketDocument, or of the
+// Acknowledgement_MarketDocument returned when there is no data, used.
+type document struct {
+	XMLName    xml.Name
+	Reason     string `xml:"Reason>text"`
+	TimeSeries []struct {
+		Period []struct {
+			Start      string `xml:"timeInterval>start"`
+			End        string `xml:"timeInterval>end"`
+			Resolution string `xml:"resolution"`
+			Points     []struct {
+				Position int     `xml:"position"`
+				Price    float64 `xml:"price.amount"`
+			} `xml:"Point"`
+		} `xml:"Period"`
+	} `xml:"TimeSeries"`
+}
+
+// Parse parses a day-ahead price document into hourly prices, the start
+// times are in UTC. Prices of shorter periods, e.g. PT15M, are averaged
+// over the hour and left out positions repeat the price before them.
+func Parse(body []byte) ([]Price, error) {
+	var d document
+	if err := xml.Unmarshal(body, &d); err != nil {
+		return nil, err
+	}
+	if d.XMLName.Local == "Acknowledgement_MarketDocument" {
+		return nil, fmt.Errorf("%w: %s", ErrNoPrices, d.Reason)
+	}
+	sums := map[time.Time]float64{}
+	counts := map[time.Time]int{}
+	for _, ts := range d.TimeSeries {
+		for _, p := range ts.Period {
+			start, err1 := time.Parse("2006-01-02T15:04Z", p.Start)
+			end, err2 := time.Parse("2006-01-02T15:04Z", p.End)
+			step, err3 := resolution(p.Resolution)
+			if err1 != nil || err2 != nil || err3 != nil || len(p.Points) == 0 {
+				return nil, fmt.Errorf("invalid period %s-%s %s", p.Start, p.End, p.Resolution)
+			}
+			next, price := 0, p.Points[0].Price
+			for i := 0; start.Add(time.Duration(i) * step).Before(end); i++ {
+				if next < len(p.Points) && p.Points[next].Position == i+1 {
+					price = p.Points[next].Price
+					next++
+				}
+				h := start.Add(time.Duration(i) * step).Truncate(time.Hour)
+				sums[h] += price
+				counts[h]++
+			}
+		}
+	}
+	if len(sums) == 0 {
+		return nil, ErrNoPrices
+	}
+	prices := make([]Price, 0, len(sums))
+	for h, s := range sums {
+		prices = append(prices, Price{Start: h, EURPerMWh: s / float64(counts[h])})
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Start.Before(prices[j].Start) })
+	return prices, nil
+}
+
+// resolution parses the resolution of a period, PT15M, PT30M or PT60M.
+func resolution(s string) (time.Duration, error) {
+	switch s {
+	case "PT15M":
+		return 15 * time.Minute, nil
+	case "PT30M":
+		return 30 * time.Minute, nil
+	case "PT60M":
+		return time.Hour, nil
+	}
+	return 0, fmt.Errorf("unknown resolution %q", s)
+}
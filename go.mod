@@ -5,3 +5,5 @@ go 1.19
 require github.com/fastly/compute-sdk-go v0.1.2
 
 require github.com/buger/jsonparser v1.1.1
+
+require github.com/andybalholm/brotli v1.1.1
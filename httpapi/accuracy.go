@@ -0,0 +1,248 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"compute-starter-kit-go/openmeteo"
+	"github.com/buger/jsonparser"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+	"github.com/fastly/compute-sdk-go/objectstore"
+)
+
+// storeName is the name of the KV Store, see fastly.toml.
+const storeName = "windy"
+
+// maxAccuracyDays is the number of days of snapshots compared.
+const maxAccuracyDays = 14
+
+// snapshot is a forecast as it was served, stored once per location and
+// day. Speeds and gusts are in m/s.
+type snapshot struct {
+	Issued time.Time      `json:"issued"`
+	Hours  []snapshotHour `json:"hours"`
+}
+
+type snapshotHour struct {
+	Hour  int64   `json:"t"`
+	Speed float64 `json:"s"`
+	Gust  float64 `json:"g"`
+}
+
+// Lead is the accuracy of the forecasts made a number of days ahead.
+type Lead struct {
+	Days      int     `json:"lead_days"`
+	Hours     int     `json:"hours"`
+	SpeedMAE  float64 `json:"speed_mae"`
+	SpeedBias float64 `json:"speed_bias"`
+	GustMAE   float64 `json:"gust_mae"`
+}
+
+// AccuracyResponse is the JSON representation of /accuracy.json.
+type AccuracyResponse struct {
+	Location  Location `json:"location"`
+	Unit      string   `json:"unit"`
+	Snapshots int      `json:"snapshots"`
+	Leads     []Lead   `json:"leads"`
+}
+
+func snapshotKey(lat, long string, t time.Time) string {
+	return fmt.Sprintf("snapshot/%s,%s/%s", lat, long, t.UTC().Format("2006-01-02"))
+}
+
+// saveSnapshot stores the first forecast of the day for the location, the
+// entries must be in m/s. It is only called with a fresh forecast, which
+// the cache of Open-Meteo limits to once an hour per location, rather than
+// looking up the snapshot on every fetch. Failures are logged, the
+// forecast is served anyway.
+func saveSnapshot(ctx context.Context, lat, long string, entries []*entry) {
+	store, err := openKV()
+	if err != nil {
+		logln(ctx, "snapshot:", err)
+		return
+	}
+	now := time.Now().UTC()
+	key := snapshotKey(lat, long, now)
+	if _, err := store.Lookup(key); err == nil {
+		return
+	}
+	s := snapshot{Issued: now.Truncate(time.Hour)}
+	for _, e := range entries {
+		if e.past {
+			continue
+		}
+		s.Hours = append(s.Hours, snapshotHour{Hour: e.hour.Unix(), Speed: e.speed, Gust: e.gust})
+	}
+	b, _ := json.Marshal(s)
+	if err := store.Insert(key, bytes.NewReader(b)); err != nil {
+		logln(ctx, "snapshot:", err)
+	}
+}
+
+// loadSnapshots returns the snapshots of the location for the last days,
+// days without a snapshot are skipped.
+func loadSnapshots(lat, long string, days int) ([]snapshot, error) {
+	store, err := openKV()
+	if err != nil {
+		return nil, err
+	}
+	snapshots := []snapshot{}
+	now := time.Now().UTC()
+	for d := 0; d < days; d++ {
+		entry, err := store.Lookup(snapshotKey(lat, long, now.AddDate(0, 0, -d)))
+		if err == objectstore.ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var s snapshot
+		if err := json.NewDecoder(entry).Decode(&s); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, nil
+}
+
+// handleAccuracy handles /accuracy.json?lat=55.6&long=13.0 and compares the
+// stored forecasts of the location with the observed winds.
+func handleAccuracy(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	q := req.URL.Query()
+	sp, err := parseSpot(ctx, req)
+	if errors.Is(err, errUnknownSpot) {
+		writeError(rw, req, fsthttp.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	if sp == nil && (q.Get("lat") == "" || q.Get("long") == "") {
+		writeError(rw, req, fsthttp.StatusBadRequest, errors.New("lat and long, or spot, are required"))
+		return
+	}
+	var defLat, defLong float64
+	if sp != nil {
+		defLat, defLong = sp.lat, sp.long
+	}
+	lat, long, err := parseCoordinates(req, defLat, defLong)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	days, err := intParam(req, "days", maxAccuracyDays, 1, maxAccuracyDays)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	snapshots, err := loadSnapshots(lat, long, days)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	observed := map[int64]observation{}
+	if len(snapshots) > 0 {
+		start := snapshots[len(snapshots)-1].Issued
+		observed, err = fetchObserved(ctx, lat, long, start, time.Now().UTC())
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadGateway, err)
+			return
+		}
+	}
+	r := AccuracyResponse{
+		Unit:      "m/s",
+		Snapshots: len(snapshots),
+		Leads:     compareSnapshots(snapshots, observed),
+	}
+	r.Location.Latitude, _ = strconv.ParseFloat(lat, 64)
+	r.Location.Longitude, _ = strconv.ParseFloat(long, 64)
+	b, _ := json.MarshalIndent(r, "", "  ")
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(b)
+}
+
+// compareSnapshots computes the errors of the forecasts grouped by how many
+// days ahead of the observation they were issued.
+func compareSnapshots(snapshots []snapshot, observed map[int64]observation) []Lead {
+	leads := []Lead{}
+	for _, s := range snapshots {
+		for _, h := range s.Hours {
+			o, ok := observed[h.Hour]
+			if !ok {
+				continue
+			}
+			d := int(time.Unix(h.Hour, 0).Sub(s.Issued).Hours()) / 24
+			for len(leads) <= d {
+				leads = append(leads, Lead{Days: len(leads)})
+			}
+			l := &leads[d]
+			l.SpeedMAE += math.Abs(h.Speed - o.speed)
+			l.SpeedBias += h.Speed - o.speed
+			l.GustMAE += math.Abs(h.Gust - o.gust)
+			l.Hours++
+		}
+	}
+	for i := range leads {
+		if n := float64(leads[i].Hours); n > 0 {
+			leads[i].SpeedMAE = round(leads[i].SpeedMAE/n, 2)
+			leads[i].SpeedBias = round(leads[i].SpeedBias/n, 2)
+			leads[i].GustMAE = round(leads[i].GustMAE/n, 2)
+		}
+	}
+	return leads
+}
+
+// observation is the observed wind of an hour in m/s.
+type observation struct {
+	speed float64
+	gust  float64
+}
+
+// fetchObserved fetches the observed winds between start and end from the
+// archive, the archive lags a few days behind so recent hours are missing.
+func fetchObserved(ctx context.Context, lat, long string, start, end time.Time) (map[int64]observation, error) {
+	body, err := sendArchiveRequest(ctx, lat, long, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	times := openmeteo.Times(body, "hourly", "time")
+	speeds := openmeteo.OptionalFloats(body, "hourly", "windspeed_10m")
+	gusts := openmeteo.OptionalFloats(body, "hourly", "windgusts_10m")
+	observed := map[int64]observation{}
+	for i, t := range times {
+		s, g := openmeteo.At(speeds, i), openmeteo.At(gusts, i)
+		if s == nil || g == nil {
+			continue
+		}
+		observed[t.Unix()] = observation{speed: *s, gust: *g}
+	}
+	return observed, nil
+}
+
+func sendArchiveRequest(ctx context.Context, lat, long, start, end string) ([]byte, error) {
+	u := fmt.Sprintf("https://archive-api.open-meteo.com/v1/archive?latitude=%s&longitude=%s&windspeed_unit=ms&timeformat=unixtime&start_date=%s&end_date=%s&hourly=windspeed_10m,windgusts_10m", lat, long, start, end)
+	logln(ctx, u)
+	req, _ := newUpstreamRequest(ctx, "GET", u)
+	req.CacheOptions.TTL = 60 * 60 * 1 // 1 hour
+	resp, err := sendUpstream(ctx, req, "open-meteo-archive")
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != fsthttp.StatusOK {
+		reason, _ := jsonparser.GetString(body, "reason")
+		return nil, fmt.Errorf("archive unavailable: %s", reason)
+	}
+	return body, nil
+}
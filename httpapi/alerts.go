@@ -0,0 +1,549 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+	"github.com/fastly/compute-sdk-go/objectstore"
+)
+
+// alertsIndexKey is the KV Store key of the index of the alerts, a JSON
+// object of the ids and the clients that registered them. Each alert is
+// kept under alertKeyPrefix and its id, so changing one alert doesn't
+// rewrite the others.
+const (
+	alertsIndexKey = "alerts/index"
+	alertKeyPrefix = "alerts/"
+)
+
+// adminClient is the client of the alerts registered with the admin-token,
+// they are not limited by max_alerts.
+const adminClient = "admin"
+
+// maxAlertHours is the maximum number of hours an alert looks ahead.
+const maxAlertHours = 72
+
+// Alert is a subscription to a condition at a location, the callback is
+// posted to, or the ntfy topic or Pushover user notified, when the
+// condition will be met within the hours. The id is random and is all that
+// is needed to read, change or delete the alert.
+type Alert struct {
+	ID        string   `json:"id"`
+	Spot      string   `json:"spot,omitempty"`
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Region    string   `json:"region"`
+	Unit      string   `json:"unit"`
+	MinSpeed  *float64 `json:"min_speed"`
+	MaxPrice  *float64 `json:"max_price,omitempty"`
+	Hours     int      `json:"hours"`
+	Callback  string   `json:"callback,omitempty"`
+	Ntfy      string   `json:"ntfy,omitempty"`
+	Pushover  string   `json:"pushover,omitempty"`
+	// LastFired is the start of the last window posted, it isn't posted
+	// again.
+	LastFired string `json:"last_fired,omitempty"`
+}
+
+// AlertWindow is a window meeting the condition of an alert.
+type AlertWindow struct {
+	Start        string   `json:"start"`
+	End          string   `json:"end"`
+	MaxSpeed     float64  `json:"max_speed"`
+	MaxGust      float64  `json:"max_gust"`
+	AveragePrice *float64 `json:"average_price,omitempty"`
+	PriceUnit    string   `json:"price_unit,omitempty"`
+}
+
+// AlertNotification is the body posted to the callback of an alert.
+type AlertNotification struct {
+	Alert    Alert         `json:"alert"`
+	Windows  []AlertWindow `json:"windows"`
+	Forecast string        `json:"forecast"`
+}
+
+// EvaluateResponse is the result of POST /alerts/evaluate.
+type EvaluateResponse struct {
+	Evaluated int               `json:"evaluated"`
+	Fired     []string          `json:"fired"`
+	Errors    map[string]string `json:"errors,omitempty"`
+}
+
+// loadAlertIndex returns the ids of the alerts and their clients.
+func loadAlertIndex(store kvStore) (map[string]string, error) {
+	index := map[string]string{}
+	entry, err := store.Lookup(alertsIndexKey)
+	if err == objectstore.ErrKeyNotFound {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(entry).Decode(&index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// saveAlertIndex replaces the index, the last write wins like for the
+// spots.
+func saveAlertIndex(store kvStore, index map[string]string) error {
+	b, _ := json.Marshal(index)
+	return store.Insert(alertsIndexKey, bytes.NewReader(b))
+}
+
+// loadAlert returns the alert with the id, a deleted alert is
+// objectstore.ErrKeyNotFound.
+func loadAlert(store kvStore, id string) (Alert, error) {
+	entry, err := store.Lookup(alertKeyPrefix + id)
+	if err != nil {
+		return Alert{}, err
+	}
+	if entry.String() == "" {
+		return Alert{}, objectstore.ErrKeyNotFound
+	}
+	var a Alert
+	if err := json.Unmarshal([]byte(entry.String()), &a); err != nil {
+		return Alert{}, err
+	}
+	return a, nil
+}
+
+func saveAlert(store kvStore, a Alert) error {
+	b, _ := json.Marshal(a)
+	return store.Insert(alertKeyPrefix+a.ID, bytes.NewReader(b))
+}
+
+// loadAlerts returns all alerts of the index by id.
+func loadAlerts(store kvStore) (map[string]Alert, error) {
+	index, err := loadAlertIndex(store)
+	if err != nil {
+		return nil, err
+	}
+	alerts := map[string]Alert{}
+	for id := range index {
+		a, err := loadAlert(store, id)
+		if err == objectstore.ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("alert %s: %w", id, err)
+		}
+		alerts[id] = a
+	}
+	return alerts, nil
+}
+
+// listAlerts handles GET /alerts and lists all alerts, it requires the
+// admin-token secret as bearer token.
+func listAlerts(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	if !checkAdmin(rw, req) {
+		return
+	}
+	store, err := openKV()
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	alerts, err := loadAlerts(store)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	list := []Alert{}
+	for _, a := range alerts {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	writeSpotJSON(rw, fsthttp.StatusOK, list)
+}
+
+// createAlert handles POST /alerts with a JSON alert, e.g. {"spot":
+// "lomma", "min_speed": 8, "hours": 24, "callback": "https://..."}, and
+// responds with the alert and its id. It requires an API key or the
+// admin-token as bearer token, a key registers at most max_alerts alerts.
+func createAlert(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	client, ok := alertClient(ctx, rw, req)
+	if !ok {
+		return
+	}
+	a, err := parseAlertBody(ctx, req.Body)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	store, err := openKV()
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	index, err := loadAlertIndex(store)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	if max := configInt(ctx, "max_alerts", 10); client != adminClient && clientAlerts(index, client) >= max {
+		writeError(rw, req, fsthttp.StatusForbidden, fmt.Errorf("an API key may register at most %d alerts", max))
+		return
+	}
+	a.ID = newVisitorID()
+	if err := saveAlert(store, a); err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	index[a.ID] = client
+	if err := saveAlertIndex(store, index); err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	rw.Header().Set("Location", "/alerts/"+a.ID)
+	writeSpotJSON(rw, fsthttp.StatusCreated, a)
+}
+
+// alertClient returns the client registering an alert, adminClient for
+// the admin-token, else the hash of the API key. On failure the error is
+// written and false returned.
+func alertClient(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) (string, bool) {
+	if hasToken(req, "admin-token") {
+		return adminClient, true
+	}
+	token := bearerToken(req)
+	if token == "" {
+		rw.Header().Set("WWW-Authenticate", `Bearer realm="windy"`)
+		writeError(rw, req, fsthttp.StatusUnauthorized, errNoAPIKey)
+		return "", false
+	}
+	if _, err := keyTier(token); err != nil {
+		if !errors.Is(err, errInvalidAPIKey) {
+			logln(ctx, "api key:", err)
+		}
+		rw.Header().Set("WWW-Authenticate", `Bearer realm="windy", error="invalid_token"`)
+		writeError(rw, req, fsthttp.StatusUnauthorized, errInvalidAPIKey)
+		return "", false
+	}
+	return "key/" + keyHash(token), true
+}
+
+// clientAlerts returns the number of alerts of the client in the index.
+func clientAlerts(index map[string]string, client string) int {
+	n := 0
+	for _, c := range index {
+		if c == client {
+			n++
+		}
+	}
+	return n
+}
+
+// getAlert handles GET /alerts/:id.
+func getAlert(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+	_, a, ok := findAlert(rw, req, params["id"])
+	if !ok {
+		return
+	}
+	writeSpotJSON(rw, fsthttp.StatusOK, a)
+}
+
+// putAlert handles PUT /alerts/:id and replaces the alert.
+func putAlert(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+	store, old, ok := findAlert(rw, req, params["id"])
+	if !ok {
+		return
+	}
+	a, err := parseAlertBody(ctx, req.Body)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	a.ID = old.ID
+	if err := saveAlert(store, a); err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	writeSpotJSON(rw, fsthttp.StatusOK, a)
+}
+
+// deleteAlert handles DELETE /alerts/:id. The KV Store can't delete keys,
+// the alert is emptied and removed from the index.
+func deleteAlert(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+	store, a, ok := findAlert(rw, req, params["id"])
+	if !ok {
+		return
+	}
+	if err := store.Insert(alertKeyPrefix+a.ID, strings.NewReader("")); err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	index, err := loadAlertIndex(store)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	delete(index, a.ID)
+	if err := saveAlertIndex(store, index); err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	rw.WriteHeader(fsthttp.StatusNoContent)
+}
+
+// findAlert returns the store and the alert with the id, on failure the
+// error is written and false returned.
+func findAlert(rw fsthttp.ResponseWriter, req *fsthttp.Request, id string) (kvStore, Alert, bool) {
+	store, err := openKV()
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return nil, Alert{}, false
+	}
+	a, err := loadAlert(store, id)
+	if err == objectstore.ErrKeyNotFound {
+		writeError(rw, req, fsthttp.StatusNotFound, fmt.Errorf("unknown alert %q", id))
+		return nil, Alert{}, false
+	}
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return nil, Alert{}, false
+	}
+	return store, a, true
+}
+
+// checkAdmin checks the admin-token, on failure the error is written and
+// false returned.
+func checkAdmin(rw fsthttp.ResponseWriter, req *fsthttp.Request) bool {
+	if !hasToken(req, "admin-token") {
+		rw.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(rw, req, fsthttp.StatusUnauthorized, errors.New("invalid token"))
+		return false
+	}
+	return true
+}
+
+// parseAlertBody parses and validates an alert. The location is a spot or
+// coordinates, the unit defaults to m/s, the minimum speed to 8 m/s and
+// the hours to 24. It is delivered to exactly one of a callback, an ntfy
+// topic or a Pushover user.
+func parseAlertBody(ctx context.Context, body io.Reader) (Alert, error) {
+	var a Alert
+	if err := json.NewDecoder(io.LimitReader(body, 8*1024)).Decode(&a); err != nil {
+		return Alert{}, fmt.Errorf("invalid alert: %w", err)
+	}
+	l, err := resolveLocation(ctx, batchLocation{Spot: a.Spot, Lat: a.Latitude, Long: a.Longitude, Region: a.Region})
+	if err != nil {
+		return Alert{}, fmt.Errorf("invalid alert: %w", err)
+	}
+	a.Latitude, a.Longitude, a.Region = l.Lat, l.Long, l.Region
+	if a.Unit == "" {
+		a.Unit = "ms"
+	}
+	u, ok := units[a.Unit]
+	if !ok {
+		return Alert{}, fmt.Errorf("invalid alert: unknown unit %q", a.Unit)
+	}
+	if a.MinSpeed == nil {
+		min := u.convert(calmWind)
+		a.MinSpeed = &min
+	}
+	if *a.MinSpeed < 0 {
+		return Alert{}, errors.New("invalid alert: min_speed must not be negative")
+	}
+	if a.Hours == 0 {
+		a.Hours = 24
+	}
+	if a.Hours < 1 || a.Hours > maxAlertHours {
+		return Alert{}, fmt.Errorf("invalid alert: hours must be between 1 and %d", maxAlertHours)
+	}
+	if err := checkDelivery(a); err != nil {
+		return Alert{}, fmt.Errorf("invalid alert: %w", err)
+	}
+	a.LastFired = ""
+	return a, nil
+}
+
+func checkDelivery(a Alert) error {
+	n := 0
+	for _, s := range []string{a.Callback, a.Ntfy, a.Pushover} {
+		if s != "" {
+			n++
+		}
+	}
+	switch {
+	case n != 1:
+		return errors.New("give one of callback, ntfy and pushover")
+	case a.Ntfy != "" && !validNtfyTopic.MatchString(a.Ntfy):
+		return fmt.Errorf("invalid ntfy topic %q", a.Ntfy)
+	case a.Pushover != "" && !validPushoverUser.MatchString(a.Pushover):
+		return fmt.Errorf("invalid pushover user key %q", a.Pushover)
+	case a.Callback != "":
+		_, err := alertBackend(a.Callback)
+		return err
+	}
+	return nil
+}
+
+// alertBackend returns the backend of the callback URL. Compute can only
+// reach hosts with a backend, the alert_backends setting lists them as
+// host=backend pairs, e.g. hooks.example.com=hooks,ntfy.sh=ntfy.
+func alertBackend(callback string) (string, error) {
+	u, err := url.Parse(callback)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("invalid callback %q, use an https URL", callback)
+	}
+	for _, pair := range strings.Split(config("alert_backends", ""), ",") {
+		host, backend, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && strings.EqualFold(host, u.Host) {
+			return backend, nil
+		}
+	}
+	return "", fmt.Errorf("callbacks to %s are not allowed", u.Host)
+}
+
+// handleEvaluate handles POST /alerts/evaluate, called by a cron job. It
+// notifies the alerts whose conditions will be met, once per window, and
+// saves only the alerts notified. It requires the admin-token secret as
+// bearer token.
+func handleEvaluate(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	if !checkAdmin(rw, req) {
+		return
+	}
+	store, err := openKV()
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	alerts, err := loadAlerts(store)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	r := EvaluateResponse{Evaluated: len(alerts), Fired: []string{}, Errors: map[string]string{}}
+	ids := make([]string, 0, len(alerts))
+	for id := range alerts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	forecasts := alertForecasts(ctx, alerts, ids)
+	base := "https://" + req.Host
+	for _, id := range ids {
+		a := alerts[id]
+		f, ok := forecasts[alertLocation(a)]
+		if !ok {
+			r.Errors[id] = "the forecast is unavailable"
+			continue
+		}
+		windows := alertWindows(f, a, time.Now())
+		if len(windows) == 0 || windows[0].Start == a.LastFired {
+			continue
+		}
+		if err := notify(ctx, a, windows, toPreview(f, base).URL); err != nil {
+			logln(ctx, "alert", id, err)
+			r.Errors[id] = err.Error()
+			continue
+		}
+		a.LastFired = windows[0].Start
+		if err := saveAlert(store, a); err != nil {
+			logln(ctx, "alert", id, err)
+			r.Errors[id] = err.Error()
+		}
+		r.Fired = append(r.Fired, id)
+	}
+	writeSpotJSON(rw, fsthttp.StatusOK, r)
+}
+
+// alertLocation is the key of the forecast of an alert, alerts at the
+// same location in the same unit share it.
+func alertLocation(a Alert) string {
+	return fmt.Sprintf("%.2f,%.2f,%s,%s", a.Latitude, a.Longitude, a.Region, a.Unit)
+}
+
+// alertForecasts fetches the forecasts of the alerts by location, in
+// batches of at most maxBatch concurrent fetches.
+func alertForecasts(ctx context.Context, alerts map[string]Alert, ids []string) map[string]*forecast {
+	byUnit := map[string][]batchLocation{}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		a := alerts[id]
+		if seen[alertLocation(a)] {
+			continue
+		}
+		seen[alertLocation(a)] = true
+		byUnit[a.Unit] = append(byUnit[a.Unit], batchLocation{Lat: a.Latitude, Long: a.Longitude, Region: a.Region})
+	}
+	forecasts := map[string]*forecast{}
+	for name, locations := range byUnit {
+		for start := 0; start < len(locations); start += maxBatch {
+			end := start + maxBatch
+			if end > len(locations) {
+				end = len(locations)
+			}
+			batch := locations[start:end]
+			fs, errs := fetchAll(ctx, batch, maxAlertHours/24+2, 0, units[name])
+			for i, l := range batch {
+				if errs[i] != nil {
+					logln(ctx, "alert forecast:", errs[i])
+					continue
+				}
+				forecasts[alertLocation(Alert{Latitude: l.Lat, Longitude: l.Long, Region: l.Region, Unit: name})] = fs[i]
+			}
+		}
+	}
+	return forecasts
+}
+
+// alertWindows returns the windows meeting the condition of the alert that
+// start within its hours from now.
+func alertWindows(f *forecast, a Alert, now time.Time) []AlertWindow {
+	windows := []AlertWindow{}
+	for _, w := range windyWindows(f.entries, *a.MinSpeed, a.MaxPrice) {
+		if !w.start.Before(now.Add(time.Duration(a.Hours) * time.Hour)) {
+			break
+		}
+		aw := AlertWindow{
+			Start: w.start.In(f.timezone).Format(time.RFC3339),
+			End:   w.end.In(f.timezone).Format(time.RFC3339),
+		}
+		for _, e := range w.entries {
+			aw.MaxSpeed, aw.MaxGust = math.Max(aw.MaxSpeed, round(e.speed, 2)), math.Max(aw.MaxGust, round(e.gust, 2))
+		}
+		if prices := withPrices(w.entries); len(prices) > 0 {
+			p := round(average(prices), 4)
+			aw.AveragePrice, aw.PriceUnit = &p, f.currency.priceUnit()
+		}
+		windows = append(windows, aw)
+	}
+	return windows
+}
+
+// postAlert posts the windows to the callback of the alert.
+func postAlert(ctx context.Context, a Alert, windows []AlertWindow, forecast string) error {
+	backend, err := alertBackend(a.Callback)
+	if err != nil {
+		return err
+	}
+	b, _ := json.Marshal(AlertNotification{Alert: a, Windows: windows, Forecast: forecast})
+	preq, err := newUpstreamRequest(ctx, "POST", a.Callback)
+	if err != nil {
+		return err
+	}
+	preq.Body = io.NopCloser(bytes.NewReader(b))
+	preq.Header.Set("Content-Type", "application/json")
+	preq.CacheOptions.Pass = true
+	resp, err := sendUpstream(ctx, preq, backend)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("callback failed with status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// rateTiers are the default requests per minute of the API key tiers, 0 is
+// unlimited. They are overridden by the rate_<tier> settings.
+var rateTiers = map[string]int{
+	"free":      60,
+	"standard":  600,
+	"unlimited": 0,
+}
+
+var (
+	errNoAPIKey      = errors.New("an API key is required as bearer token")
+	errInvalidAPIKey = errors.New("the API key is invalid")
+)
+
+// apiKeysRequired reports whether the machine endpoints require an API key,
+// toggled by the api_keys setting.
+func apiKeysRequired() bool {
+	return config("api_keys", "off") == "required"
+}
+
+// machinePath returns true for the JSON, GeoJSON, CSV, XML and line
+// protocol formats and the metrics, the HTML views stay public.
+func machinePath(p string) bool {
+	if p == "/current/metrics" {
+		return true
+	}
+	for _, ext := range []string{".json", ".geojson", ".csv", ".xml", ".influx"} {
+		if strings.HasSuffix(p, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKey wraps a machine endpoint with the API key check.
+func apiKey(h handlerFunc) handlerFunc {
+	return func(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+		if checkAPIKey(ctx, rw, req) {
+			h(ctx, rw, req, params)
+		}
+	}
+}
+
+// checkAPIKey checks the API key and its rate limit when API keys are
+// required, on failure the error is written and false returned. The keys
+// and their tiers are the JSON object in the api-keys secret, e.g.
+// {"7f3a...": "standard"}.
+func checkAPIKey(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) bool {
+	if !apiKeysRequired() {
+		return true
+	}
+	token := bearerToken(req)
+	if token == "" {
+		rw.Header().Set("WWW-Authenticate", `Bearer realm="windy"`)
+		writeError(rw, req, fsthttp.StatusUnauthorized, errNoAPIKey)
+		return false
+	}
+	tier, err := keyTier(token)
+	if err != nil {
+		if !errors.Is(err, errInvalidAPIKey) {
+			logln(ctx, "api key:", err)
+		}
+		rw.Header().Set("WWW-Authenticate", `Bearer realm="windy", error="invalid_token"`)
+		writeError(rw, req, fsthttp.StatusUnauthorized, errInvalidAPIKey)
+		return false
+	}
+	limit := configInt(ctx, "rate_"+tier, rateTiers[tier])
+	if limit == 0 {
+		return true
+	}
+	count := countRequest(ctx, token, limit, time.Now())
+	rw.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	if count > limit {
+		rw.Header().Set("X-RateLimit-Remaining", "0")
+		rw.Header().Set("Retry-After", strconv.Itoa(60-time.Now().Second()))
+		writeError(rw, req, fsthttp.StatusTooManyRequests, fmt.Errorf("the %s tier allows %d requests per minute", tier, limit))
+		return false
+	}
+	rw.Header().Set("X-RateLimit-Remaining", strconv.Itoa(limit-count))
+	return true
+}
+
+// keyTier returns the tier of the API key.
+func keyTier(token string) (string, error) {
+	b, err := secret("api-keys")
+	if err != nil {
+		return "", err
+	}
+	keys := map[string]string{}
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return "", fmt.Errorf("secret api-keys: %w", err)
+	}
+	tier, ok := keys[token]
+	if !ok {
+		return "", errInvalidAPIKey
+	}
+	if _, ok := rateTiers[tier]; !ok {
+		return "", fmt.Errorf("unknown tier %q", tier)
+	}
+	return tier, nil
+}
+
+// rateSteps is the number of steps the limit of a key is counted in. A
+// request is counted with the probability of one step and adds the step,
+// so a key writes its counter about rateSteps times a minute whatever its
+// limit, rather than on every request.
+const rateSteps = 20
+
+// countRequest counts the request of the key in the current minute and
+// returns the estimated count. The KV Store has no atomic increment so
+// concurrent requests may be undercounted, the limit is approximate.
+// Requests over the limit are not counted. Failures are logged and not
+// counted.
+func countRequest(ctx context.Context, token string, limit int, now time.Time) int {
+	store, err := openKV()
+	if err != nil {
+		logln(ctx, "rate limit:", err)
+		return 0
+	}
+	key := fmt.Sprintf("ratelimit/%s/%d", keyHash(token), now.Unix()/60)
+	count := 0
+	if entry, err := store.Lookup(key); err == nil {
+		count, _ = strconv.Atoi(entry.String())
+	}
+	step := limit / rateSteps
+	if step < 1 {
+		step = 1
+	}
+	if count > limit || !sampled(step) {
+		return count + 1
+	}
+	count += step
+	if err := store.Insert(key, strings.NewReader(strconv.Itoa(count))); err != nil {
+		logln(ctx, "rate limit:", err)
+	}
+	return count
+}
+
+// sampled reports true with the probability 1/n.
+func sampled(n int) bool {
+	var b [4]byte
+	rand.Read(b[:])
+	return binary.BigEndian.Uint32(b[:])%uint32(n) == 0
+}
+
+// keyHash identifies an API key in the KV Store without storing the key.
+func keyHash(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:8])
+}
@@ -0,0 +1,157 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// maxBatch is the maximum number of locations in a batch request.
+const maxBatch = 20
+
+// batchLocation is a named location in a batch request, given by
+// coordinates or by spot id.
+type batchLocation struct {
+	Spot   string  `json:"spot"`
+	Name   string  `json:"name"`
+	Lat    float64 `json:"lat"`
+	Long   float64 `json:"long"`
+	Region string  `json:"region"`
+}
+
+// BatchResult is the forecast, or the error, for a location in a batch.
+type BatchResult struct {
+	*Response
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleBatch handles POST /wind/batch.json with a JSON array of named
+// coordinates, e.g. [{"name": "mark 1", "lat": 55.6, "long": 13.0}], and
+// responds with the forecast for each location. The unit and window query
+// parameters apply to all locations.
+func handleBatch(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	locations, err := parseBatch(ctx, req.Body)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	u, err := parseUnit(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	days, hours, err := parseWindow(ctx, req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	forecasts, errs := fetchAll(ctx, locations, days, hours, u)
+	results := make([]BatchResult, len(locations))
+	for i, l := range locations {
+		results[i].Name = l.Name
+		if errs[i] != nil {
+			results[i].Error = errs[i].Error()
+			continue
+		}
+		r := toResponse(forecasts[i])
+		results[i].Response = &r
+	}
+	b, _ := json.MarshalIndent(results, "", "  ")
+	rw.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(rw, "%s\n", b)
+}
+
+// fetchAll fetches the forecasts for the locations concurrently. Locations
+// without a name are named after the nearest place.
+func fetchAll(ctx context.Context, locations []batchLocation, days, hours int, u unit) ([]*forecast, []error) {
+	forecasts := make([]*forecast, len(locations))
+	errs := make([]error, len(locations))
+	var wg sync.WaitGroup
+	for i, l := range locations {
+		wg.Add(1)
+		go func(i int, l batchLocation) {
+			defer wg.Done()
+			defer func() {
+				if v := recover(); v != nil {
+					errs[i] = panicError(ctx, v)
+				}
+			}()
+			lat, long := strconv.FormatFloat(l.Lat, 'f', 2, 64), strconv.FormatFloat(l.Long, 'f', 2, 64)
+			f, err := fetchForecast(ctx, lat, long, l.Region, days, hours, 0, 10, u)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			f.name = l.Name
+			if f.name == "" {
+				f.near, _ = reverseGeocode(ctx, lat, long)
+			}
+			forecasts[i] = f
+		}(i, l)
+	}
+	wg.Wait()
+	return forecasts, errs
+}
+
+func parseBatch(ctx context.Context, body io.Reader) ([]batchLocation, error) {
+	locations := []batchLocation{}
+	if err := json.NewDecoder(io.LimitReader(body, 64*1024)).Decode(&locations); err != nil {
+		return nil, fmt.Errorf("invalid batch, expected an array of locations: %w", err)
+	}
+	if len(locations) == 0 || len(locations) > maxBatch {
+		return nil, fmt.Errorf("invalid batch, must contain between 1 and %d locations", maxBatch)
+	}
+	for i, l := range locations {
+		var err error
+		locations[i], err = resolveLocation(ctx, l)
+		if err != nil {
+			return nil, fmt.Errorf("%w for location %d %q", err, i, l.Name)
+		}
+	}
+	return locations, nil
+}
+
+// resolveLocation replaces a spot with its coordinates, validates the
+// coordinates and defaults the region to the one of the latitude.
+func resolveLocation(ctx context.Context, l batchLocation) (batchLocation, error) {
+	if l.Spot != "" {
+		sp, err := findSpot(ctx, l.Spot)
+		if err != nil {
+			return l, err
+		}
+		l = spotLocation(sp, l.Name, l.Region)
+	}
+	if !(l.Lat >= -90 && l.Lat <= 90) || !(l.Long >= -180 && l.Long <= 180) {
+		return l, errors.New("invalid coordinates")
+	}
+	if l.Region == "" {
+		l.Region = regionFor(l.Lat)
+		return l, nil
+	}
+	l.Region = strings.ToUpper(l.Region)
+	if !validRegion(l.Region) {
+		return l, fmt.Errorf("unknown region %q", l.Region)
+	}
+	return l, nil
+}
+
+// spotLocation returns the location of the spot, the name and region
+// default to the ones of the spot.
+func spotLocation(sp *spot, name, region string) batchLocation {
+	l := batchLocation{Spot: sp.id, Name: name, Lat: sp.lat, Long: sp.long, Region: region}
+	if l.Name == "" {
+		l.Name = sp.name
+	}
+	if l.Region == "" {
+		l.Region = sp.region
+	}
+	return l
+}
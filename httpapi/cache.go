@@ -0,0 +1,79 @@
+package httpapi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// bucketSize is the size in degrees of the grid the coordinates sent to
+// Open-Meteo are snapped to, about 5 km. Nearby locations then share the
+// cached upstream responses, the forecast grid is coarser anyway.
+const bucketSize = 0.05
+
+// bucket snaps the coordinate to the grid.
+func bucket(coordinate string) string {
+	c, err := strconv.ParseFloat(coordinate, 64)
+	if err != nil {
+		return coordinate
+	}
+	return strconv.FormatFloat(math.Round(c/bucketSize)*bucketSize, 'f', 2, 64)
+}
+
+// redirectToBucket redirects a request for coordinates off the grid to the
+// coordinates of their bucket, so the locations of a bucket share the
+// cached responses like they share the upstream responses. It returns true
+// when the redirect is written.
+func redirectToBucket(rw fsthttp.ResponseWriter, req *fsthttp.Request) bool {
+	q := req.URL.Query()
+	lat, long := q.Get("lat"), q.Get("long")
+	if lat == "" || long == "" || bucket(lat) == lat && bucket(long) == long || debugEnabled(req) {
+		return false
+	}
+	q.Set("lat", bucket(lat))
+	q.Set("long", bucket(long))
+	u := *req.URL
+	u.RawQuery = q.Encode()
+	rw.Header().Set("Location", u.RequestURI())
+	rw.Header().Set("Cache-Control", "public, max-age=86400")
+	rw.WriteHeader(fsthttp.StatusMovedPermanently)
+	return true
+}
+
+// personalized reports whether the request has the preferences or unit
+// cookies, which change the forecast.
+func personalized(req *fsthttp.Request) bool {
+	return cookie(req, "prefs") != "" || cookie(req, "unit") != ""
+}
+
+// setCacheHeaders lets Fastly and browsers cache the forecast, serving it
+// stale while revalidating. Forecasts for the location of the client IP,
+// requested with an API key or with the preferences in cookies depend on
+// the client and are private, degraded forecasts are cached briefly and
+// debug output not at all. Only the private responses of the preferences
+// vary on the cookies, so other cookies don't split the shared cache.
+func setCacheHeaders(rw fsthttp.ResponseWriter, req *fsthttp.Request, f *forecast) {
+	if f.debug != nil {
+		rw.Header().Set("Cache-Control", "no-store")
+		return
+	}
+	if personalized(req) {
+		rw.Header().Add("Vary", "Cookie")
+		rw.Header().Set("Cache-Control", "private, max-age=300")
+		return
+	}
+	q := req.URL.Query()
+	if q.Get("lat") == "" && q.Get("city") == "" && q.Get("spot") == "" && f.name == "" || req.Header.Get("Authorization") != "" {
+		rw.Header().Set("Cache-Control", "private, max-age=300")
+		return
+	}
+	maxAge := 900
+	if len(f.warnings) > 0 {
+		maxAge = 60
+	}
+	rw.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, stale-while-revalidate=%d", maxAge/3, maxAge))
+	rw.Header().Set("Surrogate-Key", surrogateKeys(f))
+	rw.Header().Set("Surrogate-Control", fmt.Sprintf("max-age=%d, stale-while-revalidate=%d, stale-if-error=86400", maxAge, 4*maxAge))
+}
@@ -0,0 +1,171 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// Window is a period of consecutive hours and their average price.
+type Window struct {
+	Start   string  `json:"start"`
+	End     string  `json:"end"`
+	Average float64 `json:"average"`
+}
+
+// CheapestResponse is the JSON envelope returned by /cheapest.json.
+type CheapestResponse struct {
+	Region  string   `json:"region"`
+	Hours   int      `json:"hours"`
+	Unit    string   `json:"unit"`
+	Windows []Window `json:"windows"`
+}
+
+// handleCheapest handles /cheapest.json?hours=3&zone=SE4 and responds with
+// the cheapest windows of consecutive hours from now until the end of the
+// published prices. The after and before parameters, hours of the day,
+// constrain the windows, e.g. after=22&before=7 for nights. The count
+// parameter gives the number of non-overlapping windows. The zone, or
+// region, defaults to default_region and then SE4.
+func handleCheapest(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	region, err := parseRegion(req, "")
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	hours, err := intParam(req, "hours", 3, 1, 24)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	count, err := intParam(req, "count", 1, 1, 10)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	after, err := intParam(req, "after", 0, 0, 23)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	before, err := intParam(req, "before", 24, 1, 24)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	cur, err := parseCurrency(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	prices, err := fetchPrices(ctx, region)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadGateway, err)
+		return
+	}
+	c, err := fetchCurrency(ctx, cur)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadGateway, err)
+		return
+	}
+	convertEntries(prices, c)
+	now := time.Now().Truncate(time.Hour)
+	upcoming := []*entry{}
+	for _, p := range prices {
+		if !p.hour.Before(now) && inHours(p.hour.In(stockholm), after, before) {
+			upcoming = append(upcoming, p)
+		}
+	}
+	windows := cheapest(upcoming, hours, count)
+	b, _ := json.MarshalIndent(CheapestResponse{
+		Region: region,
+		Hours:  hours,
+		Unit:   c.priceUnit(),
+		Windows: mapSlice(windows, func(w []*entry) Window {
+			return Window{
+				Start:   w[0].hour.In(stockholm).Format(time.RFC3339),
+				End:     w[len(w)-1].hour.Add(time.Hour).In(stockholm).Format(time.RFC3339),
+				Average: round(average(w), 4),
+			}
+		}),
+	}, "", "  ")
+	rw.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(rw, "%s\n", b)
+}
+
+// cheapest returns the count cheapest non-overlapping windows of n
+// consecutive hours, cheapest first.
+func cheapest(prices []*entry, n, count int) [][]*entry {
+	candidates := [][]*entry{}
+	for i := 0; i+n <= len(prices); i++ {
+		w := prices[i : i+n]
+		if consecutive(w) {
+			candidates = append(candidates, w)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return average(candidates[i]) < average(candidates[j])
+	})
+	windows := [][]*entry{}
+	for _, c := range candidates {
+		if len(windows) == count {
+			break
+		}
+		overlaps := false
+		for _, w := range windows {
+			if c[0].hour.Before(w[len(w)-1].hour.Add(time.Hour)) && w[0].hour.Before(c[len(c)-1].hour.Add(time.Hour)) {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			windows = append(windows, c)
+		}
+	}
+	return windows
+}
+
+func consecutive(w []*entry) bool {
+	for i := 1; i < len(w); i++ {
+		if w[i].hour.Sub(w[i-1].hour) != time.Hour {
+			return false
+		}
+	}
+	return true
+}
+
+func average(w []*entry) float64 {
+	sum := 0.0
+	for _, e := range w {
+		sum += e.price
+	}
+	return sum / float64(len(w))
+}
+
+// inHours reports whether the hour of t is in the range [after, before),
+// ranges where after is greater than before wrap around midnight.
+func inHours(t time.Time, after, before int) bool {
+	h := t.Hour()
+	if after <= before {
+		return h >= after && h < before
+	}
+	return h >= after || h < before
+}
+
+// intParam reads an integer query parameter in the range [min, max].
+func intParam(req *fsthttp.Request, name string, def, min, max int) (int, error) {
+	s := req.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("invalid %s %q, must be between %d and %d", name, s, min, max)
+	}
+	return n, nil
+}
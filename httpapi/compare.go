@@ -0,0 +1,96 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// maxCompare is the maximum number of locations on the compare page.
+const maxCompare = 6
+
+// comparison is the data rendered by render/templates/compare.html.
+type comparison struct {
+	Unit     string
+	Timezone string
+	// Max is the largest gust of all locations, used as the max of all
+	// charts to make them comparable.
+	Max    float64
+	Charts []chart
+	Errors []string
+}
+
+// handleCompare handles /compare.html?loc=55.6,13.0&loc=57.7,11.9 and
+// renders the forecasts of the locations side by side.
+func handleCompare(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	locations, err := parseLocs(ctx, req.URL.Query()["loc"])
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	u, err := parseUnit(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	days, hours, err := parseWindow(ctx, req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	forecasts, errs := fetchAll(ctx, locations, days, hours, u)
+	c := comparison{Unit: u.label}
+	for i, f := range forecasts {
+		if errs[i] != nil {
+			c.Errors = append(c.Errors, fmt.Sprintf("%.2f, %.2f: %s", locations[i].Lat, locations[i].Long, errs[i]))
+			continue
+		}
+		c.Timezone = f.timezone.String()
+		ch := toChart(f, f.title(nil), "en")
+		for _, g := range ch.Gusts {
+			c.Max = math.Max(c.Max, math.Ceil(g))
+		}
+		c.Charts = append(c.Charts, ch)
+	}
+	html, err := render.Render("compare.html", c)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(rw, html)
+}
+
+// parseLocs parses loc parameters on the form lat,long or a spot id.
+func parseLocs(ctx context.Context, locs []string) ([]batchLocation, error) {
+	if len(locs) == 0 || len(locs) > maxCompare {
+		return nil, fmt.Errorf("invalid loc, give between 1 and %d loc=lat,long or loc=spot parameters", maxCompare)
+	}
+	locations := []batchLocation{}
+	for _, loc := range locs {
+		if !strings.Contains(loc, ",") {
+			sp, err := findSpot(ctx, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid loc: %w", err)
+			}
+			locations = append(locations, spotLocation(sp, "", ""))
+			continue
+		}
+		latStr, longStr, _ := strings.Cut(loc, ",")
+		lat, err := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+		if err != nil || !(lat >= -90 && lat <= 90) {
+			return nil, fmt.Errorf("invalid loc %q, lat must be between -90 and 90", loc)
+		}
+		long, err := strconv.ParseFloat(strings.TrimSpace(longStr), 64)
+		if err != nil || !(long >= -180 && long <= 180) {
+			return nil, fmt.Errorf("invalid loc %q, long must be between -180 and 180", loc)
+		}
+		locations = append(locations, batchLocation{Lat: lat, Long: long, Region: regionFor(lat)})
+	}
+	return locations, nil
+}
@@ -0,0 +1,114 @@
+package httpapi
+
+import (
+	"compress/gzip"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// brotliQuality is the quality of the Brotli encoder, the default 6 costs
+// too much CPU per request for little gain on small bodies.
+const brotliQuality = 4
+
+// compressedTypes are the content types worth compressing.
+var compressedTypes = []string{
+	"application/json",
+	"application/problem+json",
+	"application/geo+json",
+	"application/manifest+json",
+	"application/xml",
+	"application/rss+xml",
+	"image/svg+xml",
+	"text/csv",
+	"text/plain",
+	"text/calendar",
+	"text/html",
+	"text/javascript",
+}
+
+// compressWriter compresses the body with Brotli or gzip when the client
+// accepts it and the content type is compressible. The decision is made
+// when the header is written.
+type compressWriter struct {
+	fsthttp.ResponseWriter
+	encoding    string
+	wroteHeader bool
+	enc         io.WriteCloser
+}
+
+func newCompressWriter(rw fsthttp.ResponseWriter, req *fsthttp.Request) *compressWriter {
+	return &compressWriter{ResponseWriter: rw, encoding: acceptedEncoding(req)}
+}
+
+// acceptedEncoding returns br or gzip if the Accept-Encoding header allows
+// it, Brotli is preferred as it is smaller, else the empty string.
+func acceptedEncoding(req *fsthttp.Request) string {
+	accepted := map[string]bool{}
+	for _, e := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(e), ";")
+		name = strings.TrimSpace(name)
+		if _, ok := accepted[name]; ok {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(params), "q="), 64)
+		accepted[name] = err != nil || q > 0
+	}
+	for _, name := range []string{"br", "gzip"} {
+		if accepted[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	h := w.Header()
+	if compressible(h.Get("Content-Type")) {
+		h.Add("Vary", "Accept-Encoding")
+		if w.encoding != "" && code != fsthttp.StatusNoContent && code != fsthttp.StatusNotModified && h.Get("Content-Encoding") == "" {
+			h.Set("Content-Encoding", w.encoding)
+			h.Del("Content-Length")
+			if w.encoding == "br" {
+				w.enc = brotli.NewWriterLevel(w.ResponseWriter, brotliQuality)
+			} else {
+				w.enc = gzip.NewWriter(w.ResponseWriter)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(fsthttp.StatusOK)
+	}
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// finish writes the end of the compressed body.
+func (w *compressWriter) finish() {
+	if w.enc != nil {
+		w.enc.Close()
+	}
+}
+
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	for _, t := range compressedTypes {
+		if strings.TrimSpace(mediaType) == t {
+			return true
+		}
+	}
+	return false
+}
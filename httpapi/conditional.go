@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// httpTime is the format of HTTP dates.
+const httpTime = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// recordModified keeps the newest Date of the upstream responses of the
+// request.
+func recordModified(ctx context.Context, resp *fsthttp.Response) {
+	t, err := time.Parse(httpTime, resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+	s := stateOf(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.After(s.modified) {
+		s.modified = t
+	}
+}
+
+// upstreamModified returns the newest Date of the upstream responses of
+// the request, when the data was fetched.
+func upstreamModified(ctx context.Context) time.Time {
+	s := stateOf(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.modified
+}
+
+// etag returns a weak ETag of the forecast in the format, it only changes
+// when the data does, unlike the body which has the time it was generated.
+func etag(f *forecast, format string) string {
+	r := toResponse(f)
+	r.GeneratedAt = time.Time{}
+	b, _ := json.Marshal(r)
+	h := sha256.Sum256(append(b, format...))
+	return `W/"` + hex.EncodeToString(h[:16]) + `"`
+}
+
+// notModified sets the ETag and Last-Modified headers and responds with 304
+// Not Modified if the client has the current version. If-None-Match takes
+// precedence over If-Modified-Since.
+func notModified(rw fsthttp.ResponseWriter, req *fsthttp.Request, tag string, modified time.Time) bool {
+	rw.Header().Set("ETag", tag)
+	if !modified.IsZero() {
+		rw.Header().Set("Last-Modified", modified.UTC().Format(httpTime))
+	}
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		for _, t := range strings.Split(inm, ",") {
+			t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+			if t == "*" || t == strings.TrimPrefix(tag, "W/") {
+				rw.WriteHeader(fsthttp.StatusNotModified)
+				return true
+			}
+		}
+		return false
+	}
+	since, err := time.Parse(httpTime, req.Header.Get("If-Modified-Since"))
+	if err == nil && !modified.IsZero() && !modified.Truncate(time.Second).After(since) {
+		rw.WriteHeader(fsthttp.StatusNotModified)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package httpapi
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/configstore"
+)
+
+// cacheSettings is set when the settings and secrets read are kept for the
+// rest of the instance. On Fastly an instance serves one request, the
+// net/http server serves all of them and reads the settings each time.
+var cacheSettings = true
+
+// configCache holds the settings read by the instance.
+var (
+	configCache   = map[string]string{}
+	configCacheMu sync.Mutex
+)
+
+// config returns the setting from the windy Config Store, falling back to
+// the environment variable WINDY_<KEY>, e.g. WINDY_BACKEND_OPEN_METEO for
+// backend_open-meteo, and finally to the default.
+func config(key, def string) string {
+	if cacheSettings {
+		configCacheMu.Lock()
+		v, ok := configCache[key]
+		configCacheMu.Unlock()
+		if ok {
+			return v
+		}
+	}
+	v := def
+	if env := os.Getenv("WINDY_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))); env != "" {
+		v = env
+	}
+	if store, err := configstore.Open(storeName); err == nil {
+		if s, err := store.Get(key); err == nil && s != "" {
+			v = s
+		}
+	}
+	if cacheSettings {
+		configCacheMu.Lock()
+		configCache[key] = v
+		configCacheMu.Unlock()
+	}
+	return v
+}
+
+// configInt returns an integer setting, invalid values are logged and the
+// default used.
+func configInt(ctx context.Context, key string, def int) int {
+	v := config(key, "")
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		logln(ctx, "invalid config", key, strconv.Quote(v))
+		return def
+	}
+	return n
+}
+
+// configDuration returns a duration setting such as 10s, invalid values
+// are logged and the default used.
+func configDuration(ctx context.Context, key string, def time.Duration) time.Duration {
+	v := config(key, "")
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		logln(ctx, "invalid config", key, strconv.Quote(v))
+		return def
+	}
+	return d
+}
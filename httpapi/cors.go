@@ -0,0 +1,65 @@
+package httpapi
+
+import (
+	"strings"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// corsHeaders are the request headers allowed in cross-origin requests.
+const corsHeaders = "Authorization, Content-Type, If-None-Match, If-Modified-Since"
+
+// corsExposed are the response headers readable by cross-origin scripts.
+const corsExposed = "ETag, Last-Modified, X-Request-ID"
+
+// corsPath returns true for the API paths, the HTML pages, forms and admin
+// endpoints are not shared with other origins.
+func corsPath(path string) bool {
+	if strings.HasPrefix(path, "/admin/") || strings.HasSuffix(path, ".html") {
+		return false
+	}
+	switch path {
+	case "/", "/prefs", "/favorites":
+		return false
+	}
+	return true
+}
+
+// allowedOrigin returns the value of Access-Control-Allow-Origin for the
+// origin, or "" if it is not allowed. The allowed origins are the comma
+// separated cors_origins setting, * allows all.
+func allowedOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	for _, o := range strings.Split(config("cors_origins", ""), ",") {
+		o = strings.TrimSpace(o)
+		if o == "*" {
+			return "*"
+		}
+		if o != "" && strings.EqualFold(o, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// setCORSHeaders sets the CORS headers of API responses for allowed origins.
+// The allowed methods of preflight requests are set by the router.
+func setCORSHeaders(rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	if !corsPath(req.URL.Path) || config("cors_origins", "") == "" {
+		return
+	}
+	rw.Header().Add("Vary", "Origin")
+	origin := allowedOrigin(req.Header.Get("Origin"))
+	if origin == "" {
+		return
+	}
+	rw.Header().Set("Access-Control-Allow-Origin", origin)
+	if req.Method == "OPTIONS" {
+		rw.Header().Set("Access-Control-Allow-Headers", corsHeaders)
+		rw.Header().Set("Access-Control-Max-Age", "86400")
+		return
+	}
+	rw.Header().Set("Access-Control-Expose-Headers", corsExposed)
+}
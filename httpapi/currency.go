@@ -0,0 +1,82 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/buger/jsonparser"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// currency is a currency of the prices, rate is the amount of it per SEK.
+type currency struct {
+	name string
+	rate float64
+}
+
+// sek is the currency of the prices from elprisetjustnu.se.
+var sek = currency{name: "SEK", rate: 1}
+
+// currencies are the currencies the prices can be shown in.
+var currencies = []string{"SEK", "EUR", "NOK", "DKK"}
+
+// priceUnit is the unit of the prices, e.g. SEK/kWh.
+func (c currency) priceUnit() string {
+	return c.name + "/kWh"
+}
+
+// parseCurrency reads the currency from the query, defaulting to the
+// default_currency setting or SEK.
+func parseCurrency(req *fsthttp.Request) (string, error) {
+	name := req.URL.Query().Get("currency")
+	if name == "" {
+		name = config("default_currency", "SEK")
+	}
+	name = strings.ToUpper(name)
+	if !contains(currencies, name) {
+		return "", fmt.Errorf("unknown currency %q, use one of %s", name, strings.Join(currencies, ", "))
+	}
+	return name, nil
+}
+
+// ratesURL returns the URL of the latest ECB reference rate of the
+// currency against SEK from Frankfurter.
+func ratesURL(name string) string {
+	return "https://api.frankfurter.app/latest?from=SEK&to=" + name
+}
+
+// fetchCurrency fetches the exchange rate of the currency, SEK needs none.
+func fetchCurrency(ctx context.Context, name string) (currency, error) {
+	if name == sek.name {
+		return sek, nil
+	}
+	defer timed(ctx, "rates")()
+	status, body, err := transport{}.Get(ctx, "rates", ratesURL(name))
+	if err != nil {
+		return currency{}, err
+	}
+	if status != fsthttp.StatusOK {
+		return currency{}, fmt.Errorf("rates responded with status %d", status)
+	}
+	rate, err := jsonparser.GetFloat(body, "rates", name)
+	if err != nil || rate <= 0 {
+		return currency{}, fmt.Errorf("no exchange rate for %s", name)
+	}
+	return currency{name: name, rate: rate}, nil
+}
+
+// convertPrices converts the prices of the forecast from SEK to the
+// currency.
+func convertPrices(f *forecast, c currency) {
+	convertEntries(f.entries, c)
+	f.currency = c
+}
+
+// convertEntries converts the prices of the entries from SEK to the
+// currency.
+func convertEntries(entries []*entry, c currency) {
+	for _, e := range entries {
+		e.price *= c.rate
+	}
+}
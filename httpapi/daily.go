@@ -0,0 +1,102 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/geo"
+)
+
+// Day is the JSON representation of the aggregated hours of a day.
+type Day struct {
+	Date         string   `json:"date"`
+	MinSpeed     float64  `json:"min_speed"`
+	AverageSpeed float64  `json:"average_speed"`
+	MaxSpeed     float64  `json:"max_speed"`
+	MaxGust      float64  `json:"max_gust"`
+	AveragePrice *float64 `json:"average_price"`
+}
+
+// DailyResponse is the JSON envelope returned by /wind/daily.json.
+type DailyResponse struct {
+	Location    Location  `json:"location"`
+	Units       Units     `json:"units"`
+	Region      string    `json:"region"`
+	Timezone    string    `json:"timezone"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Days        []Day     `json:"days"`
+}
+
+// aggregateDaily aggregates the hourly entries into days in the timezone of
+// the forecast.
+func aggregateDaily(f *forecast) []Day {
+	days := []Day{}
+	var hours, priced int
+	var speedSum, priceSum float64
+	finish := func() {
+		if len(days) == 0 {
+			return
+		}
+		d := &days[len(days)-1]
+		d.AverageSpeed = round(speedSum/float64(hours), 2)
+		if priced > 0 {
+			p := round(priceSum/float64(priced), 4)
+			d.AveragePrice = &p
+		}
+	}
+	for _, e := range f.entries {
+		date := e.hour.In(f.timezone).Format("2006-01-02")
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			finish()
+			days = append(days, Day{Date: date, MinSpeed: e.speed})
+			hours, priced, speedSum, priceSum = 0, 0, 0, 0
+		}
+		d := &days[len(days)-1]
+		d.MinSpeed = round(math.Min(d.MinSpeed, e.speed), 2)
+		d.MaxSpeed = round(math.Max(d.MaxSpeed, e.speed), 2)
+		d.MaxGust = round(math.Max(d.MaxGust, e.gust), 2)
+		hours++
+		speedSum += e.speed
+		if e.hasPrice {
+			priced++
+			priceSum += e.price
+		}
+	}
+	finish()
+	return days
+}
+
+func toDailyJSON(f *forecast) string {
+	r := toResponse(f)
+	b, _ := json.MarshalIndent(DailyResponse{
+		Location:    r.Location,
+		Units:       r.Units,
+		Region:      r.Region,
+		Timezone:    r.Timezone,
+		GeneratedAt: r.GeneratedAt,
+		Days:        aggregateDaily(f),
+	}, "", "  ")
+	return string(b)
+}
+
+// dailyChart is the data rendered by render/templates/daily.html.
+type dailyChart struct {
+	Title     string
+	Warnings  []string
+	Unit      string
+	PriceUnit string
+	Days      []Day
+}
+
+func toDailyHTML(f *forecast, g *geo.Geo) (string, error) {
+	return render.Render("daily.html", dailyChart{
+		Title:     fmt.Sprintf("Daily %s, prices for %s", f.title(g), f.region),
+		Warnings:  f.warnings,
+		Unit:      f.unit.label,
+		PriceUnit: f.currency.priceUnit(),
+		Days:      aggregateDaily(f),
+	})
+}
@@ -0,0 +1,104 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"os"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// UpstreamCall is a request sent to an upstream, shown in debug mode.
+type UpstreamCall struct {
+	Backend string  `json:"backend"`
+	URL     string  `json:"url"`
+	Status  int     `json:"status,omitempty"`
+	Cache   string  `json:"cache,omitempty"`
+	Ms      float64 `json:"ms"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// Debug is the _debug section of the responses in debug mode.
+type Debug struct {
+	RequestID string             `json:"request_id"`
+	Upstreams []UpstreamCall     `json:"upstreams"`
+	TimingsMs map[string]float64 `json:"timings_ms"`
+}
+
+// sendUpstream sends the request to the backend and records the call for
+// debug mode. Responses with an Age are counted as cache hits. With the
+// fixtures setting on the recorded fixtures respond instead.
+func sendUpstream(ctx context.Context, req *fsthttp.Request, backend string) (*fsthttp.Response, error) {
+	send := sendRequest
+	if fixturesEnabled() {
+		send = sendFixture
+	}
+	start := time.Now()
+	resp, err := send(ctx, req, backend)
+	c := UpstreamCall{
+		Backend: backend,
+		URL:     redact(req.URL.String()),
+		Ms:      round(float64(time.Since(start).Microseconds())/1000, 1),
+	}
+	if err != nil {
+		c.Error = err.Error()
+	} else {
+		c.Status = resp.StatusCode
+		c.Cache = "miss"
+		if cached(resp) {
+			c.Cache = "hit"
+		}
+	}
+	st := stateOf(ctx)
+	st.mu.Lock()
+	st.upstreamCalls = append(st.upstreamCalls, c)
+	st.mu.Unlock()
+	return resp, err
+}
+
+// cached reports whether the response is from the Fastly cache rather than
+// the upstream.
+func cached(resp *fsthttp.Response) bool {
+	age := resp.Header.Get("Age")
+	return age != "" && age != "0"
+}
+
+// debugEnabled reports whether the request asks for debug output with
+// debug=1. Outside the local server the X-Debug-Token header must match
+// the debug-token secret.
+func debugEnabled(req *fsthttp.Request) bool {
+	if req.URL.Query().Get("debug") != "1" {
+		return false
+	}
+	if os.Getenv("FASTLY_HOSTNAME") == "localhost" {
+		return true
+	}
+	token := req.Header.Get("X-Debug-Token")
+	want, err := secret("debug-token")
+	if token == "" || err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), want) == 1
+}
+
+// debugSection returns the debug output of the forecast, nil unless it is
+// in debug mode.
+func (f *forecast) debugSection() *Debug {
+	if f.debug == nil {
+		return nil
+	}
+	return f.debug.debugInfo()
+}
+
+// debugInfo returns the upstream calls and timings of the request so far.
+func (s *requestState) debugInfo() *Debug {
+	_, ms := s.durations()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &Debug{
+		RequestID: s.id,
+		Upstreams: append([]UpstreamCall{}, s.upstreamCalls...),
+		TimingsMs: ms,
+	}
+}
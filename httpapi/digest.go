@@ -0,0 +1,239 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/mail"
+	"strings"
+	"time"
+
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+	"github.com/fastly/compute-sdk-go/geo"
+)
+
+// digestStep is the number of hours between the rows of the digest.
+const digestStep = 3
+
+// maxRecipients is the maximum number of recipients of a digest.
+const maxRecipients = 10
+
+// digest is the compact forecast of the next 24 hours in the morning
+// email, rendered by render/templates/digest.html and toDigestText.
+type digest struct {
+	Title     string
+	Summary   string
+	Link      string
+	Image     string
+	Unit      string
+	PriceUnit string
+	Rows      []digestRow
+	Windows   []string
+	Charge    string
+}
+
+type digestRow struct {
+	Time      string
+	Speed     string
+	Gust      string
+	Direction string
+	Price     string
+}
+
+// toDigest returns the digest of the forecast linking to base, e.g.
+// https://windy.edgecompute.app.
+func toDigest(f *forecast, g *geo.Geo, base string) digest {
+	p := toPreview(f, base)
+	d := digest{
+		Title:     f.title(g),
+		Summary:   summary(f),
+		Link:      p.URL,
+		Image:     p.Image + "&hours=24",
+		Unit:      f.unit.label,
+		PriceUnit: f.currency.priceUnit(),
+	}
+	end := time.Now().Add(24 * time.Hour)
+	upcoming := []*entry{}
+	for _, e := range f.entries {
+		if !e.past && e.hour.Before(end) {
+			upcoming = append(upcoming, e)
+		}
+	}
+	for i, e := range upcoming {
+		if i%digestStep != 0 {
+			continue
+		}
+		r := digestRow{
+			Time:      e.hour.In(f.timezone).Format("Mon 15:04"),
+			Speed:     fmt.Sprintf("%.1f", e.speed),
+			Gust:      fmt.Sprintf("%.1f", e.gust),
+			Direction: render.Compass(e.direction) + " " + render.Arrow(e.direction),
+		}
+		if e.hasPrice {
+			r.Price = fmt.Sprintf("%.2f", e.price)
+		}
+		d.Rows = append(d.Rows, r)
+	}
+	for _, w := range windyWindows(upcoming, f.unit.convert(calmWind), nil) {
+		maxSpeed, maxGust := 0.0, 0.0
+		for _, e := range w.entries {
+			maxSpeed, maxGust = math.Max(maxSpeed, e.speed), math.Max(maxGust, e.gust)
+		}
+		start, end := w.start.In(f.timezone), w.end.In(f.timezone)
+		d.Windows = append(d.Windows, fmt.Sprintf("%s %s-%s up to %.1f %s, gusts %.1f %s", start.Format("Mon"), start.Format("15:04"), end.Format("15:04"), maxSpeed, f.unit.label, maxGust, f.unit.label))
+	}
+	if w := cheapWindow(f, chargeHours); w != nil {
+		start := w[0].hour.In(f.timezone)
+		end := w[len(w)-1].hour.Add(time.Hour).In(f.timezone)
+		d.Charge = fmt.Sprintf("%s %s-%s, %.2f %s on average", start.Format("Mon"), start.Format("15:04"), end.Format("15:04"), average(w), f.currency.priceUnit())
+	}
+	return d
+}
+
+// toDigestText renders the digest as plain text.
+func toDigestText(d digest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n%s\n\n", d.Title, d.Summary)
+	fmt.Fprintf(&b, "%-10s %6s %6s  %-5s %7s\n", "", "Wind", "Gust", "From", d.PriceUnit)
+	for _, r := range d.Rows {
+		fmt.Fprintf(&b, "%-10s %6s %6s  %-5s %7s\n", r.Time, r.Speed, r.Gust, r.Direction, r.Price)
+	}
+	b.WriteString("\nWindy windows:\n")
+	if len(d.Windows) == 0 {
+		b.WriteString("- none in the next 24 hours\n")
+	}
+	for _, w := range d.Windows {
+		b.WriteString("- " + w + "\n")
+	}
+	if d.Charge != "" {
+		fmt.Fprintf(&b, "\nCheapest charging: %s\n", d.Charge)
+	}
+	fmt.Fprintf(&b, "\nFull forecast: %s\n", d.Link)
+	return b.String()
+}
+
+// SendGridMail is the body of the SendGrid v3 mail send API.
+type SendGridMail struct {
+	Personalizations []SendGridPersonalization `json:"personalizations"`
+	From             SendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []SendGridContent         `json:"content"`
+}
+
+type SendGridPersonalization struct {
+	To []SendGridAddress `json:"to"`
+}
+
+type SendGridAddress struct {
+	Email string `json:"email"`
+}
+
+type SendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// handleDigestSend handles POST /digest/:spot?to=a@example.com and mails
+// the digest of the spot to the comma separated recipients through
+// SendGrid, with the API key in the sendgrid-api-key secret. It requires
+// the admin-token secret as bearer token and is meant to be called by a
+// cron job in the morning.
+func handleDigestSend(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+	if !checkAdmin(rw, req) {
+		return
+	}
+	to, err := parseRecipients(req.URL.Query().Get("to"))
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	sp, err := findSpot(ctx, params["spot"])
+	if errors.Is(err, errUnknownSpot) {
+		writeError(rw, req, fsthttp.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	l, err := resolveLocation(ctx, spotLocation(sp, "", ""))
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	u, err := parseUnit(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	key, err := secret("sendgrid-api-key")
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	forecasts, errs := fetchAll(ctx, []batchLocation{l}, 2, 0, u)
+	if errs[0] != nil {
+		writeError(rw, req, upstreamStatus(errs[0]), errs[0])
+		return
+	}
+	d := toDigest(forecasts[0], nil, "https://"+req.Host)
+	html, err := render.Render("digest.html", d)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	m := SendGridMail{
+		From:    SendGridAddress{Email: config("digest_from", "windy@edgecompute.app")},
+		Subject: fmt.Sprintf("Wind in %s today", sp.name),
+		Content: []SendGridContent{
+			{Type: "text/plain", Value: toDigestText(d)},
+			{Type: "text/html", Value: html},
+		},
+	}
+	for _, a := range to {
+		m.Personalizations = append(m.Personalizations, SendGridPersonalization{To: []SendGridAddress{{Email: a}}})
+	}
+	b, _ := json.Marshal(m)
+	preq, _ := newUpstreamRequest(ctx, "POST", "https://api.sendgrid.com/v3/mail/send")
+	preq.Body = io.NopCloser(bytes.NewReader(b))
+	preq.Header.Set("Content-Type", "application/json")
+	preq.Header.Set("Authorization", "Bearer "+string(key))
+	preq.CacheOptions.Pass = true
+	resp, err := sendUpstream(ctx, preq, config("backend_sendgrid", "sendgrid"))
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadGateway, err)
+		return
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		writeError(rw, req, fsthttp.StatusBadGateway, fmt.Errorf("sendgrid failed with status %d: %s", resp.StatusCode, body))
+		return
+	}
+	logln(ctx, "mailed digest of", sp.id, "to", len(to), "recipients")
+	rw.WriteHeader(fsthttp.StatusNoContent)
+}
+
+// parseRecipients parses the comma separated email addresses, each one
+// gets its own copy of the digest.
+func parseRecipients(s string) ([]string, error) {
+	to := []string{}
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a == "" {
+			continue
+		}
+		addr, err := mail.ParseAddress(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q", a)
+		}
+		to = append(to, addr.Address)
+	}
+	if len(to) == 0 || len(to) > maxRecipients {
+		return nil, fmt.Errorf("invalid to, give between 1 and %d recipients", maxRecipients)
+	}
+	return to, nil
+}
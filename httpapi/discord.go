@@ -0,0 +1,188 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// DiscordMessage is the body of a post to a Discord webhook.
+type DiscordMessage struct {
+	Username string         `json:"username"`
+	Embeds   []DiscordEmbed `json:"embeds"`
+}
+
+// DiscordEmbed is a rich embed of a Discord message.
+type DiscordEmbed struct {
+	Title       string         `json:"title"`
+	URL         string         `json:"url"`
+	Description string         `json:"description"`
+	Color       int            `json:"color"`
+	Fields      []DiscordField `json:"fields,omitempty"`
+	Image       *DiscordImage  `json:"image,omitempty"`
+	Timestamp   string         `json:"timestamp"`
+}
+
+// DiscordField is a field of an embed.
+type DiscordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// DiscordImage is the image of an embed.
+type DiscordImage struct {
+	URL string `json:"url"`
+}
+
+// The colors of the embed, with and without a windy window today.
+const (
+	discordWindy = 0x2e7d32
+	discordCalm  = 0x9e9e9e
+)
+
+// handleDiscord handles POST /discord/:spot?webhook=club and posts today's
+// windy windows at the spot, with the chart, to the named Discord webhook.
+// The webhook URLs are the JSON object in the discord-webhooks secret,
+// e.g. {"club": "https://discord.com/api/webhooks/..."}. It requires the
+// admin-token secret as bearer token and is meant to be called by a cron
+// job. The min and max_price parameters limit the windows as for
+// /wind.ics.
+func handleDiscord(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+	if !hasToken(req, "admin-token") {
+		rw.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(rw, req, fsthttp.StatusUnauthorized, errors.New("invalid token"))
+		return
+	}
+	webhook, err := discordWebhook(ctx, req.URL.Query().Get("webhook"))
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	sp, err := findSpot(ctx, params["spot"])
+	if errors.Is(err, errUnknownSpot) {
+		writeError(rw, req, fsthttp.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	l, err := resolveLocation(ctx, spotLocation(sp, "", ""))
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	u, err := parseUnit(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	min, maxPrice, err := parseWindowLimits(req, u, sek)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	forecasts, errs := fetchAll(ctx, []batchLocation{l}, 1, 0, u)
+	if errs[0] != nil {
+		writeError(rw, req, upstreamStatus(errs[0]), errs[0])
+		return
+	}
+	f := forecasts[0]
+	b, _ := json.Marshal(toDiscord(f, windyWindows(f.entries, min, maxPrice), "https://"+req.Host))
+	preq, _ := newUpstreamRequest(ctx, "POST", webhook)
+	preq.Body = io.NopCloser(bytes.NewReader(b))
+	preq.Header.Set("Content-Type", "application/json")
+	preq.CacheOptions.Pass = true
+	resp, err := sendUpstream(ctx, preq, config("backend_discord", "discord"))
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadGateway, err)
+		return
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		writeError(rw, req, fsthttp.StatusBadGateway, fmt.Errorf("discord webhook failed with status %d: %s", resp.StatusCode, body))
+		return
+	}
+	logln(ctx, "posted", sp.id, "to discord webhook", req.URL.Query().Get("webhook"))
+	rw.WriteHeader(fsthttp.StatusNoContent)
+}
+
+// discordWebhook returns the URL of the named webhook from the
+// discord-webhooks secret. Only Discord webhook URLs are allowed.
+func discordWebhook(ctx context.Context, name string) (string, error) {
+	s, err := secret("discord-webhooks")
+	if err != nil {
+		logln(ctx, "discord:", err)
+		return "", fmt.Errorf("unknown webhook %q", name)
+	}
+	webhooks := map[string]string{}
+	if err := json.Unmarshal(s, &webhooks); err != nil {
+		logln(ctx, "discord: invalid discord-webhooks:", err)
+		return "", fmt.Errorf("unknown webhook %q", name)
+	}
+	u, ok := webhooks[name]
+	if !ok {
+		return "", fmt.Errorf("unknown webhook %q", name)
+	}
+	if !strings.HasPrefix(u, "https://discord.com/api/webhooks/") {
+		return "", fmt.Errorf("webhook %q is not a Discord webhook", name)
+	}
+	return u, nil
+}
+
+// toDiscord returns an embed with a field for each windy window starting
+// today and the chart from base, e.g. https://windy.edgecompute.app.
+func toDiscord(f *forecast, windows []window, base string) DiscordMessage {
+	p := toPreview(f, base)
+	today := time.Now().In(f.timezone).Format("2006-01-02")
+	e := DiscordEmbed{
+		Title:       fmt.Sprintf("Wind in %s today", f.name),
+		URL:         p.URL,
+		Description: summary(f),
+		Color:       discordCalm,
+		Image:       &DiscordImage{URL: p.Image + "&days=1"},
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, w := range windows {
+		if w.start.In(f.timezone).Format("2006-01-02") != today {
+			continue
+		}
+		maxSpeed, maxGust := 0.0, 0.0
+		for _, en := range w.entries {
+			maxSpeed, maxGust = math.Max(maxSpeed, en.speed), math.Max(maxGust, en.gust)
+		}
+		value := fmt.Sprintf("Up to %.1f %s, gusts %.1f %s", maxSpeed, f.unit.label, maxGust, f.unit.label)
+		if prices := withPrices(w.entries); len(prices) > 0 {
+			value += fmt.Sprintf(", %.2f %s on average", average(prices), f.currency.priceUnit())
+		}
+		e.Fields = append(e.Fields, DiscordField{
+			Name:  fmt.Sprintf("%s-%s", w.start.In(f.timezone).Format("15:04"), w.end.In(f.timezone).Format("15:04")),
+			Value: value,
+		})
+		e.Color = discordWindy
+	}
+	if len(e.Fields) == 0 {
+		e.Fields = append(e.Fields, DiscordField{Name: "No windy window", Value: "Not enough wind for the rest of the day."})
+	}
+	return DiscordMessage{Username: "Windy", Embeds: []DiscordEmbed{e}}
+}
+
+// withPrices returns the entries that have a price.
+func withPrices(entries []*entry) []*entry {
+	prices := []*entry{}
+	for _, e := range entries {
+		if e.hasPrice {
+			prices = append(prices, e)
+		}
+	}
+	return prices
+}
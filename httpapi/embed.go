@@ -0,0 +1,143 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"path"
+	"strings"
+
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+	"github.com/fastly/compute-sdk-go/geo"
+)
+
+// embedPage is the data rendered by render/templates/embed.html.
+type embedPage struct {
+	Title   string
+	Chart   template.HTML
+	Summary string
+	Link    string
+}
+
+// toEmbedHTML renders the minimal page of /embed.html, the SVG chart and a
+// summary linking to the full forecast on base, for iframes.
+func toEmbedHTML(f *forecast, g *geo.Geo, base string, width, height int) (string, error) {
+	return render.Render("embed.html", embedPage{
+		Title:   f.title(g),
+		Chart:   template.HTML(toRenderChart(f, f.title(g)).SVG(width, height)),
+		Summary: summary(f),
+		Link:    toPreview(f, base).URL,
+	})
+}
+
+// OEmbed is the oEmbed response of /oembed, a rich embed of /embed.html.
+type OEmbed struct {
+	Version         string `json:"version"`
+	Type            string `json:"type"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	Title           string `json:"title"`
+	HTML            string `json:"html"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	ThumbnailURL    string `json:"thumbnail_url"`
+	ThumbnailWidth  int    `json:"thumbnail_width"`
+	ThumbnailHeight int    `json:"thumbnail_height"`
+}
+
+// embedText is the height of the summary below the chart of the embed.
+const embedText = 40
+
+// handleOEmbed handles /oembed?url=https://windy.edgecompute.app/wind/lomma
+// and responds with an iframe of the embed page of the forecast. The
+// maxwidth and maxheight parameters limit the size of the iframe.
+func handleOEmbed(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	q := req.URL.Query()
+	if f := q.Get("format"); f != "" && f != "json" {
+		writeError(rw, req, fsthttp.StatusNotImplemented, fmt.Errorf("unsupported format %q, use json", f))
+		return
+	}
+	u, err := url.Parse(q.Get("url"))
+	if err != nil || q.Get("url") == "" {
+		writeError(rw, req, fsthttp.StatusBadRequest, fmt.Errorf("invalid url %q", q.Get("url")))
+		return
+	}
+	embed, title, ok := embedQuery(u)
+	if !ok || u.Host != req.Host {
+		writeError(rw, req, fsthttp.StatusNotFound, fmt.Errorf("there is no forecast at %s", u))
+		return
+	}
+	width, err := intParam(req, "maxwidth", 800, 1, 10000)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	maxHeight, err := intParam(req, "maxheight", 0, 0, 10000)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	// The chart is at least 300x100 and at most 800x300.
+	if width < 300 {
+		width = 300
+	} else if width > 800 {
+		width = 800
+	}
+	chartHeight := width * 3 / 8
+	if maxHeight > 0 && chartHeight+embedText > maxHeight {
+		chartHeight = maxHeight - embedText
+		if chartHeight < 100 {
+			chartHeight = 100
+		}
+	}
+	base := "https://" + req.Host
+	thumbnail := base + "/wind.png?" + embed.Encode()
+	embed.Set("size", fmt.Sprintf("%dx%d", width, chartHeight))
+	b, _ := json.MarshalIndent(OEmbed{
+		Version:      "1.0",
+		Type:         "rich",
+		ProviderName: "Windy",
+		ProviderURL:  base + "/",
+		Title:        title,
+		HTML: fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" style="border:0" title="%s"></iframe>`,
+			template.HTMLEscapeString(base+"/embed.html?"+embed.Encode()), width, chartHeight+embedText, template.HTMLEscapeString(title)),
+		Width:           width,
+		Height:          chartHeight + embedText,
+		ThumbnailURL:    thumbnail,
+		ThumbnailWidth:  1200,
+		ThumbnailHeight: 630,
+	}, "", "  ")
+	rw.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(rw, "%s\n", b)
+}
+
+// embedQuery returns the query of the embed page and the title of a
+// forecast URL like /wind/lomma or /wind.html?spot=lomma, false for other
+// URLs.
+func embedQuery(u *url.URL) (url.Values, string, bool) {
+	q := u.Query()
+	q.Del("format")
+	p := u.Path
+	if strings.HasPrefix(p, "/wind/") {
+		name := strings.TrimPrefix(p, "/wind/")
+		name = strings.TrimSuffix(name, path.Ext(name))
+		if name == "" || strings.Contains(name, "/") || reservedNames[name] {
+			return nil, "", false
+		}
+		q.Set("city", name)
+		p = "/wind"
+	}
+	if p != "/wind" && p != "/wind.html" && p != "/embed.html" {
+		return nil, "", false
+	}
+	title := "Wind forecast"
+	for _, k := range []string{"city", "spot"} {
+		if v := q.Get(k); v != "" {
+			title = fmt.Sprintf("Wind forecast for %s", v)
+		}
+	}
+	return q, title, true
+}
@@ -0,0 +1,111 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"compute-starter-kit-go/openmeteo"
+	"github.com/buger/jsonparser"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// spread is the spread of the ensemble members' wind speed for an hour.
+type spread struct {
+	p10, p50, p90 float64
+}
+
+// Ensemble is the JSON representation of the ensemble spread.
+type Ensemble struct {
+	P10 float64 `json:"p10"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+}
+
+// fetchEnsemble fetches the wind speed of all ensemble members and returns
+// their percentiles for each hour.
+func fetchEnsemble(ctx context.Context, lat, long string, days int) (map[time.Time]spread, error) {
+	defer timed(ctx, "ensemble")()
+	body, err := sendEnsembleRequest(ctx, lat, long, days)
+	if err != nil {
+		return nil, err
+	}
+	times := openmeteo.Times(body, "hourly", "time")
+	members := [][]float64{}
+	jsonparser.ObjectEach(body, func(key []byte, value []byte, dataType jsonparser.ValueType, offset int) error {
+		if strings.HasPrefix(string(key), "windspeed_10m") {
+			members = append(members, openmeteo.Floats(value))
+		}
+		return nil
+	}, "hourly")
+	spreads := map[time.Time]spread{}
+	for i, t := range times {
+		values := []float64{}
+		for _, m := range members {
+			if i < len(m) {
+				values = append(values, m[i])
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		spreads[t] = spread{
+			p10: percentile(values, 10),
+			p50: percentile(values, 50),
+			p90: percentile(values, 90),
+		}
+	}
+	return spreads, nil
+}
+
+func sendEnsembleRequest(ctx context.Context, lat, long string, days int) ([]byte, error) {
+	u := fmt.Sprintf("https://ensemble-api.open-meteo.com/v1/ensemble?latitude=%s&longitude=%s&windspeed_unit=ms&timezone=auto&timeformat=unixtime&forecast_days=%d&models=icon_seamless&hourly=windspeed_10m", lat, long, days)
+	logln(ctx, u)
+	req, _ := newUpstreamRequest(ctx, "GET", u)
+	req.CacheOptions.TTL = 60 * 60 * 1 // 1 hour
+	resp, err := sendUpstream(ctx, req, "open-meteo-ensemble")
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != fsthttp.StatusOK {
+		reason, _ := jsonparser.GetString(body, "reason")
+		return nil, fmt.Errorf("ensemble forecast unavailable: %s", reason)
+	}
+	return body, nil
+}
+
+// mergeEnsemble adds the spreads to the entries, converted to the unit.
+func mergeEnsemble(entries []*entry, spreads map[time.Time]spread, u unit) {
+	for _, e := range entries {
+		s, ok := spreads[e.hour]
+		if !ok {
+			continue
+		}
+		e.ensemble = &spread{p10: u.convert(s.p10), p50: u.convert(s.p50), p90: u.convert(s.p90)}
+	}
+}
+
+func toEnsemble(s *spread) *Ensemble {
+	if s == nil {
+		return nil
+	}
+	return &Ensemble{P10: round(s.p10, 2), P50: round(s.p50, 2), P90: round(s.p90, 2)}
+}
+
+// ensembleValue returns a function getting a percentile from an entry, nil
+// if the entry has no ensemble data.
+func ensembleValue(get func(*spread) float64) func(*entry) *float64 {
+	return func(e *entry) *float64 {
+		if e.ensemble == nil {
+			return nil
+		}
+		v := round(get(e.ensemble), 2)
+		return &v
+	}
+}
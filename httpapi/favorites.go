@@ -0,0 +1,187 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+	"github.com/fastly/compute-sdk-go/objectstore"
+)
+
+// maxFavorites is the maximum number of saved locations per visitor.
+const maxFavorites = 20
+
+// Favorite is a location saved by a visitor.
+type Favorite struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// handleFavorites handles POST /favorites from the save and remove forms.
+// The name, lat and long fields save a location, the remove field removes
+// the location with that name. Visitors are identified by a signed cookie
+// that is created on the first save.
+func handleFavorites(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	key, err := cookieKey()
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, 8*1024))
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	id := visitorID(req, key)
+	if id == "" {
+		id = newVisitorID()
+		rw.Header().Add("Set-Cookie", fmt.Sprintf("visitor=%s.%s; Path=/; Max-Age=31536000; SameSite=Lax; HttpOnly", id, sign(id, key)))
+	}
+	favorites, err := loadFavorites(id)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	if name := form.Get("remove"); name != "" {
+		kept := []Favorite{}
+		for _, fav := range favorites {
+			if fav.Name != name {
+				kept = append(kept, fav)
+			}
+		}
+		favorites = kept
+	} else {
+		fav, err := parseFavorite(form)
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadRequest, err)
+			return
+		}
+		if len(favorites) >= maxFavorites {
+			writeError(rw, req, fsthttp.StatusBadRequest, fmt.Errorf("at most %d locations can be saved", maxFavorites))
+			return
+		}
+		favorites = append(favorites, fav)
+	}
+	if err := saveFavorites(id, favorites); err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	rw.Header().Set("Location", "/")
+	rw.WriteHeader(fsthttp.StatusSeeOther)
+}
+
+func parseFavorite(form url.Values) (Favorite, error) {
+	lat, err := strconv.ParseFloat(form.Get("lat"), 64)
+	if err != nil || !(lat >= -90 && lat <= 90) {
+		return Favorite{}, fmt.Errorf("invalid lat %q, must be between -90 and 90", form.Get("lat"))
+	}
+	long, err := strconv.ParseFloat(form.Get("long"), 64)
+	if err != nil || !(long >= -180 && long <= 180) {
+		return Favorite{}, fmt.Errorf("invalid long %q, must be between -180 and 180", form.Get("long"))
+	}
+	name := strings.TrimSpace(form.Get("name"))
+	if name == "" {
+		name = fmt.Sprintf("%.2f, %.2f", lat, long)
+	}
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	return Favorite{Name: name, Latitude: lat, Longitude: long}, nil
+}
+
+// visitorFavorites returns the saved locations of the visitor, errors are
+// logged since the page works without them.
+func visitorFavorites(ctx context.Context, req *fsthttp.Request) []Favorite {
+	if cookie(req, "visitor") == "" {
+		return nil
+	}
+	key, err := cookieKey()
+	if err != nil {
+		logln(ctx, "favorites:", err)
+		return nil
+	}
+	id := visitorID(req, key)
+	if id == "" {
+		return nil
+	}
+	favorites, err := loadFavorites(id)
+	if err != nil {
+		logln(ctx, "favorites:", err)
+	}
+	return favorites
+}
+
+func favoritesKey(id string) string {
+	return "favorites/" + id
+}
+
+func loadFavorites(id string) ([]Favorite, error) {
+	store, err := openKV()
+	if err != nil {
+		return nil, err
+	}
+	favorites := []Favorite{}
+	entry, err := store.Lookup(favoritesKey(id))
+	if err == objectstore.ErrKeyNotFound {
+		return favorites, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(entry).Decode(&favorites); err != nil {
+		return nil, err
+	}
+	return favorites, nil
+}
+
+func saveFavorites(id string, favorites []Favorite) error {
+	store, err := openKV()
+	if err != nil {
+		return err
+	}
+	b, _ := json.Marshal(favorites)
+	return store.Insert(favoritesKey(id), bytes.NewReader(b))
+}
+
+// cookieKey returns the key used to sign cookies, the cookie-key secret.
+func cookieKey() ([]byte, error) {
+	return secret("cookie-key")
+}
+
+// visitorID returns the id in the visitor cookie if the signature is
+// valid, otherwise the empty string.
+func visitorID(req *fsthttp.Request, key []byte) string {
+	id, sig, ok := strings.Cut(cookie(req, "visitor"), ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(sign(id, key))) {
+		return ""
+	}
+	return id
+}
+
+func newVisitorID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func sign(value string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
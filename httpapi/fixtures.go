@@ -0,0 +1,228 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/buger/jsonparser"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// fixtures are responses of the upstream services recorded on fixtureDay,
+// served instead of sending the requests when the fixtures setting is on.
+//
+//go:embed fixtures/*.json fixtures/*.xml
+var fixtures embed.FS
+
+// fixtureDay is the first day of the fixtures, in Swedish time.
+var fixtureDay = time.Date(2023, 2, 15, 0, 0, 0, 0, stockholm)
+
+// fixtureFiles are the fixtures of the upstream hosts.
+var fixtureFiles = map[string]string{
+	"api.open-meteo.com":           "forecast.json",
+	"archive-api.open-meteo.com":   "forecast.json",
+	"marine-api.open-meteo.com":    "marine.json",
+	"ensemble-api.open-meteo.com":  "ensemble.json",
+	"geocoding-api.open-meteo.com": "geocoding.json",
+	"nominatim.openstreetmap.org":  "nominatim.json",
+	"www.elprisetjustnu.se":        "prices.json",
+	"api.frankfurter.app":          "rates.json",
+	"web-api.tp.entsoe.eu":         "entsoe.xml",
+	"api.awattar.de":               "awattar.json",
+	"api.awattar.at":               "awattar.json",
+}
+
+// fixturesEnabled reports whether upstream requests are served from the
+// fixtures, for running offline and deterministic tests.
+func fixturesEnabled() bool {
+	return config("fixtures", "off") == "on"
+}
+
+// sendFixture responds to the request with the fixture of the host. The
+// times are moved from fixtureDay to today, or to the requested day of the
+// prices and the archive, so the forecast and the prices are current. The
+// forecast is the archive as well, trimmed to the requested days. Hosts
+// without fixtures respond 404.
+func sendFixture(ctx context.Context, req *fsthttp.Request, backend string) (*fsthttp.Response, error) {
+	status, body := fsthttp.StatusOK, []byte(nil)
+	name, ok := fixtureFiles[req.URL.Host]
+	if ok {
+		b, err := fixtures.ReadFile("fixtures/" + name)
+		if err != nil {
+			return nil, err
+		}
+		if name == "prices.json" {
+			body, err = shiftPrices(b, req.URL.Path)
+		} else if name == "entsoe.xml" {
+			body, err = shiftPeriod(b, req.URL.Query())
+		} else if name == "awattar.json" {
+			body, err = shiftMarketdata(b, req.URL.Query())
+		} else {
+			var day time.Time
+			var days int
+			day, days, err = fixtureWindow(req.URL.Query())
+			if err == nil {
+				body, err = shiftTimes(b, day)
+			}
+			if err == nil {
+				body, err = trimHours(body, days*24)
+			}
+		}
+		if err != nil {
+			status, body = fsthttp.StatusNotFound, []byte(`{"reason": "`+err.Error()+`"}`)
+		}
+	} else {
+		status, body = fsthttp.StatusNotFound, []byte(`{"reason": "no fixture for `+req.URL.Host+`"}`)
+	}
+	logln(ctx, "fixture", req.URL.Host, status)
+	return &fsthttp.Response{
+		Request:    req,
+		Backend:    backend,
+		StatusCode: status,
+		Header:     fsthttp.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
+
+// shiftTimes moves the unix times of the hourly Open-Meteo fixture from
+// fixtureDay to the day.
+func shiftTimes(body []byte, day time.Time) ([]byte, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, stockholm)
+	offset := start.Unix() - fixtureDay.Unix()
+	times := []string{}
+	var err error
+	jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, _ int, _ error) {
+		t, perr := strconv.ParseInt(string(value), 10, 64)
+		if perr != nil {
+			err = perr
+		}
+		times = append(times, strconv.FormatInt(t+offset, 10))
+	}, "hourly", "time")
+	if err != nil {
+		return nil, err
+	}
+	return jsonparser.Set(body, []byte("["+strings.Join(times, ",")+"]"), "hourly", "time")
+}
+
+// fixtureWindow returns the first day and the number of days of an
+// Open-Meteo request, the start_date to the end_date of the archive or the
+// past_days before today and the forecast_days from today. The number is
+// 0 when the request has no window.
+func fixtureWindow(q url.Values) (time.Time, int, error) {
+	if start := q.Get("start_date"); start != "" {
+		day, err := time.ParseInLocation("2006-01-02", start, stockholm)
+		if err != nil {
+			return time.Time{}, 0, err
+		}
+		end, err := time.ParseInLocation("2006-01-02", q.Get("end_date"), stockholm)
+		if err != nil {
+			return day, 0, nil
+		}
+		return day, int(end.Sub(day).Hours()/24+0.5) + 1, nil
+	}
+	day := time.Now().In(stockholm)
+	days, err := strconv.Atoi(q.Get("forecast_days"))
+	if err != nil {
+		return day, 0, nil
+	}
+	past, _ := strconv.Atoi(q.Get("past_days"))
+	return day.AddDate(0, 0, -past), past + days, nil
+}
+
+// trimHours keeps the first n hours of the hourly Open-Meteo fixture, all
+// of them when n is 0 or more than there are.
+func trimHours(body []byte, n int) ([]byte, error) {
+	if n <= 0 {
+		return body, nil
+	}
+	keys := []string{}
+	err := jsonparser.ObjectEach(body, func(key []byte, _ []byte, _ jsonparser.ValueType, _ int) error {
+		keys = append(keys, string(key))
+		return nil
+	}, "hourly")
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		values := []string{}
+		jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, _ int, _ error) {
+			if len(values) == n {
+				return
+			}
+			if dataType == jsonparser.String {
+				value = []byte(`"` + string(value) + `"`)
+			}
+			values = append(values, string(value))
+		}, "hourly", key)
+		if body, err = jsonparser.Set(body, []byte("["+strings.Join(values, ",")+"]"), "hourly", key); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+// shiftPeriod moves the period of the ENTSO-E fixture to the requested
+// periodStart and periodEnd, e.g. 202302142300.
+func shiftPeriod(body []byte, q url.Values) ([]byte, error) {
+	start, err1 := time.Parse("200601021504", q.Get("periodStart"))
+	end, err2 := time.Parse("200601021504", q.Get("periodEnd"))
+	if err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("no fixture for period %s-%s", q.Get("periodStart"), q.Get("periodEnd"))
+	}
+	const layout = "2006-01-02T15:04Z"
+	fixtureStart := fixtureDay.UTC()
+	return []byte(strings.NewReplacer(
+		fixtureStart.Format(layout), start.Format(layout),
+		fixtureStart.AddDate(0, 0, 1).Format(layout), end.Format(layout),
+	).Replace(string(body))), nil
+}
+
+// shiftMarketdata moves the timestamps of the aWATTar fixture to the
+// requested start, in milliseconds.
+func shiftMarketdata(body []byte, q url.Values) ([]byte, error) {
+	start, err := strconv.ParseInt(q.Get("start"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("no fixture for start %q", q.Get("start"))
+	}
+	offset := start - fixtureDay.UnixMilli()
+	n := 0
+	jsonparser.ArrayEach(body, func([]byte, jsonparser.ValueType, int, error) { n++ }, "data")
+	for i := 0; i < n; i++ {
+		for _, key := range []string{"start_timestamp", "end_timestamp"} {
+			path := []string{"data", fmt.Sprintf("[%d]", i), key}
+			t, err := jsonparser.GetInt(body, path...)
+			if err != nil {
+				return nil, err
+			}
+			if body, err = jsonparser.Set(body, []byte(strconv.FormatInt(t+offset, 10)), path...); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return body, nil
+}
+
+// shiftPrices moves the times of the price fixture to the day of the path,
+// e.g. /api/v1/prices/2023/02-15_SE4.json.
+func shiftPrices(body []byte, path string) ([]byte, error) {
+	i := strings.LastIndex(path, "/prices/") + len("/prices/")
+	if i < len("/prices/") || len(path) < i+len("2006/01-02") {
+		return nil, fmt.Errorf("no fixture for %s", path)
+	}
+	day, err := time.ParseInLocation("2006/01-02", path[i:i+len("2006/01-02")], stockholm)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.NewReplacer(
+		fixtureDay.Format("2006-01-02"), day.Format("2006-01-02"),
+		fixtureDay.AddDate(0, 0, 1).Format("2006-01-02"), day.AddDate(0, 0, 1).Format("2006-01-02"),
+		fixtureDay.Format(":00-07:00"), day.Format(":00-07:00"),
+	).Replace(string(body))), nil
+}
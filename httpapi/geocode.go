@@ -0,0 +1,186 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"compute-starter-kit-go/render"
+	"github.com/buger/jsonparser"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// place is a geocoding result from Open-Meteo.
+type place struct {
+	name        string
+	admin       string
+	country     string
+	countryCode string
+	lat, long   float64
+}
+
+func (p place) String() string {
+	parts := []string{p.name}
+	if p.admin != "" && p.admin != p.name {
+		parts = append(parts, p.admin)
+	}
+	if p.country != "" {
+		parts = append(parts, p.country)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// reservedNames are paths under /wind/ that are not place names.
+var reservedNames = map[string]bool{"batch": true, "daily": true}
+
+// placeName returns the place name given by the city query parameter or
+// the name path parameter of /wind/:name together with the path of the
+// requested format, e.g. /wind/lomma.json gives lomma and /wind.json.
+// Without an extension, /wind/lomma, the path is /wind and the format is
+// negotiated.
+func placeName(req *fsthttp.Request, params map[string]string) (string, string) {
+	if name := params["name"]; name != "" && !reservedNames[strings.TrimSuffix(name, path.Ext(name))] {
+		ext := path.Ext(name)
+		return strings.TrimSuffix(name, ext), "/wind" + ext
+	}
+	return req.URL.Query().Get("city"), req.URL.Path
+}
+
+func geocode(ctx context.Context, name, country string) ([]place, error) {
+	body, err := sendGeocodeRequest(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	places := []place{}
+	jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		p := place{}
+		p.name, _ = jsonparser.GetString(value, "name")
+		p.admin, _ = jsonparser.GetString(value, "admin1")
+		p.country, _ = jsonparser.GetString(value, "country")
+		p.countryCode, _ = jsonparser.GetString(value, "country_code")
+		p.lat, _ = jsonparser.GetFloat(value, "latitude")
+		p.long, _ = jsonparser.GetFloat(value, "longitude")
+		if country == "" || strings.EqualFold(country, p.countryCode) {
+			places = append(places, p)
+		}
+	}, "results")
+	return places, nil
+}
+
+func sendGeocodeRequest(ctx context.Context, name string) ([]byte, error) {
+	u := fmt.Sprintf("https://geocoding-api.open-meteo.com/v1/search?name=%s&count=10&language=en&format=json", url.QueryEscape(name))
+	logln(ctx, u)
+	req, _ := newUpstreamRequest(ctx, "GET", u)
+	req.CacheOptions.TTL = 60 * 60 * 24 // 1 day
+	resp, err := sendUpstream(ctx, req, "open-meteo-geocoding")
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// pickPlace picks the place matching the name. A single result, or a single
+// result with exactly the given name, is a match, otherwise the user has to
+// choose.
+func pickPlace(name string, places []place) (place, bool) {
+	if len(places) == 1 {
+		return places[0], true
+	}
+	exact := []place{}
+	for _, p := range places {
+		if strings.EqualFold(p.name, name) {
+			exact = append(exact, p)
+		}
+	}
+	if len(exact) == 1 {
+		return exact[0], true
+	}
+	return place{}, false
+}
+
+// Place is the JSON representation of a place to choose from.
+type Place struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	URL       string  `json:"url"`
+}
+
+// writePlaces responds with the places to choose from when a name is
+// ambiguous, in the format of the request path.
+func writePlaces(rw fsthttp.ResponseWriter, req *fsthttp.Request, p string, name string, places []place) {
+	choices := mapSlice(places, func(pl place) Place {
+		return Place{
+			Name:      pl.String(),
+			Latitude:  pl.lat,
+			Longitude: pl.long,
+			URL:       fmt.Sprintf("%s?lat=%.4f&long=%.4f", p, pl.lat, pl.long),
+		}
+	})
+	status := fsthttp.StatusMultipleChoices
+	if len(places) == 0 {
+		status = fsthttp.StatusNotFound
+	}
+	if path.Ext(p) != ".html" {
+		b, _ := json.MarshalIndent(choices, "", "  ")
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(status)
+		fmt.Fprintf(rw, "%s\n", b)
+		return
+	}
+	html, err := render.Render("places.html", struct {
+		Name   string
+		Places []Place
+	}{name, choices})
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	rw.WriteHeader(status)
+	fmt.Fprint(rw, html)
+}
+
+// reverseGeocode returns the name of the place nearest the coordinates,
+// e.g. "Lomma, Sweden".
+func reverseGeocode(ctx context.Context, lat, long string) (string, error) {
+	u := fmt.Sprintf("https://nominatim.openstreetmap.org/reverse?lat=%s&lon=%s&format=jsonv2&zoom=10&accept-language=en", lat, long)
+	logln(ctx, u)
+	req, _ := newUpstreamRequest(ctx, "GET", u)
+	// Nominatim requires an identifying user agent.
+	req.Header.Set("User-Agent", "windy (https://windy.edgecompute.app/)")
+	req.CacheOptions.TTL = 60 * 60 * 24 * 7 // 1 week
+	resp, err := sendUpstream(ctx, req, "nominatim")
+	if err != nil {
+		return "", err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != fsthttp.StatusOK {
+		return "", fmt.Errorf("nominatim responded with status %d", resp.StatusCode)
+	}
+	name := ""
+	for _, key := range []string{"city", "town", "village", "municipality", "county"} {
+		if name, _ = jsonparser.GetString(body, "address", key); name != "" {
+			break
+		}
+	}
+	country, _ := jsonparser.GetString(body, "address", "country")
+	if name == "" {
+		return country, nil
+	}
+	if country == "" {
+		return name, nil
+	}
+	return name + ", " + country, nil
+}
@@ -0,0 +1,49 @@
+package httpapi
+
+import "encoding/json"
+
+// GeoJSON is the forecast as a GeoJSON FeatureCollection, RFC 7946, for
+// maps and GIS tools.
+type GeoJSON struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is the location of the forecast with the forecast as
+// properties.
+type Feature struct {
+	Type       string            `json:"type"`
+	Geometry   Geometry          `json:"geometry"`
+	Properties FeatureProperties `json:"properties"`
+}
+
+// Geometry is a GeoJSON point, the coordinates are longitude and latitude.
+type Geometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// FeatureProperties is the forecast with the current hour, for styling the
+// feature on a map.
+type FeatureProperties struct {
+	Response
+	Current *Entry `json:"current"`
+}
+
+func toGeoJSON(f *forecast) string {
+	r := toResponse(f)
+	p := FeatureProperties{Response: r}
+	if len(r.Entries) > 0 {
+		p.Current = &r.Entries[0]
+	}
+	g := GeoJSON{
+		Type: "FeatureCollection",
+		Features: []Feature{{
+			Type:       "Feature",
+			Geometry:   Geometry{Type: "Point", Coordinates: []float64{r.Location.Longitude, r.Location.Latitude}},
+			Properties: p,
+		}},
+	}
+	b, _ := json.MarshalIndent(g, "", "  ")
+	return string(b)
+}
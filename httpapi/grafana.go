@@ -0,0 +1,186 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// grafanaSeries are the time series of the Grafana datasource by target
+// name, speed and gust are converted to the unit of the target.
+var grafanaSeries = map[string]func(e *entry) (float64, bool){
+	"speed":                     func(e *entry) (float64, bool) { return e.speed, true },
+	"gust":                      func(e *entry) (float64, bool) { return e.gust, true },
+	"direction":                 func(e *entry) (float64, bool) { return e.direction, true },
+	"temperature":               func(e *entry) (float64, bool) { return e.temperature, true },
+	"apparent_temperature":      func(e *entry) (float64, bool) { return e.apparentTemperature, true },
+	"precipitation":             func(e *entry) (float64, bool) { return e.precipitation, true },
+	"precipitation_probability": func(e *entry) (float64, bool) { return e.precipitationProbability, true },
+	"price":                     func(e *entry) (float64, bool) { return e.price, e.hasPrice },
+	"score":                     func(e *entry) (float64, bool) { return e.score, e.hasPrice },
+}
+
+// grafanaQuery is the body of a query of the Grafana JSON datasource.
+type grafanaQuery struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []grafanaTarget `json:"targets"`
+}
+
+// grafanaTarget is a series of a query. The location is given in the
+// payload, or data in the older datasource, else by the spot of the
+// datasource URL.
+type grafanaTarget struct {
+	Target  string          `json:"target"`
+	Payload *grafanaPayload `json:"payload"`
+	Data    *grafanaPayload `json:"data"`
+}
+
+// grafanaPayload is the location and unit of a target.
+type grafanaPayload struct {
+	batchLocation
+	Unit string `json:"unit"`
+}
+
+// GrafanaSeries is a time series of a query response, the datapoints are
+// pairs of value and Unix milliseconds.
+type GrafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// handleGrafana answers the connection test of the datasource.
+func handleGrafana(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+	if params["spot"] != "" {
+		if _, err := findSpot(ctx, params["spot"]); err != nil {
+			writeError(rw, req, fsthttp.StatusNotFound, err)
+			return
+		}
+	}
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(rw, "OK")
+}
+
+// handleGrafanaSearch lists the series matching the target of the search.
+func handleGrafanaSearch(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+	var search struct {
+		Target string `json:"target"`
+	}
+	if err := json.NewDecoder(io.LimitReader(req.Body, 64*1024)).Decode(&search); err != nil && err != io.EOF {
+		writeError(rw, req, fsthttp.StatusBadRequest, fmt.Errorf("invalid search: %w", err))
+		return
+	}
+	names := []string{}
+	for name := range grafanaSeries {
+		if strings.Contains(name, search.Target) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	b, _ := json.Marshal(names)
+	rw.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(rw, "%s\n", b)
+}
+
+// handleGrafanaQuery responds with the series of the targets within the
+// range. The forecast starts at the current hour.
+func handleGrafanaQuery(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+	var q grafanaQuery
+	if err := json.NewDecoder(io.LimitReader(req.Body, 64*1024)).Decode(&q); err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, fmt.Errorf("invalid query: %w", err))
+		return
+	}
+	if len(q.Targets) > maxBatch {
+		writeError(rw, req, fsthttp.StatusBadRequest, fmt.Errorf("invalid query, at most %d targets", maxBatch))
+		return
+	}
+	locations, targetUnits, err := grafanaLocations(ctx, q.Targets, params["spot"])
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	days := int(math.Ceil(time.Until(q.Range.To).Hours()/24)) + 1
+	if days < 1 || days > 16 {
+		days = 16
+	}
+	// Each location is fetched once, in m/s, the units are per target.
+	unique, index := []batchLocation{}, map[batchLocation]int{}
+	for _, l := range locations {
+		if _, ok := index[l]; !ok {
+			index[l] = len(unique)
+			unique = append(unique, l)
+		}
+	}
+	forecasts, errs := fetchAll(ctx, unique, days, 0, units["ms"])
+	series := make([]GrafanaSeries, len(q.Targets))
+	for i, t := range q.Targets {
+		j := index[locations[i]]
+		if errs[j] != nil {
+			writeError(rw, req, upstreamStatus(errs[j]), errs[j])
+			return
+		}
+		value := grafanaSeries[t.Target]
+		series[i] = GrafanaSeries{Target: t.Target, Datapoints: [][2]float64{}}
+		for _, e := range forecasts[j].entries {
+			v, ok := value(e)
+			if !ok || e.hour.Before(q.Range.From) || !q.Range.To.IsZero() && e.hour.After(q.Range.To) {
+				continue
+			}
+			if t.Target == "speed" || t.Target == "gust" {
+				v = round(targetUnits[i].convert(v), 2)
+			}
+			series[i].Datapoints = append(series[i].Datapoints, [2]float64{v, float64(e.hour.UnixMilli())})
+		}
+	}
+	b, _ := json.Marshal(series)
+	rw.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(rw, "%s\n", b)
+}
+
+// grafanaLocations returns the location and unit of each target.
+func grafanaLocations(ctx context.Context, targets []grafanaTarget, spot string) ([]batchLocation, []unit, error) {
+	locations := make([]batchLocation, len(targets))
+	us := make([]unit, len(targets))
+	for i, t := range targets {
+		if grafanaSeries[t.Target] == nil {
+			return nil, nil, fmt.Errorf("unknown target %q", t.Target)
+		}
+		p := t.Payload
+		if p == nil {
+			p = t.Data
+		}
+		if p == nil {
+			p = &grafanaPayload{}
+		}
+		l := p.batchLocation
+		if l.Spot == "" && l.Lat == 0 && l.Long == 0 {
+			l.Spot = spot
+		}
+		if l.Spot == "" && l.Lat == 0 && l.Long == 0 {
+			return nil, nil, errors.New("no location, give a spot or lat and long in the payload or use /grafana/<spot> as the datasource URL")
+		}
+		var err error
+		if locations[i], err = resolveLocation(ctx, l); err != nil {
+			return nil, nil, fmt.Errorf("%w for target %q", err, t.Target)
+		}
+		us[i] = units["ms"]
+		if p.Unit != "" {
+			u, ok := units[strings.ToLower(p.Unit)]
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown unit %q for target %q", p.Unit, t.Target)
+			}
+			us[i] = u
+		}
+	}
+	return locations, us, nil
+}
@@ -0,0 +1,894 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"compute-starter-kit-go/elpris"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// GraphQLForecast is the forecast of the GraphQL API, the hourly entries
+// and their daily aggregates.
+type GraphQLForecast struct {
+	Location    Location  `json:"location"`
+	Units       Units     `json:"units"`
+	Region      string    `json:"region"`
+	Height      int       `json:"height"`
+	Timezone    string    `json:"timezone"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Stats       *Stats    `json:"stats"`
+	Warnings    []string  `json:"warnings"`
+	Entries     []Entry   `json:"entries"`
+	Days        []Day     `json:"days"`
+}
+
+// graphQLRoot is a field of the query type, resolved with the arguments
+// of the query.
+type graphQLRoot struct {
+	args    []graphQLArg
+	typ     reflect.Type
+	resolve func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+}
+
+type graphQLArg struct {
+	name, typ string
+}
+
+// graphQLRoots are the fields of the query type. The field names are the
+// JSON names of the REST API.
+var graphQLRoots = map[string]graphQLRoot{
+	"forecast": {
+		args: []graphQLArg{
+			{"spot", "String"}, {"lat", "Float"}, {"long", "Float"}, {"region", "String"}, {"unit", "String"},
+			{"days", "Int"}, {"hours", "Int"}, {"from", "String"}, {"to", "String"},
+		},
+		typ:     reflect.TypeOf(&GraphQLForecast{}),
+		resolve: resolveForecast,
+	},
+	"spots": {
+		typ:     reflect.TypeOf([]Spot{}),
+		resolve: resolveSpots,
+	},
+	"regions": {
+		args:    []graphQLArg{{"names", "[String!]"}, {"date", "String"}},
+		typ:     reflect.TypeOf([]RegionPrices{}),
+		resolve: resolveRegions,
+	},
+}
+
+// maxGraphQLFields limits the fields of the query type in a query, aliases
+// included, since each one may fetch forecasts and prices.
+const maxGraphQLFields = 10
+
+// graphQLArgError is an error caused by the arguments of a field, its
+// message is returned. Other errors of the resolvers may hold details of
+// the upstreams, they are logged and a generic message is returned.
+type graphQLArgError struct {
+	error
+}
+
+// GraphQLRequest is the body of POST /graphql.
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQLError is an error of a GraphQL response.
+type GraphQLError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// handleGraphQL handles /graphql, queries of the forecasts, spots and
+// prices selecting exactly the fields needed. GET without a query returns
+// the schema. Only queries with fields, aliases, arguments and variables
+// are supported, no fragments, directives or introspection.
+func handleGraphQL(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	var r GraphQLRequest
+	if req.Method == "POST" {
+		body, err := io.ReadAll(io.LimitReader(req.Body, 64*1024))
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadRequest, err)
+			return
+		}
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "application/graphql") {
+			r.Query = string(body)
+		} else if err := json.Unmarshal(body, &r); err != nil {
+			writeError(rw, req, fsthttp.StatusBadRequest, fmt.Errorf("invalid GraphQL request: %w", err))
+			return
+		}
+	} else {
+		q := req.URL.Query()
+		if q.Get("query") == "" {
+			rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(rw, graphQLSchema())
+			return
+		}
+		r.Query, r.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &r.Variables); err != nil {
+				writeError(rw, req, fsthttp.StatusBadRequest, fmt.Errorf("invalid variables: %w", err))
+				return
+			}
+		}
+	}
+	status, resp := executeGraphQL(ctx, r)
+	b, _ := json.Marshal(resp)
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	fmt.Fprintf(rw, "%s\n", b)
+}
+
+// executeGraphQL parses, validates and executes the query. Invalid queries
+// get a 400 without data, failing fields are null with an error.
+func executeGraphQL(ctx context.Context, r GraphQLRequest) (int, interface{}) {
+	invalid := func(err error) (int, interface{}) {
+		return fsthttp.StatusBadRequest, map[string]interface{}{"errors": []GraphQLError{{Message: err.Error()}}}
+	}
+	op, err := parseGraphQL(r.Query)
+	if err != nil {
+		return invalid(err)
+	}
+	if r.OperationName != "" && r.OperationName != op.name {
+		return invalid(fmt.Errorf("unknown operation %q", r.OperationName))
+	}
+	vars := map[string]interface{}{}
+	for name, v := range op.defaults {
+		vars[name] = v
+	}
+	for name, v := range r.Variables {
+		vars[name] = v
+	}
+	n := 0
+	for _, s := range op.selections {
+		if s.name != "__typename" {
+			n++
+		}
+	}
+	if n > maxGraphQLFields {
+		return invalid(fmt.Errorf("the query selects %d fields, at most %d are allowed", n, maxGraphQLFields))
+	}
+	roots := make([]map[string]interface{}, len(op.selections))
+	for i, s := range op.selections {
+		if s.name == "__typename" {
+			continue
+		}
+		root, ok := graphQLRoots[s.name]
+		if !ok {
+			return invalid(fmt.Errorf("cannot query field %q on type \"Query\"", s.name))
+		}
+		if roots[i], err = graphQLArgs(root, s, vars); err != nil {
+			return invalid(err)
+		}
+		if err := validateSelection(root.typ, s); err != nil {
+			return invalid(err)
+		}
+	}
+	data := orderedObject{}
+	errs := []GraphQLError{}
+	for i, s := range op.selections {
+		if s.name == "__typename" {
+			data = append(data, orderedField{s.key(), "Query"})
+			continue
+		}
+		v, err := graphQLRoots[s.name].resolve(ctx, roots[i])
+		if err != nil {
+			msg := err.Error()
+			if !errors.As(err, &graphQLArgError{}) {
+				logln(ctx, "graphql:", s.key(), err)
+				msg = fmt.Sprintf("%s is unavailable", s.key())
+			}
+			errs = append(errs, GraphQLError{Message: msg, Path: []interface{}{s.key()}})
+			data = append(data, orderedField{s.key(), nil})
+			continue
+		}
+		data = append(data, orderedField{s.key(), project(reflect.ValueOf(v), s.selections)})
+	}
+	resp := map[string]interface{}{"data": data}
+	if len(errs) > 0 {
+		resp["errors"] = errs
+	}
+	return fsthttp.StatusOK, resp
+}
+
+// graphQLArgs returns the arguments of the root field with the variables
+// substituted, checking their names and types.
+func graphQLArgs(root graphQLRoot, s *selection, vars map[string]interface{}) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	for name, v := range s.args {
+		typ := ""
+		for _, a := range root.args {
+			if a.name == name {
+				typ = a.typ
+			}
+		}
+		if typ == "" {
+			return nil, fmt.Errorf("unknown argument %q on field %q", name, s.name)
+		}
+		v, err := coerce(v, typ, vars)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument %q on field %q: %w", name, s.name, err)
+		}
+		if v != nil {
+			args[name] = v
+		}
+	}
+	return args, nil
+}
+
+// coerce checks the value against the GraphQL type, Int, Float, String or
+// a list of them, and converts it to int, float64, string or []interface{}.
+// Null is only allowed for nullable types, those without !.
+func coerce(v interface{}, typ string, vars map[string]interface{}) (interface{}, error) {
+	if ref, ok := v.(variable); ok {
+		v, ok = vars[string(ref)]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", ref)
+		}
+	}
+	if v == nil {
+		if strings.HasSuffix(typ, "!") {
+			return nil, fmt.Errorf("expected %s, got null", typ)
+		}
+		return nil, nil
+	}
+	typ = strings.TrimSuffix(typ, "!")
+	switch typ {
+	case "String":
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case "Float":
+		if f, ok := v.(float64); ok {
+			return f, nil
+		}
+	case "Int":
+		if f, ok := v.(float64); ok && f == float64(int(f)) {
+			return int(f), nil
+		}
+	default:
+		list, ok := v.([]interface{})
+		if !ok {
+			list = []interface{}{v}
+		}
+		out := []interface{}{}
+		for _, e := range list {
+			e, err := coerce(e, strings.TrimSuffix(strings.TrimPrefix(typ, "["), "]"), vars)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, e)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("expected %s", typ)
+}
+
+// validateSelection checks the selections against the fields of the type.
+func validateSelection(t reflect.Type, s *selection) error {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	object := t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{})
+	if object && len(s.selections) == 0 {
+		return fmt.Errorf("field %q of type %q must have a selection of subfields", s.name, graphQLTypeName(t))
+	}
+	if !object && len(s.selections) > 0 {
+		return fmt.Errorf("field %q must not have a selection since type %q has no subfields", s.name, graphQLTypeName(t))
+	}
+	for _, sub := range s.selections {
+		if len(sub.args) > 0 {
+			return fmt.Errorf("field %q has no arguments", sub.name)
+		}
+		if sub.name == "__typename" {
+			continue
+		}
+		f, ok := jsonField(t, sub.name)
+		if !ok {
+			return fmt.Errorf("cannot query field %q on type %q", sub.name, graphQLTypeName(t))
+		}
+		if err := validateSelection(f.Type, sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonField returns the field of the struct with the JSON name.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		n, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if n == name && f.IsExported() {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// orderedObject is a JSON object keeping the order of its fields, e.g. the
+// order of the selections of a GraphQL query.
+type orderedObject []orderedField
+
+type orderedField struct {
+	key   string
+	value interface{}
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, f := range o {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		k, _ := json.Marshal(f.key)
+		v, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteString(":")
+		b.Write(v)
+	}
+	b.WriteString("}")
+	return []byte(b.String()), nil
+}
+
+// project returns the selected fields of the value, which has been
+// validated against the selections.
+func project(v reflect.Value, selections []*selection) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return project(v.Elem(), selections)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = project(v.Index(i), selections)
+		}
+		return list
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return t.Format(time.RFC3339)
+		}
+		o := orderedObject{}
+		for _, s := range selections {
+			if s.name == "__typename" {
+				o = append(o, orderedField{s.key(), graphQLTypeName(v.Type())})
+				continue
+			}
+			f, _ := jsonField(v.Type(), s.name)
+			o = append(o, orderedField{s.key(), project(v.FieldByIndex(f.Index), s.selections)})
+		}
+		return o
+	}
+	return v.Interface()
+}
+
+// graphQLTypeName is the GraphQL name of the type, GraphQLForecast is
+// Forecast.
+func graphQLTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "String"
+	case reflect.Float32, reflect.Float64:
+		return "Float"
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return "Int"
+	case reflect.Bool:
+		return "Boolean"
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "String"
+	}
+	return strings.TrimPrefix(t.Name(), "GraphQL")
+}
+
+// graphQLSchema returns the schema of the API in the GraphQL schema
+// language, generated from the types like the OpenAPI document.
+func graphQLSchema() string {
+	types := map[string]string{}
+	var typeRef func(t reflect.Type) string
+	typeRef = func(t reflect.Type) string {
+		switch t.Kind() {
+		case reflect.Ptr:
+			return strings.TrimSuffix(typeRef(t.Elem()), "!")
+		case reflect.Slice:
+			return "[" + typeRef(t.Elem()) + "]"
+		case reflect.Struct:
+			if t == reflect.TypeOf(time.Time{}) {
+				break
+			}
+			name := graphQLTypeName(t)
+			if _, ok := types[name]; !ok {
+				types[name] = ""
+				var b strings.Builder
+				fmt.Fprintf(&b, "type %s {\n", name)
+				for i := 0; i < t.NumField(); i++ {
+					f := t.Field(i)
+					n, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+					if n == "" || n == "-" || !f.IsExported() {
+						continue
+					}
+					fmt.Fprintf(&b, "  %s: %s\n", n, typeRef(f.Type))
+				}
+				b.WriteString("}\n")
+				types[name] = b.String()
+			}
+		}
+		return graphQLTypeName(t) + "!"
+	}
+	var b strings.Builder
+	b.WriteString("type Query {\n")
+	names := []string{}
+	for name := range graphQLRoots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		root := graphQLRoots[name]
+		args := []string{}
+		for _, a := range root.args {
+			args = append(args, a.name+": "+a.typ)
+		}
+		field := name
+		if len(args) > 0 {
+			field += "(" + strings.Join(args, ", ") + ")"
+		}
+		fmt.Fprintf(&b, "  %s: %s\n", field, typeRef(root.typ))
+	}
+	b.WriteString("}\n")
+	names = names[:0]
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString("\n" + types[name])
+	}
+	return b.String()
+}
+
+// resolveForecast fetches the forecast of a spot or coordinates, limited
+// to the hours from and to when given as RFC3339 times.
+func resolveForecast(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	l := batchLocation{}
+	l.Spot, _ = args["spot"].(string)
+	l.Region, _ = args["region"].(string)
+	lat, hasLat := args["lat"].(float64)
+	long, hasLong := args["long"].(float64)
+	if l.Spot == "" && !(hasLat && hasLong) {
+		return nil, graphQLArgError{errors.New("give spot or lat and long")}
+	}
+	if l.Region != "" && !validRegion(strings.ToUpper(l.Region)) {
+		return nil, graphQLArgError{fmt.Errorf("unknown region %q", l.Region)}
+	}
+	l.Lat, l.Long = lat, long
+	resolved, err := resolveLocation(ctx, l)
+	if err != nil {
+		// Besides unknown spots, spots fail when the KV Store does.
+		if l.Spot == "" || errors.Is(err, errUnknownSpot) {
+			return nil, graphQLArgError{err}
+		}
+		return nil, err
+	}
+	name, _ := args["unit"].(string)
+	if name == "" {
+		name = "ms"
+	}
+	u, ok := units[name]
+	if !ok {
+		return nil, graphQLArgError{fmt.Errorf("unknown unit %q", name)}
+	}
+	days, ok := args["days"].(int)
+	if !ok {
+		days = configInt(ctx, "default_days", 3)
+	}
+	if days < 1 || days > maxDays {
+		return nil, graphQLArgError{fmt.Errorf("days must be between 1 and %d", maxDays)}
+	}
+	hours, ok := args["hours"].(int)
+	if ok && (hours < 1 || hours > days*24) {
+		return nil, graphQLArgError{fmt.Errorf("hours must be between 1 and %d", days*24)}
+	}
+	var from, to time.Time
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		if s, ok := args[p.name].(string); ok {
+			if *p.t, err = time.Parse(time.RFC3339, s); err != nil {
+				return nil, graphQLArgError{fmt.Errorf("invalid %s %q, use an RFC3339 time", p.name, s)}
+			}
+		}
+	}
+	forecasts, errs := fetchAll(ctx, []batchLocation{resolved}, days, hours, u)
+	if errs[0] != nil {
+		return nil, errs[0]
+	}
+	f := forecasts[0]
+	f.entries = slice(f.entries, from, to)
+	r := toResponse(f)
+	return &GraphQLForecast{
+		Location:    r.Location,
+		Units:       r.Units,
+		Region:      r.Region,
+		Height:      r.Height,
+		Timezone:    r.Timezone,
+		GeneratedAt: r.GeneratedAt,
+		Stats:       r.Stats,
+		Warnings:    r.Warnings,
+		Entries:     r.Entries,
+		Days:        aggregateDaily(f),
+	}, nil
+}
+
+func resolveSpots(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	all, err := loadSpots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := []Spot{}
+	for _, s := range all {
+		list = append(list, toSpot(s))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// resolveRegions fetches the prices of the named regions, all by default,
+// for today and tomorrow or the date.
+func resolveRegions(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	rs := elpris.Regions
+	if names, ok := args["names"].([]interface{}); ok {
+		if len(names) > len(zones()) {
+			return nil, graphQLArgError{fmt.Errorf("at most %d names are allowed", len(zones()))}
+		}
+		rs = []string{}
+		for _, n := range names {
+			name, ok := n.(string)
+			if !ok {
+				return nil, graphQLArgError{fmt.Errorf("invalid region %v", n)}
+			}
+			r := strings.ToUpper(name)
+			if !validRegion(r) {
+				return nil, graphQLArgError{fmt.Errorf("unknown region %q, use one of %s", r, strings.Join(elpris.Regions, ", "))}
+			}
+			rs = append(rs, r)
+		}
+	}
+	var date time.Time
+	if s, ok := args["date"].(string); ok {
+		var err error
+		if date, err = time.ParseInLocation("2006-01-02", s, stockholm); err != nil {
+			return nil, graphQLArgError{fmt.Errorf("invalid date %q, use YYYY-MM-DD", s)}
+		}
+	}
+	prices, err := fetchRegions(ctx, rs, date)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]RegionPrices, len(rs))
+	for i, r := range rs {
+		list[i] = toRegionPrices(r, prices[i])
+	}
+	return list, nil
+}
+
+// operation is a parsed GraphQL query.
+type operation struct {
+	name       string
+	defaults   map[string]interface{}
+	selections []*selection
+}
+
+// selection is a field of a selection set.
+type selection struct {
+	alias, name string
+	args        map[string]interface{}
+	selections  []*selection
+}
+
+// key is the key of the field in the response, the alias if given.
+func (s *selection) key() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+// variable is a reference to a variable in an argument.
+type variable string
+
+// graphQLParser is a recursive descent parser of the query subset of the
+// GraphQL language.
+type graphQLParser struct {
+	src   string
+	pos   int
+	depth int
+}
+
+// maxGraphQLDepth limits the nesting of selections and lists.
+const maxGraphQLDepth = 10
+
+func parseGraphQL(src string) (*operation, error) {
+	p := &graphQLParser{src: src}
+	op, err := p.operation()
+	if err != nil {
+		return nil, fmt.Errorf("syntax error: %w", err)
+	}
+	return op, nil
+}
+
+func (p *graphQLParser) operation() (*operation, error) {
+	op := &operation{defaults: map[string]interface{}{}}
+	if p.peek() != '{' {
+		switch kw := p.name(); kw {
+		case "query":
+		case "mutation", "subscription":
+			return nil, fmt.Errorf("only queries are supported, not %s", kw)
+		case "fragment":
+			return nil, errors.New("fragments are not supported")
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", kw, p.pos)
+		}
+		if isNameStart(p.peek()) {
+			op.name = p.name()
+		}
+		if p.peek() == '(' {
+			if err := p.variables(op); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var err error
+	if op.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	if p.peek() != 0 {
+		return nil, errors.New("only one operation is supported")
+	}
+	return op, nil
+}
+
+// variables parses the variable definitions, keeping the default values,
+// null if none. The types are checked when the variables are used.
+func (p *graphQLParser) variables(op *operation) error {
+	p.pos++
+	for p.peek() != ')' {
+		if err := p.expect('$'); err != nil {
+			return err
+		}
+		name := p.name()
+		if err := p.expect(':'); err != nil {
+			return err
+		}
+		op.defaults[name] = nil
+		for c := p.peek(); c == '[' || c == ']' || c == '!' || isNameStart(c); c = p.peek() {
+			if isNameStart(c) {
+				p.name()
+			} else {
+				p.pos++
+			}
+		}
+		if p.peek() == '=' {
+			p.pos++
+			v, err := p.value()
+			if err != nil {
+				return err
+			}
+			op.defaults[name] = v
+		}
+		if p.peek() == 0 {
+			return errors.New("unterminated variable definitions")
+		}
+	}
+	p.pos++
+	return nil
+}
+
+func (p *graphQLParser) selectionSet() ([]*selection, error) {
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	p.depth++
+	if p.depth > maxGraphQLDepth {
+		return nil, errors.New("the query is nested too deep")
+	}
+	selections := []*selection{}
+	for p.peek() != '}' {
+		switch c := p.peek(); {
+		case c == '.':
+			return nil, errors.New("fragments are not supported")
+		case c == '@':
+			return nil, errors.New("directives are not supported")
+		case !isNameStart(c):
+			return nil, p.unexpected()
+		}
+		s := &selection{name: p.name()}
+		if p.peek() == ':' {
+			p.pos++
+			if !isNameStart(p.peek()) {
+				return nil, p.unexpected()
+			}
+			s.alias, s.name = s.name, p.name()
+		}
+		if p.peek() == '(' {
+			p.pos++
+			s.args = map[string]interface{}{}
+			for p.peek() != ')' {
+				if !isNameStart(p.peek()) {
+					return nil, p.unexpected()
+				}
+				name := p.name()
+				if err := p.expect(':'); err != nil {
+					return nil, err
+				}
+				v, err := p.value()
+				if err != nil {
+					return nil, err
+				}
+				s.args[name] = v
+			}
+			p.pos++
+		}
+		if p.peek() == '@' {
+			return nil, errors.New("directives are not supported")
+		}
+		if p.peek() == '{' {
+			var err error
+			if s.selections, err = p.selectionSet(); err != nil {
+				return nil, err
+			}
+		}
+		selections = append(selections, s)
+	}
+	p.pos++
+	p.depth--
+	if len(selections) == 0 {
+		return nil, errors.New("empty selection set")
+	}
+	return selections, nil
+}
+
+// value parses a value, numbers become float64 like in JSON variables.
+func (p *graphQLParser) value() (interface{}, error) {
+	switch c := p.peek(); {
+	case c == '$':
+		p.pos++
+		return variable(p.name()), nil
+	case c == '"':
+		return p.string()
+	case c == '-' || c >= '0' && c <= '9':
+		start := p.pos
+		for p.pos < len(p.src) && strings.ContainsRune("+-.eE0123456789", rune(p.src[p.pos])) {
+			p.pos++
+		}
+		f, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", p.src[start:p.pos])
+		}
+		return f, nil
+	case c == '[':
+		p.pos++
+		p.depth++
+		if p.depth > maxGraphQLDepth {
+			return nil, errors.New("the query is nested too deep")
+		}
+		list := []interface{}{}
+		for p.peek() != ']' {
+			if p.peek() == 0 {
+				return nil, errors.New("unterminated list")
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		p.pos++
+		p.depth--
+		return list, nil
+	case isNameStart(c):
+		switch n := p.name(); n {
+		case "true", "false":
+			return n == "true", nil
+		case "null":
+			return nil, nil
+		default:
+			return n, nil
+		}
+	case c == '{':
+		return nil, errors.New("object values are not supported")
+	}
+	return nil, p.unexpected()
+}
+
+func (p *graphQLParser) string() (string, error) {
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		return "", errors.New("block strings are not supported")
+	}
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.src) && p.src[p.pos] != '"' {
+		if p.src[p.pos] == '\n' {
+			break
+		}
+		if p.src[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.src) || p.src[p.pos] != '"' {
+		return "", errors.New("unterminated string")
+	}
+	p.pos++
+	// The escapes of GraphQL strings are the ones of JSON.
+	var s string
+	if err := json.Unmarshal([]byte(p.src[start:p.pos]), &s); err != nil {
+		return "", fmt.Errorf("invalid string %s", p.src[start:p.pos])
+	}
+	return s, nil
+}
+
+// peek skips white space, commas and comments and returns the next
+// character, 0 at the end.
+func (p *graphQLParser) peek() byte {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return c
+		}
+	}
+	return 0
+}
+
+func (p *graphQLParser) name() string {
+	p.peek()
+	start := p.pos
+	for p.pos < len(p.src) && (isNameStart(p.src[p.pos]) || p.src[p.pos] >= '0' && p.src[p.pos] <= '9') {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *graphQLParser) expect(c byte) error {
+	if p.peek() != c {
+		return fmt.Errorf("expected %q at %d", c, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *graphQLParser) unexpected() error {
+	if p.peek() == 0 {
+		return errors.New("unexpected end of query")
+	}
+	return fmt.Errorf("unexpected %q at %d", p.src[p.pos], p.pos)
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,88 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// HAResponse is the flat object returned by /ha.json, shaped for the
+// RESTful sensor of Home Assistant. Values that are unavailable are null.
+type HAResponse struct {
+	Name              string   `json:"name,omitempty"`
+	Unit              string   `json:"unit"`
+	Region            string   `json:"region"`
+	Speed             *float64 `json:"speed"`
+	Gust              *float64 `json:"gust"`
+	Direction         *float64 `json:"direction"`
+	Price             *float64 `json:"price"`
+	NextSpeed         *float64 `json:"next_speed"`
+	NextGust          *float64 `json:"next_gust"`
+	NextPrice         *float64 `json:"next_price"`
+	CheapWindowStart  string   `json:"cheap_window_start,omitempty"`
+	CheapWindowEnd    string   `json:"cheap_window_end,omitempty"`
+	CheapWindowPrice  *float64 `json:"cheap_window_price"`
+	CheapWindowLength int      `json:"cheap_window_hours"`
+	GeneratedAt       string   `json:"generated_at"`
+}
+
+// toHA returns the current and the next hour of the forecast together with
+// the cheapest window of n consecutive hours from now.
+func toHA(f *forecast, n int) string {
+	r := HAResponse{
+		Name:              f.name,
+		Unit:              f.unit.label,
+		Region:            f.region,
+		CheapWindowLength: n,
+		GeneratedAt:       time.Now().UTC().Format(time.RFC3339),
+	}
+	upcoming := []*entry{}
+	for _, e := range f.entries {
+		if !e.past {
+			upcoming = append(upcoming, e)
+		}
+	}
+	value := func(i int, v func(e *entry) float64) *float64 {
+		if i >= len(upcoming) || f.noWind {
+			return nil
+		}
+		x := round(v(upcoming[i]), 2)
+		return &x
+	}
+	price := func(i int) *float64 {
+		if i >= len(upcoming) || !upcoming[i].hasPrice {
+			return nil
+		}
+		x := round(upcoming[i].price, 4)
+		return &x
+	}
+	r.Speed = value(0, func(e *entry) float64 { return e.speed })
+	r.Gust = value(0, func(e *entry) float64 { return e.gust })
+	r.Direction = value(0, func(e *entry) float64 { return e.direction })
+	r.Price = price(0)
+	r.NextSpeed = value(1, func(e *entry) float64 { return e.speed })
+	r.NextGust = value(1, func(e *entry) float64 { return e.gust })
+	r.NextPrice = price(1)
+	if w := cheapWindow(f, n); w != nil {
+		avg := round(average(w), 4)
+		r.CheapWindowStart = w[0].hour.In(stockholm).Format(time.RFC3339)
+		r.CheapWindowEnd = w[n-1].hour.Add(time.Hour).In(stockholm).Format(time.RFC3339)
+		r.CheapWindowPrice = &avg
+	}
+	b, _ := json.MarshalIndent(r, "", "  ")
+	return string(b)
+}
+
+// cheapWindow returns the cheapest n consecutive hours of the forecast
+// from now, nil if the prices don't cover n hours.
+func cheapWindow(f *forecast, n int) []*entry {
+	prices := []*entry{}
+	for _, e := range f.entries {
+		if !e.past && e.hasPrice {
+			prices = append(prices, e)
+		}
+	}
+	if w := cheapest(prices, n, 1); len(w) > 0 {
+		return w[0]
+	}
+	return nil
+}
@@ -0,0 +1,626 @@
+//go:build nethttp
+
+package httpapi_test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"compute-starter-kit-go/httpapi"
+	"github.com/andybalholm/brotli"
+)
+
+// TestHandlerFixtures runs the handler chain with the recorded upstream
+// responses, go test -tags nethttp ./httpapi.
+func TestHandlerFixtures(t *testing.T) {
+	t.Setenv("WINDY_FIXTURES", "on")
+	srv := httptest.NewServer(httpapi.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+		check       func(t *testing.T, body []byte)
+	}{
+		{"/wind.json", http.StatusOK, "application/json", func(t *testing.T, body []byte) {
+			var r httpapi.Response
+			if err := json.Unmarshal(body, &r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Region != "SE4" || r.Units.Price != "SEK/kWh" || len(r.Entries) == 0 {
+				t.Errorf("got region %s, price unit %s and %d entries", r.Region, r.Units.Price, len(r.Entries))
+			}
+		}},
+		{"/wind.json?days=1&past_days=1", http.StatusOK, "application/json", func(t *testing.T, body []byte) {
+			var r httpapi.Response
+			if err := json.Unmarshal(body, &r); err != nil {
+				t.Fatal(err)
+			}
+			if len(r.Entries) != 48 || !r.Entries[0].Past {
+				t.Errorf("got %d entries, want 48 from yesterday", len(r.Entries))
+			}
+		}},
+		{"/wind.txt", http.StatusOK, "text/plain; charset=utf-8", func(t *testing.T, body []byte) {
+			if !strings.HasPrefix(string(body), "Wind at browser location (55.67, 13.06) in m/s, prices in SEK/kWh for SE4") {
+				t.Errorf("got %.100q", body)
+			}
+		}},
+		{"/prices.json?regions=SE3,SE4", http.StatusOK, "application/json", func(t *testing.T, body []byte) {
+			var r httpapi.PricesResponse
+			if err := json.Unmarshal(body, &r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Unit != "SEK/kWh" || len(r.Regions) != 2 || r.Regions[1].Region != "SE4" || len(r.Regions[1].Prices) == 0 {
+				t.Errorf("got %+v", r)
+			}
+		}},
+		{"/nothing-here", http.StatusNotFound, "application/problem+json", func(t *testing.T, body []byte) {
+			var p httpapi.Problem
+			if err := json.Unmarshal(body, &p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Status != http.StatusNotFound || p.Title != "Not Found" || p.Instance != "/nothing-here" || p.RequestID != "test-request-1" {
+				t.Errorf("got %+v", p)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req, err := http.NewRequest("GET", srv.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("X-Request-ID", "test-request-1")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("got status %d, want %d: %.200s", resp.StatusCode, tt.status, body)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("got Content-Type %q, want %q", ct, tt.contentType)
+			}
+			if id := resp.Header.Get("X-Request-ID"); id != "test-request-1" {
+				t.Errorf("got X-Request-ID %q", id)
+			}
+			tt.check(t, body)
+		})
+	}
+}
+
+// TestAlerts registers alerts with an API key until max_alerts and with
+// the admin-token, and deletes one.
+func TestAlerts(t *testing.T) {
+	t.Setenv("WINDY_FIXTURES", "on")
+	t.Setenv("WINDY_MAX_ALERTS", "2")
+	t.Setenv("WINDY_SECRET_ADMIN_TOKEN", "admin-secret")
+	t.Setenv("WINDY_SECRET_API_KEYS", `{"free-key": "free"}`)
+	srv := httptest.NewServer(httpapi.Handler())
+	defer srv.Close()
+
+	send := func(method, path, token string) (*http.Response, []byte) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(`{"latitude": 55.67, "longitude": 13.06, "region": "SE4", "ntfy": "windy-lomma"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, body
+	}
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"without a token", "", http.StatusUnauthorized},
+		{"unknown key", "other-key", http.StatusUnauthorized},
+		{"first alert of the key", "free-key", http.StatusCreated},
+		{"second alert of the key", "free-key", http.StatusCreated},
+		{"over max_alerts", "free-key", http.StatusForbidden},
+		{"admin-token", "admin-secret", http.StatusCreated},
+	}
+	var created []httpapi.Alert
+	for _, tt := range tests {
+		resp, body := send("POST", "/alerts", tt.token)
+		if resp.StatusCode != tt.status {
+			t.Fatalf("%s: got status %d, want %d: %s", tt.name, resp.StatusCode, tt.status, body)
+		}
+		if resp.StatusCode == http.StatusCreated {
+			var a httpapi.Alert
+			if err := json.Unmarshal(body, &a); err != nil {
+				t.Fatal(err)
+			}
+			created = append(created, a)
+		}
+	}
+
+	if resp, body := send("DELETE", "/alerts/"+created[0].ID, ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete: got status %d: %s", resp.StatusCode, body)
+	}
+	if resp, _ := send("GET", "/alerts/"+created[0].ID, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("got status %d for the deleted alert", resp.StatusCode)
+	}
+	if resp, body := send("POST", "/alerts", "free-key"); resp.StatusCode != http.StatusCreated {
+		t.Errorf("got status %d after deleting an alert of the key: %s", resp.StatusCode, body)
+	}
+	resp, body := send("GET", "/alerts", "admin-secret")
+	var list []httpapi.Alert
+	if err := json.Unmarshal(body, &list); err != nil {
+		t.Fatalf("status %d: %v", resp.StatusCode, err)
+	}
+	if len(list) != 3 {
+		t.Errorf("got %d alerts, want 3", len(list))
+	}
+}
+
+// TestConsumerPrices checks that the tariff of DK1 is added in DKK.
+func TestConsumerPrices(t *testing.T) {
+	t.Setenv("WINDY_FIXTURES", "on")
+	t.Setenv("WINDY_SECRET_ENTSOE_TOKEN", "test-token")
+	srv := httptest.NewServer(httpapi.Handler())
+	defer srv.Close()
+
+	get := func(query string) httpapi.Response {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/wind.json?zone=DK1&currency=DKK" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var r httpapi.Response
+		if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	spot, consumer := get(""), get("&price=consumer&grid_fee=0.1")
+	if consumer.Tariff == nil || consumer.Tariff.Currency != "DKK" || consumer.Tariff.VAT != 25 || consumer.Tariff.EnergyTax != 0.761 {
+		t.Fatalf("got tariff %+v", consumer.Tariff)
+	}
+	n := 0
+	for i, e := range spot.Entries {
+		if e.Price == nil || consumer.Entries[i].Price == nil {
+			continue
+		}
+		n++
+		if want := (*e.Price + 0.761 + 0.1) * 1.25; math.Abs(*consumer.Entries[i].Price-want) > 0.001 {
+			t.Errorf("entry %d: got %v DKK/kWh, want %v", i, *consumer.Entries[i].Price, want)
+		}
+	}
+	if n == 0 {
+		t.Error("got no prices")
+	}
+}
+
+// TestAPIKeys checks the Authorization header and the rate limit of the
+// API keys.
+func TestAPIKeys(t *testing.T) {
+	t.Setenv("WINDY_FIXTURES", "on")
+	t.Setenv("WINDY_API_KEYS", "required")
+	t.Setenv("WINDY_RATE_FREE", "3")
+	t.Setenv("WINDY_SECRET_API_KEYS", `{"free-key": "free"}`)
+	srv := httptest.NewServer(httpapi.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		authorization string
+		status        int
+	}{
+		{"", http.StatusUnauthorized},
+		{"free-key", http.StatusUnauthorized},
+		{"Basic free-key", http.StatusUnauthorized},
+		{"Bearerfree-key", http.StatusUnauthorized},
+		{"Bearer free-key", http.StatusOK},
+		{"bearer free-key", http.StatusOK},
+		{"Bearer free-key", http.StatusOK},
+		{"Bearer free-key", http.StatusTooManyRequests},
+	}
+	for i, tt := range tests {
+		req, err := http.NewRequest("GET", srv.URL+"/wind.json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", tt.authorization)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("request %d with %q: got status %d, want %d", i, tt.authorization, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+// TestPrefs saves the preferences and checks that only the signed cookie
+// is applied.
+func TestPrefs(t *testing.T) {
+	t.Setenv("WINDY_FIXTURES", "on")
+	t.Setenv("WINDY_SECRET_COOKIE_KEY", "cookie-secret")
+	srv := httptest.NewServer(httpapi.Handler())
+	defer srv.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Post(srv.URL+"/prefs", "application/x-www-form-urlencoded", strings.NewReader("unit=kn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	cookies := resp.Cookies()
+	if resp.StatusCode != http.StatusSeeOther || len(cookies) != 1 {
+		t.Fatalf("got status %d and cookies %v", resp.StatusCode, cookies)
+	}
+	signed := cookies[0].Value
+	tests := []struct {
+		name   string
+		cookie string
+		speed  string
+	}{
+		{"signed", signed, "knots"},
+		{"tampered", strings.Replace(signed, ".", ".x", 1), "m/s"},
+		{"unsigned", strings.SplitN(signed, ".", 2)[0], "m/s"},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", srv.URL+"/wind.json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.AddCookie(&http.Cookie{Name: "prefs", Value: tt.cookie})
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r httpapi.Response
+		err = json.NewDecoder(resp.Body).Decode(&r)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Units.Speed != tt.speed {
+			t.Errorf("%s: got speed in %s, want %s", tt.name, r.Units.Speed, tt.speed)
+		}
+	}
+}
+
+// TestCompression checks that JSON is compressed with the preferred
+// encoding the client accepts.
+func TestCompression(t *testing.T) {
+	t.Setenv("WINDY_FIXTURES", "on")
+	srv := httptest.NewServer(httpapi.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		acceptEncoding string
+		encoding       string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0, gzip", "gzip"},
+		{"identity", ""},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", srv.URL+"/wind.json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if got := resp.Header.Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%q: got Content-Encoding %q, want %q", tt.acceptEncoding, got, tt.encoding)
+			continue
+		}
+		var body io.Reader = resp.Body
+		switch tt.encoding {
+		case "gzip":
+			if body, err = gzip.NewReader(resp.Body); err != nil {
+				t.Fatal(err)
+			}
+		case "br":
+			body = brotli.NewReader(resp.Body)
+		}
+		var r httpapi.Response
+		if err := json.NewDecoder(body).Decode(&r); err != nil {
+			t.Errorf("%q: %v", tt.acceptEncoding, err)
+		}
+	}
+}
+
+// TestCacheHeaders checks that the coordinates are redirected to their
+// bucket and that only the responses of the preferences vary on cookies.
+func TestCacheHeaders(t *testing.T) {
+	t.Setenv("WINDY_FIXTURES", "on")
+	srv := httptest.NewServer(httpapi.Handler())
+	defer srv.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	tests := []struct {
+		path         string
+		cookie       string
+		status       int
+		location     string
+		cacheControl string
+		vary         bool
+	}{
+		{"/wind.json?lat=55.67&long=13.06&unit=kn", "", http.StatusMovedPermanently, "/wind.json?lat=55.65&long=13.05&unit=kn", "public, max-age=86400", false},
+		{"/wind.json?lat=55.65&long=13.05", "", http.StatusOK, "", "public, max-age=300, stale-while-revalidate=900", false},
+		{"/wind.json?lat=55.65&long=13.05", "visitor=abc", http.StatusOK, "", "public, max-age=300, stale-while-revalidate=900", false},
+		{"/wind.json?lat=55.65&long=13.05", "unit=kn", http.StatusOK, "", "private, max-age=300", true},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", srv.URL+tt.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.cookie != "" {
+			req.Header.Set("Cookie", tt.cookie)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		varyCookie := strings.Contains(strings.Join(resp.Header.Values("Vary"), ","), "Cookie")
+		if resp.StatusCode != tt.status || resp.Header.Get("Location") != tt.location || resp.Header.Get("Cache-Control") != tt.cacheControl || varyCookie != tt.vary {
+			t.Errorf("%s with %q: got status %d, Location %q, Cache-Control %q and Vary %q", tt.path, tt.cookie, resp.StatusCode, resp.Header.Get("Location"), resp.Header.Get("Cache-Control"), resp.Header.Values("Vary"))
+		}
+	}
+}
+
+// TestSnapshot checks that a forecast fetched from Open-Meteo is stored as
+// the snapshot of the day.
+func TestSnapshot(t *testing.T) {
+	t.Setenv("WINDY_FIXTURES", "on")
+	srv := httptest.NewServer(httpapi.Handler())
+	defer srv.Close()
+
+	for _, path := range []string{"/wind.json?lat=55.65&long=13.05", "/wind.json?lat=55.65&long=13.05&unit=kn"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	resp, err := http.Get(srv.URL + "/accuracy.json?lat=55.65&long=13.05&days=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var r httpapi.AccuracyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		t.Fatalf("status %d: %v", resp.StatusCode, err)
+	}
+	if r.Snapshots != 1 {
+		t.Errorf("got %d snapshots, want 1", r.Snapshots)
+	}
+}
+
+// TestGraphQL checks the validation of the arguments and the number of
+// fields, and that the errors of the upstreams are not returned.
+func TestGraphQL(t *testing.T) {
+	t.Setenv("WINDY_FIXTURES", "on")
+	srv := httptest.NewServer(httpapi.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		data   string
+		errors string
+	}{
+		{"null in a list of non-null", `{ regions(names: [null]) { region } }`, http.StatusBadRequest, "", `invalid argument "names" on field "regions": expected String!, got null`},
+		{"null variable in a list of non-null", `query($r: String) { regions(names: [$r]) { region } }`, http.StatusBadRequest, "", "expected String!, got null"},
+		{"regions", `{ regions(names: ["se4"]) { region } }`, http.StatusOK, `{"regions":[{"region":"SE4"}]}`, ""},
+		{"too many fields", `{ a: spots { id } b: spots { id } c: spots { id } d: spots { id } e: spots { id } f: spots { id } g: spots { id } h: spots { id } i: spots { id } j: spots { id } k: spots { id } }`, http.StatusBadRequest, "", "at most 10"},
+		{"hours out of range", `{ forecast(lat: 55.65, long: 13.05, days: 1, hours: 25) { region } }`, http.StatusOK, `{"forecast":null}`, "hours must be between 1 and 24"},
+		{"upstream error", `{ prices: regions(names: ["DK1"]) { region } }`, http.StatusOK, `{"prices":null}`, "prices is unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]string{"query": tt.query})
+			resp, err := http.Post(srv.URL+"/graphql", "application/json", strings.NewReader(string(body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var r struct {
+				Data   json.RawMessage        `json:"data"`
+				Errors []httpapi.GraphQLError `json:"errors"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.data != "" && string(r.Data) != tt.data {
+				t.Errorf("got data %s, want %s", r.Data, tt.data)
+			}
+			switch {
+			case tt.errors == "" && len(r.Errors) > 0:
+				t.Errorf("got errors %+v", r.Errors)
+			case tt.errors != "" && (len(r.Errors) != 1 || !strings.Contains(r.Errors[0].Message, tt.errors)):
+				t.Errorf("got errors %+v, want %q", r.Errors, tt.errors)
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	t.Setenv("WINDY_FIXTURES", "on")
+	srv := httptest.NewServer(httpapi.Handler())
+	defer srv.Close()
+
+	priceLine := func(t *testing.T, query string) string {
+		resp, err := http.Get(srv.URL + "/current/metrics?lat=55.65&long=13.05" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d: %s", resp.StatusCode, body)
+		}
+		for _, l := range strings.Split(string(body), "\n") {
+			if strings.HasPrefix(l, "windy_price_sek_per_kwh{") {
+				return l
+			}
+		}
+		t.Fatalf("no price metric in %s", body)
+		return ""
+	}
+	want := priceLine(t, "")
+
+	tests := []struct {
+		name     string
+		query    string
+		currency string
+	}{
+		{"currency parameter", "&currency=EUR", ""},
+		{"default currency", "", "NOK"},
+		{"unit parameter", "&unit=kn", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.currency != "" {
+				t.Setenv("WINDY_DEFAULT_CURRENCY", tt.currency)
+			}
+			if got := priceLine(t, tt.query); got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestTextHeadline(t *testing.T) {
+	t.Setenv("WINDY_FIXTURES", "on")
+	srv := httptest.NewServer(httpapi.Handler())
+	defer srv.Close()
+
+	get := func(t *testing.T, path string) []byte {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d: %s", resp.StatusCode, body)
+		}
+		return body
+	}
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"default", ""},
+		{"past days", "?past_days=1"},
+		{"knots", "?unit=kn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r httpapi.Response
+			if err := json.Unmarshal(get(t, "/wind.json"+tt.query), &r); err != nil {
+				t.Fatal(err)
+			}
+			want := ""
+			for _, e := range r.Entries {
+				if !e.Past {
+					h, err := time.Parse(time.RFC3339, e.Hour)
+					if err != nil {
+						t.Fatal(err)
+					}
+					want = h.Format("Mon 15:04")
+					break
+				}
+			}
+			if want == "" {
+				t.Fatal("no current hour in the forecast")
+			}
+			lines := strings.Split(string(get(t, "/wind.txt"+tt.query)), "\n")
+			if len(lines) < 3 {
+				t.Fatalf("got %q", lines)
+			}
+			if !strings.HasPrefix(lines[2], want+"  ") {
+				t.Errorf("got headline %q, want the hour %s", lines[2], want)
+			}
+		})
+	}
+}
+
+func TestCheapest(t *testing.T) {
+	t.Setenv("WINDY_FIXTURES", "on")
+	srv := httptest.NewServer(httpapi.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		name          string
+		query         string
+		defaultRegion string
+		status        int
+		region        string
+	}{
+		{"default", "", "", http.StatusOK, "SE4"},
+		{"region", "?region=se3", "", http.StatusOK, "SE3"},
+		{"zone", "?zone=SE2", "", http.StatusOK, "SE2"},
+		{"zone before region", "?zone=SE1&region=SE3", "", http.StatusOK, "SE1"},
+		{"default region", "", "SE3", http.StatusOK, "SE3"},
+		{"unknown zone", "?zone=XX", "", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.defaultRegion != "" {
+				t.Setenv("WINDY_DEFAULT_REGION", tt.defaultRegion)
+			}
+			resp, err := http.Get(srv.URL + "/cheapest.json" + tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var r httpapi.CheapestResponse
+			if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Region != tt.region || len(r.Windows) != 1 {
+				t.Errorf("got region %s and %d windows, want %s and 1", r.Region, len(r.Windows), tt.region)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package httpapi
+
+import (
+	"strconv"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// headWriter discards the body of a HEAD response and sends the headers,
+// with the Content-Length the body would have had, when finished.
+type headWriter struct {
+	fsthttp.ResponseWriter
+	code   int
+	length int
+}
+
+func (w *headWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *headWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = fsthttp.StatusOK
+	}
+	w.length += len(p)
+	return len(p), nil
+}
+
+// finish writes the headers.
+func (w *headWriter) finish() {
+	if w.code == 0 {
+		w.code = fsthttp.StatusOK
+	}
+	if w.code != fsthttp.StatusNoContent && w.code != fsthttp.StatusNotModified {
+		w.Header().Set("Content-Length", strconv.Itoa(w.length))
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+}
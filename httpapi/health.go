@@ -0,0 +1,98 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// readyTimeout is the time budget of each readiness check.
+const readyTimeout = 2 * time.Second
+
+// readyChecks are the upstreams checked by /readyz and the URLs sent a HEAD
+// request.
+var readyChecks = map[string]string{
+	"open-meteo": "https://api.open-meteo.com/v1/forecast?latitude=55.6&longitude=13.0&hourly=windspeed_10m",
+	"elpris":     "https://www.elprisetjustnu.se/",
+}
+
+// Dependency is the status of an upstream in /readyz.
+type Dependency struct {
+	Status string  `json:"status"`
+	Code   int     `json:"code,omitempty"`
+	Ms     float64 `json:"ms"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// ReadyResponse is the JSON representation of /readyz.
+type ReadyResponse struct {
+	Status       string                `json:"status"`
+	Dependencies map[string]Dependency `json:"dependencies"`
+}
+
+// handleHealth handles /healthz, the service is up if it responds.
+func handleHealth(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.Header().Set("Cache-Control", "no-store")
+	fmt.Fprintln(rw, "OK")
+}
+
+// handleReady handles /readyz and checks the upstreams concurrently with
+// uncached HEAD requests. It responds with 503 if one of them fails.
+func handleReady(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	r := ReadyResponse{Status: "ok", Dependencies: map[string]Dependency{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for upstream, u := range readyChecks {
+		wg.Add(1)
+		go func(upstream, u string) {
+			defer wg.Done()
+			d := checkUpstream(ctx, upstream, u)
+			mu.Lock()
+			defer mu.Unlock()
+			r.Dependencies[upstream] = d
+		}(upstream, u)
+	}
+	wg.Wait()
+	status := fsthttp.StatusOK
+	for _, d := range r.Dependencies {
+		if d.Status != "ok" {
+			r.Status = "unavailable"
+			status = fsthttp.StatusServiceUnavailable
+		}
+	}
+	b, _ := json.MarshalIndent(r, "", "  ")
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.WriteHeader(status)
+	fmt.Fprintf(rw, "%s\n", b)
+}
+
+// checkUpstream sends a HEAD request to the backend of the upstream, any
+// status below 500 means it is up.
+func checkUpstream(ctx context.Context, upstream, u string) Dependency {
+	var d Dependency
+	start := time.Now()
+	resp, err := withTimeout(ctx, upstream, readyTimeout, func(ctx context.Context) (*fsthttp.Response, error) {
+		req, err := newUpstreamRequest(ctx, "HEAD", u)
+		if err != nil {
+			return nil, err
+		}
+		req.CacheOptions.Pass = true
+		return sendUpstream(ctx, req, config("backend_"+upstream, upstream))
+	})
+	d.Ms = round(float64(time.Since(start).Microseconds())/1000, 1)
+	switch {
+	case err != nil:
+		d.Status, d.Error = "failed", err.Error()
+	case resp.StatusCode >= 500:
+		d.Status, d.Code = "failed", resp.StatusCode
+	default:
+		d.Status, d.Code = "ok", resp.StatusCode
+	}
+	return d
+}
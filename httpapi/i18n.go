@@ -0,0 +1,57 @@
+package httpapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// parseLang reads the lang parameter, with the prefs cookie as default,
+// and otherwise uses the first supported language of Accept-Language.
+func parseLang(req *fsthttp.Request) (string, error) {
+	l := req.URL.Query().Get("lang")
+	if l == "" {
+		return acceptLanguage(req.Header.Get("Accept-Language")), nil
+	}
+	if !contains(render.Languages, l) {
+		return "", fmt.Errorf("unknown lang %q, use one of %s", l, strings.Join(render.Languages, ", "))
+	}
+	return l, nil
+}
+
+// pageLang returns the language of a page that can't fail, e.g. an error
+// page, English for an unknown lang parameter.
+func pageLang(req *fsthttp.Request) string {
+	l, err := parseLang(req)
+	if err != nil {
+		return render.Languages[0]
+	}
+	return l
+}
+
+// acceptLanguage returns the supported language with the highest weight in
+// an Accept-Language header, e.g. sv for sv-SE,sv;q=0.9,en;q=0.8, English
+// when none is supported.
+func acceptLanguage(header string) string {
+	best, bestQ := render.Languages[0], 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !contains(render.Languages, base) {
+			continue
+		}
+		q := 1.0
+		if p := strings.TrimSpace(params); strings.HasPrefix(p, "q=") {
+			if f, err := strconv.ParseFloat(strings.TrimPrefix(p, "q="), 64); err == nil {
+				q = f
+			}
+		}
+		if q > bestQ {
+			best, bestQ = base, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,146 @@
+package httpapi
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// window is a period of consecutive hours with enough wind.
+type window struct {
+	start, end time.Time
+	entries    []*entry
+}
+
+// parseWindowLimits reads the minimum wind speed, in the unit of the
+// forecast and by default 8 m/s, and the optional maximum price of the
+// windows in the currency of the forecast.
+func parseWindowLimits(req *fsthttp.Request, u unit, c currency) (float64, *float64, error) {
+	q := req.URL.Query()
+	min := u.convert(calmWind)
+	if s := q.Get("min"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 {
+			return 0, nil, fmt.Errorf("invalid min %q, use a wind speed in %s", s, u.label)
+		}
+		min = v
+	}
+	if s := q.Get("max_price"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid max_price %q, use a price in %s", s, c.priceUnit())
+		}
+		return min, &v, nil
+	}
+	return min, nil, nil
+}
+
+// windyWindows returns the periods where the wind is at least min and,
+// with a maximum price, the price at most maxPrice. Hours without a price
+// are not cheap enough. Each entry lasts until the next.
+func windyWindows(entries []*entry, min float64, maxPrice *float64) []window {
+	step := time.Hour
+	if len(entries) > 1 {
+		step = entries[1].hour.Sub(entries[0].hour)
+	}
+	windows := []window{}
+	var w *window
+	for _, e := range entries {
+		ok := !e.past && e.speed >= min && (maxPrice == nil || e.hasPrice && e.price <= *maxPrice)
+		if !ok {
+			w = nil
+			continue
+		}
+		if w == nil {
+			windows = append(windows, window{start: e.hour})
+			w = &windows[len(windows)-1]
+		}
+		w.entries = append(w.entries, e)
+		w.end = e.hour.Add(step)
+	}
+	return windows
+}
+
+// toICS returns an iCalendar with an event for each windy window, linking
+// to the forecast on base, e.g. https://windy.edgecompute.app.
+func toICS(f *forecast, windows []window, base string) string {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(foldICS(s) + "\r\n")
+	}
+	now := time.Now().UTC().Format(icsTime)
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//windy//wind forecast//EN")
+	line("CALSCALE:GREGORIAN")
+	name := f.name
+	if name == "" {
+		name = f.lat + ", " + f.long
+	}
+	line("X-WR-CALNAME:" + escapeICS("Windy "+name))
+	line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	line("X-PUBLISHED-TTL:PT1H")
+	for _, w := range windows {
+		maxSpeed, maxGust := 0.0, 0.0
+		for _, e := range w.entries {
+			maxSpeed, maxGust = math.Max(maxSpeed, e.speed), math.Max(maxGust, e.gust)
+		}
+		summary := fmt.Sprintf("Wind up to %.1f %s, gusts %.1f %s", maxSpeed, f.unit.label, maxGust, f.unit.label)
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:%s-%s,%s@windy", w.start.UTC().Format(icsTime), f.lat, f.long))
+		line("DTSTAMP:" + now)
+		line("DTSTART:" + w.start.UTC().Format(icsTime))
+		line("DTEND:" + w.end.UTC().Format(icsTime))
+		line("SUMMARY:" + escapeICS(summary))
+		line("DESCRIPTION:" + escapeICS(describeWindow(f, w)))
+		line(fmt.Sprintf("GEO:%s;%s", f.lat, f.long))
+		q := url.Values{"lat": {f.lat}, "long": {f.long}, "unit": {f.unit.name}, "from": {w.start.UTC().Format(time.RFC3339)}, "to": {w.end.UTC().Format(time.RFC3339)}}
+		line("URL:" + base + "/wind.html?" + q.Encode())
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+const icsTime = "20060102T150405Z"
+
+// describeWindow lists the hours of the window.
+func describeWindow(f *forecast, w window) string {
+	lines := []string{}
+	for _, e := range w.entries {
+		l := fmt.Sprintf("%s %.1f (%.1f) %s from %.0f°", e.hour.In(f.timezone).Format("15:04"), e.speed, e.gust, f.unit.label, e.direction)
+		if e.hasPrice {
+			l += fmt.Sprintf(", %.2f %s", e.price, f.currency.priceUnit())
+		}
+		lines = append(lines, l)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeICS escapes the text of a property value, RFC 5545 3.3.11.
+func escapeICS(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// foldICS folds a content line longer than 75 octets, without splitting
+// UTF-8 characters.
+func foldICS(s string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		l := len(string(r))
+		if n+l > 75 {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += l
+	}
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package httpapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// influxEscape escapes the commas, equal signs and spaces of tag keys and
+// values of the line protocol.
+var influxEscape = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// toInflux returns the forecast as InfluxDB line protocol, a wind point per
+// hour tagged with the location and timestamped in nanoseconds. The price
+// field is left out of hours without a price.
+func toInflux(f *forecast) string {
+	// The tags are sorted by key, as InfluxDB prefers.
+	tags := "wind,lat=" + influxEscape.Replace(f.lat) + ",long=" + influxEscape.Replace(f.long)
+	if f.name != "" {
+		tags += ",name=" + influxEscape.Replace(f.name)
+	}
+	tags += ",region=" + influxEscape.Replace(f.region) + ",unit=" + influxEscape.Replace(f.unit.name)
+	var b strings.Builder
+	for _, e := range f.entries {
+		fields := []string{}
+		if !f.noWind {
+			fields = append(fields,
+				"speed="+influxFloat(e.speed),
+				"gust="+influxFloat(e.gust),
+				"direction="+influxFloat(e.direction),
+				"temperature="+influxFloat(e.temperature),
+				"precipitation="+influxFloat(e.precipitation),
+			)
+		}
+		if e.hasPrice {
+			fields = append(fields, "price="+influxFloat(e.price))
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %s %d\n", tags, strings.Join(fields, ","), e.hour.UnixNano())
+	}
+	return b.String()
+}
+
+func influxFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
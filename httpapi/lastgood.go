@@ -0,0 +1,119 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// lastGood is the most recent complete forecast of a location bucket and
+// region, served when all upstreams are down. Speeds are in m/s.
+type lastGood struct {
+	Saved    time.Time     `json:"saved"`
+	Timezone string        `json:"timezone"`
+	Entries  []storedEntry `json:"entries"`
+}
+
+type storedEntry struct {
+	Hour                     int64   `json:"t"`
+	Speed                    float64 `json:"s"`
+	Gust                     float64 `json:"g"`
+	Direction                float64 `json:"d"`
+	Temperature              float64 `json:"temp"`
+	ApparentTemperature      float64 `json:"app"`
+	Precipitation            float64 `json:"pr"`
+	PrecipitationProbability float64 `json:"pp"`
+	Price                    float64 `json:"p"`
+	HasPrice                 bool    `json:"hp"`
+	Score                    float64 `json:"sc"`
+}
+
+// lastGoodKey buckets the location to a tenth of a degree, about 10 km, so
+// nearby requests share the fallback.
+func lastGoodKey(lat, long, region string, height int) string {
+	la, _ := strconv.ParseFloat(lat, 64)
+	lo, _ := strconv.ParseFloat(long, 64)
+	return fmt.Sprintf("lastgood/%.1f,%.1f/%s/%d", la, lo, region, height)
+}
+
+// saveLastGood stores the entries, in m/s, as the fallback of the location
+// at most once an hour. Failures are logged.
+func saveLastGood(ctx context.Context, key string, tz *time.Location, entries []*entry) {
+	store, err := openKV()
+	if err != nil {
+		logln(ctx, "last good:", err)
+		return
+	}
+	if lg, err := lookupLastGood(store, key); err == nil && time.Since(lg.Saved) < time.Hour {
+		return
+	}
+	lg := lastGood{Saved: time.Now().UTC(), Timezone: tz.String()}
+	for _, e := range entries {
+		lg.Entries = append(lg.Entries, storedEntry{
+			Hour:                     e.hour.Unix(),
+			Speed:                    e.speed,
+			Gust:                     e.gust,
+			Direction:                e.direction,
+			Temperature:              e.temperature,
+			ApparentTemperature:      e.apparentTemperature,
+			Precipitation:            e.precipitation,
+			PrecipitationProbability: e.precipitationProbability,
+			Price:                    e.price,
+			HasPrice:                 e.hasPrice,
+			Score:                    e.score,
+		})
+	}
+	b, _ := json.Marshal(lg)
+	if err := store.Insert(key, bytes.NewReader(b)); err != nil {
+		logln(ctx, "last good:", err)
+	}
+}
+
+// loadLastGood returns the fallback entries of the location, their
+// timezone and when they were saved.
+func loadLastGood(key string) ([]*entry, *time.Location, time.Time, error) {
+	store, err := openKV()
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	lg, err := lookupLastGood(store, key)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	tz, err := time.LoadLocation(lg.Timezone)
+	if err != nil {
+		tz = time.UTC
+	}
+	now := time.Now().Truncate(time.Hour)
+	entries := mapSlice(lg.Entries, func(s storedEntry) *entry {
+		hour := time.Unix(s.Hour, 0).UTC()
+		return &entry{
+			hour:                     hour,
+			speed:                    s.Speed,
+			gust:                     s.Gust,
+			direction:                s.Direction,
+			temperature:              s.Temperature,
+			apparentTemperature:      s.ApparentTemperature,
+			precipitation:            s.Precipitation,
+			precipitationProbability: s.PrecipitationProbability,
+			price:                    s.Price,
+			hasPrice:                 s.HasPrice,
+			score:                    s.Score,
+			past:                     hour.Before(now),
+		}
+	})
+	return entries, tz, lg.Saved, nil
+}
+
+func lookupLastGood(store kvStore, key string) (lastGood, error) {
+	var lg lastGood
+	e, err := store.Lookup(key)
+	if err != nil {
+		return lg, err
+	}
+	err = json.NewDecoder(e).Decode(&lg)
+	return lg, err
+}
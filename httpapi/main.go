@@ -0,0 +1,1300 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+
+	"compute-starter-kit-go/merge"
+	"compute-starter-kit-go/openmeteo"
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+	"github.com/fastly/compute-sdk-go/geo"
+)
+
+type entry struct {
+	hour      time.Time
+	gust      float64
+	speed     float64
+	direction float64
+	// temperature and apparentTemperature are in °C.
+	temperature         float64
+	apparentTemperature float64
+	// precipitation is in mm, precipitationProbability in percent.
+	precipitation            float64
+	precipitationProbability float64
+	price                    float64
+	hasPrice                 bool
+	// score is how good the hour is for running heavy loads, 0-100.
+	score float64
+	// session is how good the hour is for kitesurfing or windsurfing at
+	// the spot of the forecast, 0-100.
+	session float64
+	// past is set for hours before the current hour, they are observations
+	// or old forecasts rather than predictions.
+	past     bool
+	marine   *marine
+	ensemble *spread
+}
+
+// forecast is the merged wind and price data for a location together with
+// the parameters used to present it.
+type forecast struct {
+	// name is the place name given by the user and near is the place
+	// nearest to given coordinates.
+	name      string
+	near      string
+	lat, long string
+	region    string
+	unit      unit
+	currency  currency
+	// tariff is set when the prices are consumer prices.
+	tariff   *Tariff
+	height   int
+	spot     *spot
+	timezone *time.Location
+	show     map[string]bool
+	// warnings are shown when a part of the forecast is unavailable,
+	// noWind is set when only the prices are available.
+	warnings []string
+	noWind   bool
+	// stale is set when the forecast is an old one from the KV Store.
+	stale bool
+	// debug is the state of the request when the upstream calls and
+	// timings are added to the output, nil otherwise.
+	debug   *requestState
+	entries []*entry
+}
+
+// Serve serves the requests on Fastly Compute.
+func Serve() {
+	fsthttp.ServeFunc(handler())
+}
+
+// handler registers the routes and returns the handler of all requests.
+func handler() fsthttp.HandlerFunc {
+	r := &router{}
+	r.handle("GET", "/", plain(handleRoot))
+	r.handle("GET", "/version", plain(handleVersion))
+	r.handle("GET", "/openapi.json", plain(handleOpenAPI))
+	r.handle("GET", "/docs", plain(handleDocs))
+	r.handle("GET", "/wind.xsd", plain(handleXSD))
+	r.handle("GET", "/static/:name", handleStatic)
+	for _, name := range rootFiles {
+		r.handle("GET", "/"+name, handleRootFile(name))
+	}
+	r.handle("GET", "/sw.js", plain(handleServiceWorker))
+	r.handle("GET", "/oembed", plain(handleOEmbed))
+	r.handle("GET", "/healthz", plain(handleHealth))
+	r.handle("GET", "/readyz", plain(handleReady))
+	r.handle("GET", "/prefs", plain(handlePrefs))
+	r.handle("POST", "/prefs", plain(handlePrefs))
+	r.handle("POST", "/favorites", plain(handleFavorites))
+	r.handle("GET", "/spots", plain(listSpots))
+	r.handle("GET", "/spots/:id", getSpot)
+	r.handle("PUT", "/spots/:id", putSpot)
+	r.handle("DELETE", "/spots/:id", deleteSpot)
+	r.handle("GET", "/alerts", plain(listAlerts))
+	r.handle("POST", "/alerts", plain(createAlert))
+	r.handle("POST", "/alerts/evaluate", plain(handleEvaluate))
+	r.handle("GET", "/alerts/:id", getAlert)
+	r.handle("PUT", "/alerts/:id", putAlert)
+	r.handle("DELETE", "/alerts/:id", deleteAlert)
+	r.handle("GET", "/compare.html", plain(handleCompare))
+	r.handle("GET", "/prices.json", apiKey(plain(handlePrices)))
+	r.handle("GET", "/prices.html", plain(handlePrices))
+	r.handle("GET", "/cheapest.json", apiKey(plain(handleCheapest)))
+	r.handle("GET", "/accuracy.json", apiKey(plain(handleAccuracy)))
+	r.handle("GET", "/score.json", apiKey(plain(handleScore)))
+	r.handle("POST", "/wind/batch.json", apiKey(plain(handleBatch)))
+	r.handle("GET", "/graphql", apiKey(plain(handleGraphQL)))
+	r.handle("POST", "/graphql", apiKey(plain(handleGraphQL)))
+	r.handle("POST", "/admin/purge", plain(handlePurge))
+	r.handle("POST", "/slack", plain(handleSlack))
+	r.handle("POST", "/telegram", plain(handleTelegram))
+	r.handle("POST", "/discord/:spot", handleDiscord)
+	r.handle("POST", "/digest/:spot", handleDigestSend)
+	r.handle("GET", "/grafana", apiKey(handleGrafana))
+	r.handle("GET", "/grafana/", apiKey(handleGrafana))
+	r.handle("POST", "/grafana/search", apiKey(handleGrafanaSearch))
+	r.handle("POST", "/grafana/query", apiKey(handleGrafanaQuery))
+	r.handle("GET", "/grafana/:spot", apiKey(handleGrafana))
+	r.handle("GET", "/grafana/:spot/", apiKey(handleGrafana))
+	r.handle("POST", "/grafana/:spot/search", apiKey(handleGrafanaSearch))
+	r.handle("POST", "/grafana/:spot/query", apiKey(handleGrafanaQuery))
+	for p := range forecastPaths {
+		r.handle("GET", p, handleForecast)
+	}
+	r.handle("GET", "/wind/stream", plain(handleStream))
+	r.handle("POST", "/wind/stream/publish", plain(handlePublish))
+	r.handle("GET", "/wind", handleForecast)
+	r.handle("GET", "/wind/:name", handleForecast)
+	return func(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+		start := time.Now()
+		ctx, state := withRequestState(ctx, req)
+		defer func() { state.logTimings(req, time.Since(start)) }()
+		ctx, cancel := context.WithTimeout(ctx, configDuration(ctx, "deadline", deadline))
+		defer cancel()
+		if req.Method == "HEAD" {
+			hw := &headWriter{ResponseWriter: rw}
+			defer hw.finish()
+			rw = hw
+		}
+		cw := newCompressWriter(rw, req)
+		defer cw.finish()
+		cw.Header().Set("X-Request-ID", state.id)
+		tw := &timingWriter{ResponseWriter: cw, state: state}
+		defer recoverPanic(ctx, tw, req)
+		setCORSHeaders(tw, req)
+		applyPrefs(req, readPrefs(ctx, req))
+		r.serve(ctx, tw, req)
+	}
+}
+
+// forecastPaths are the formats of the forecast, /wind/<name>.json gives
+// the same format as /wind.json.
+var forecastPaths = map[string]bool{
+	"/wind.json":       true,
+	"/wind.html":       true,
+	"/wind.csv":        true,
+	"/wind.txt":        true,
+	"/wind.xml":        true,
+	"/wind.geojson":    true,
+	"/wind.svg":        true,
+	"/wind.png":        true,
+	"/embed.html":      true,
+	"/digest.html":     true,
+	"/digest.txt":      true,
+	"/wind.rss":        true,
+	"/wind.ics":        true,
+	"/wind.influx":     true,
+	"/wind/daily.json": true,
+	"/wind/daily.html": true,
+	"/marine.json":     true,
+	"/marine.html":     true,
+	"/current/metrics": true,
+	"/ha.json":         true,
+}
+
+// lookupGeo looks up the location of the client IP, on failure the error
+// is written and false returned.
+func lookupGeo(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) (*geo.Geo, bool) {
+	ip := net.ParseIP(req.RemoteAddr)
+	if ip == nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, fmt.Errorf("unable to parse the client IP %q", req.RemoteAddr))
+		return nil, false
+	}
+	defer timed(ctx, "geo")()
+	g, err := withTimeout(context.Background(), "the geo lookup", configDuration(ctx, "timeout_geo", geoTimeout), func(context.Context) (*geo.Geo, error) {
+		return lookupIP(ip)
+	})
+	if err != nil && upstreamStatus(err) == fsthttp.StatusGatewayTimeout {
+		writeError(rw, req, fsthttp.StatusGatewayTimeout, err)
+		return nil, false
+	}
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, fmt.Errorf("unable to look up the client location: %w", err))
+		return nil, false
+	}
+	return g, true
+}
+
+func handleRoot(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	rw.Header().Add("Vary", "User-Agent")
+	if terminalClient(req) {
+		req.URL.Path = "/wind.txt"
+		handleForecast(ctx, rw, req, nil)
+		return
+	}
+	g, ok := lookupGeo(ctx, rw, req)
+	if !ok {
+		return
+	}
+	html, err := rootHTML(g, visitorFavorites(ctx, req))
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(rw, html)
+}
+
+// handleForecast handles the forecast formats for the location given by
+// coordinates, place name or spot.
+func handleForecast(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+	city, p := placeName(req, params)
+	if p == "/wind" {
+		rw.Header().Add("Vary", "Accept")
+		rw.Header().Add("Vary", "User-Agent")
+		format, err := negotiate(req)
+		if err == errNotAcceptable {
+			writeError(rw, req, fsthttp.StatusNotAcceptable, err)
+			return
+		}
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadRequest, err)
+			return
+		}
+		p = "/wind." + format
+	}
+	if !forecastPaths[p] {
+		notFound(rw, req)
+		return
+	}
+	if machinePath(p) && !checkAPIKey(ctx, rw, req) {
+		return
+	}
+	g, ok := lookupGeo(ctx, rw, req)
+	if !ok {
+		return
+	}
+	defLat, defLong := g.Latitude, g.Longitude
+	name := ""
+	if city != "" {
+		places, err := geocode(ctx, city, req.URL.Query().Get("country"))
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadGateway, err)
+			return
+		}
+		pl, ok := pickPlace(city, places)
+		if !ok {
+			writePlaces(rw, req, p, city, places)
+			return
+		}
+		defLat, defLong, name = pl.lat, pl.long, pl.String()
+	}
+	sp, err := parseSpot(ctx, req)
+	if errors.Is(err, errUnknownSpot) {
+		writeError(rw, req, fsthttp.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	if sp != nil {
+		defLat, defLong, name = sp.lat, sp.long, sp.name
+	}
+	lat, long, err := parseCoordinates(req, defLat, defLong)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	if redirectToBucket(rw, req) {
+		return
+	}
+	logln(ctx, "latlong", lat, long)
+	near := ""
+	if name == "" && req.URL.Query().Get("lat") != "" {
+		near, err = reverseGeocode(ctx, lat, long)
+		if err != nil {
+			logln(ctx, "reverse geocoding failed", err)
+		}
+	}
+	u, err := parseUnit(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	if req.URL.Query().Get("unit") != "" {
+		setCookie(rw, "unit", u.name)
+	}
+	// The metrics are in SI units.
+	if p == "/current/metrics" {
+		u = units["ms"]
+	}
+	region, err := parseRegion(req, lat)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	if sp != nil && req.URL.Query().Get("region") == "" && req.URL.Query().Get("zone") == "" {
+		region = sp.region
+	}
+	cur, err := parseCurrency(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	// The price metric is in SEK.
+	if p == "/current/metrics" {
+		cur = sek.name
+	}
+	tariff, err := parseTariff(req, region)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	days, hours, err := parseWindow(ctx, req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	pastDays, err := parsePastDays(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	resolution, smoothing, err := parseTransform(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	height, err := parseHeight(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	// Sessions are scored in m/s, convert the units afterwards.
+	f, err := fetchForecast(ctx, lat, long, region, days, hours, pastDays, height, units["ms"])
+	if err != nil {
+		writeError(rw, req, upstreamStatus(err), err)
+		return
+	}
+	if sp != nil {
+		scoreSessions(f.entries, sp)
+	}
+	convertUnits(f.entries, u)
+	if tariff != nil {
+		if err := applyTariff(ctx, f, tariff); err != nil {
+			logln(ctx, "exchange rates unavailable:", err)
+			f.warnings = append(f.warnings, "Exchange rates are unavailable, the prices are spot prices.")
+		}
+	}
+	if c, err := fetchCurrency(ctx, cur); err != nil {
+		logln(ctx, "exchange rates unavailable:", err)
+		f.warnings = append(f.warnings, fmt.Sprintf("Exchange rates are unavailable, prices are in %s.", sek.name))
+	} else {
+		convertPrices(f, c)
+	}
+	f.unit, f.spot = u, sp
+	f.name, f.near, f.show = name, near, parseShow(req)
+	if debugEnabled(req) {
+		f.debug = stateOf(ctx)
+	}
+	if strings.HasPrefix(p, "/marine") {
+		m, err := fetchMarine(ctx, lat, long, days)
+		if err != nil {
+			logln(ctx, "marine forecast unavailable:", err)
+			f.warnings = append(f.warnings, "The sea state is unavailable.")
+		}
+		mergeMarine(f.entries, m)
+	}
+	if f.show["ensemble"] {
+		spreads, err := fetchEnsemble(ctx, lat, long, days)
+		if err != nil {
+			logln(ctx, "ensemble unavailable:", err)
+			f.warnings = append(f.warnings, "The ensemble spread is unavailable.")
+		}
+		mergeEnsemble(f.entries, spreads, u)
+	}
+	from, to, err := parseRange(req, f.timezone)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	f.entries = slice(f.entries, from, to)
+	smooth(f.entries, smoothing)
+	f.entries = resample(f.entries, resolution, f.timezone)
+	// The render timing ends when the header is written.
+	timed(ctx, "render")
+	if p == "/marine.json" {
+		rw.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(rw, "%s\n", toJSON(f))
+		return
+	}
+	if p == "/marine.html" {
+		html, err := toMarineHTML(f, g)
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusInternalServerError, err)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(rw, "%s\n", html)
+		return
+	}
+	if p == "/wind.json" {
+		s, err := parseShape(req)
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadRequest, err)
+			return
+		}
+		setCacheHeaders(rw, req, f)
+		if s != nil {
+			if notModified(rw, req, etag(f, p+s.key()), upstreamModified(ctx)) {
+				return
+			}
+			rw.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(rw, "%s\n", toShapedJSON(f, s))
+			return
+		}
+		if notModified(rw, req, etag(f, p), upstreamModified(ctx)) {
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(rw, "%s\n", toJSON(f))
+		return
+	}
+	if p == "/wind/daily.json" {
+		rw.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(rw, "%s\n", toDailyJSON(f))
+		return
+	}
+	if p == "/wind/daily.html" {
+		html, err := toDailyHTML(f, g)
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusInternalServerError, err)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(rw, "%s\n", html)
+		return
+	}
+	if p == "/wind.csv" {
+		if notModified(rw, req, etag(f, p), upstreamModified(ctx)) {
+			return
+		}
+		rw.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		rw.Header().Set("Content-Disposition", `attachment; filename="wind.csv"`)
+		fmt.Fprint(rw, toCSV(f))
+		return
+	}
+	if p == "/wind.svg" || p == "/wind.png" {
+		// The PNG defaults to the size of Open Graph preview images.
+		width, height, err := parseSize(req, 800, 300)
+		if p == "/wind.png" {
+			width, height, err = parseSize(req, 1200, 630)
+		}
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadRequest, err)
+			return
+		}
+		setCacheHeaders(rw, req, f)
+		if notModified(rw, req, etag(f, p), upstreamModified(ctx)) {
+			return
+		}
+		c := toRenderChart(f, f.title(g))
+		if p == "/wind.svg" {
+			rw.Header().Set("Content-Type", "image/svg+xml")
+			fmt.Fprint(rw, c.SVG(width, height))
+			return
+		}
+		b, err := c.PNG(width, height)
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusInternalServerError, err)
+			return
+		}
+		rw.Header().Set("Content-Type", "image/png")
+		rw.Write(b)
+		return
+	}
+	if p == "/wind.geojson" {
+		setCacheHeaders(rw, req, f)
+		if notModified(rw, req, etag(f, p), upstreamModified(ctx)) {
+			return
+		}
+		rw.Header().Set("Content-Type", "application/geo+json")
+		fmt.Fprintf(rw, "%s\n", toGeoJSON(f))
+		return
+	}
+	if p == "/wind.influx" {
+		setCacheHeaders(rw, req, f)
+		if notModified(rw, req, etag(f, p), upstreamModified(ctx)) {
+			return
+		}
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(rw, toInflux(f))
+		return
+	}
+	if p == "/ha.json" {
+		n, err := intParam(req, "window", 3, 1, 24)
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadRequest, err)
+			return
+		}
+		setCacheHeaders(rw, req, f)
+		rw.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(rw, "%s\n", toHA(f, n))
+		return
+	}
+	if p == "/current/metrics" {
+		setCacheHeaders(rw, req, f)
+		rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		fmt.Fprint(rw, toMetrics(f))
+		return
+	}
+	if p == "/wind.ics" {
+		min, maxPrice, err := parseWindowLimits(req, u, f.currency)
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadRequest, err)
+			return
+		}
+		setCacheHeaders(rw, req, f)
+		rw.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		fmt.Fprint(rw, toICS(f, windyWindows(f.entries, min, maxPrice), "https://"+req.Host))
+		return
+	}
+	if p == "/wind.rss" {
+		setCacheHeaders(rw, req, f)
+		rw.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(rw, toRSS(f, g, "https://"+req.Host))
+		return
+	}
+	if p == "/wind.xml" {
+		setCacheHeaders(rw, req, f)
+		if notModified(rw, req, etag(f, p), upstreamModified(ctx)) {
+			return
+		}
+		rw.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(rw, toXML(f))
+		return
+	}
+	if p == "/wind.txt" {
+		setCacheHeaders(rw, req, f)
+		if notModified(rw, req, etag(f, p), upstreamModified(ctx)) {
+			return
+		}
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(rw, toText(f, req.URL.Query().Get("color") == "1"))
+		return
+	}
+	if p == "/digest.html" || p == "/digest.txt" {
+		d := toDigest(f, g, "https://"+req.Host)
+		body, contentType := toDigestText(d), "text/plain; charset=utf-8"
+		if p == "/digest.html" {
+			html, err := render.Render("digest.html", d)
+			if err != nil {
+				writeError(rw, req, fsthttp.StatusInternalServerError, err)
+				return
+			}
+			body, contentType = html, "text/html; charset=utf-8"
+		}
+		setCacheHeaders(rw, req, f)
+		if notModified(rw, req, etag(f, p+body), upstreamModified(ctx)) {
+			return
+		}
+		rw.Header().Set("Content-Type", contentType)
+		fmt.Fprint(rw, body)
+		return
+	}
+	if p == "/embed.html" {
+		width, height, err := parseSize(req, 800, 300)
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadRequest, err)
+			return
+		}
+		html, err := toEmbedHTML(f, g, "https://"+req.Host, width, height)
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusInternalServerError, err)
+			return
+		}
+		setCacheHeaders(rw, req, f)
+		if notModified(rw, req, etag(f, p+html), upstreamModified(ctx)) {
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(rw, "%s\n", html)
+		return
+	}
+	if p == "/wind.html" {
+		o, err := parseChartOptions(req, f)
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadRequest, err)
+			return
+		}
+		html, err := toHTML(f, g, "https://"+req.Host, o)
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusInternalServerError, err)
+			return
+		}
+		setCacheHeaders(rw, req, f)
+		rw.Header().Add("Vary", "Accept-Language")
+		if notModified(rw, req, etag(f, p+html), upstreamModified(ctx)) {
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(rw, "%s\n", html)
+
+		return
+	}
+}
+
+// fetchForecast fetches the winds and prices for a location and merges them
+// into a forecast. If one of them fails the forecast has what is available
+// and a warning. If both fail the last complete forecast of the location is
+// returned as stale, it is only an error if there is none.
+func fetchForecast(ctx context.Context, lat, long, region string, days, hours, pastDays, height int, u unit) (*forecast, error) {
+	entries, tz, fresh, windErr := fetchWinds(ctx, lat, long, days, hours, pastDays, height)
+	prices, priceErr := fetchPrices(ctx, region)
+	key := lastGoodKey(lat, long, region, height)
+	if windErr != nil && priceErr != nil {
+		entries, tz, saved, err := loadLastGood(key)
+		if err != nil {
+			logln(ctx, "no last good forecast:", err)
+			var te *timeoutError
+			if errors.As(windErr, &te) {
+				return nil, fmt.Errorf("%v; %w", priceErr, windErr)
+			}
+			return nil, fmt.Errorf("%v; %w", windErr, priceErr)
+		}
+		convertUnits(entries, u)
+		f := &forecast{lat: lat, long: long, region: region, unit: u, currency: sek, height: height, timezone: tz, entries: entries, stale: true}
+		f.warnings = append(f.warnings, fmt.Sprintf("The forecast and prices are unavailable, showing the forecast from %s.", saved.In(tz).Format("2006-01-02 15:04")))
+		return f, nil
+	}
+	f := &forecast{lat: lat, long: long, region: region, unit: u, currency: sek, height: height, timezone: tz}
+	if windErr != nil {
+		logln(ctx, "wind forecast unavailable:", windErr)
+		f.warnings = append(f.warnings, "The wind forecast is unavailable, only electricity prices are shown.")
+		f.noWind = true
+		f.timezone = stockholm
+		entries = prices
+	}
+	if priceErr != nil {
+		logln(ctx, "prices unavailable:", priceErr)
+		f.warnings = append(f.warnings, "Electricity prices are unavailable.")
+	}
+	stop := timed(ctx, "merge")
+	mergePrices(entries, prices)
+	scoreEntries(entries)
+	stop()
+	if height == 10 && fresh && !f.noWind {
+		saveSnapshot(ctx, lat, long, entries)
+	}
+	if len(f.warnings) == 0 {
+		saveLastGood(ctx, key, tz, entries)
+	}
+	convertUnits(entries, u)
+	f.entries = entries
+	return f, nil
+}
+
+// fetchWinds fetches the forecast for the given number of days starting at
+// midnight. If hours is not zero only the next hours, starting with the
+// current hour, are returned. The pastDays before today are included as
+// well. The speed and direction are at the given height in meters, gusts
+// are only available at 10 m. Fresh is true when the forecast came from
+// Open-Meteo rather than the cache, at most once per ttl_open-meteo.
+func fetchWinds(ctx context.Context, lat, long string, days, hours, pastDays, height int) ([]*entry, *time.Location, bool, error) {
+	stop := timed(ctx, "wind")
+	fresh := false
+	client := &openmeteo.Client{Transport: transport{fresh: &fresh}}
+	f, err := client.Forecast(ctx, openmeteo.Query{
+		Latitude:  bucket(lat),
+		Longitude: bucket(long),
+		Days:      days,
+		PastDays:  pastDays,
+		Height:    height,
+	})
+	stop()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	now := time.Now().Truncate(time.Hour)
+	start := now.Add(-time.Duration(pastDays) * 24 * time.Hour)
+	entries := []*entry{}
+	future := 0
+	for _, h := range f.Hours {
+		past := h.Time.Before(now)
+		if hours > 0 && (h.Time.Before(start) || !past && future == hours) {
+			continue
+		}
+		if !past {
+			future++
+		}
+		e := entry{
+			hour:                     h.Time,
+			speed:                    h.Speed,
+			gust:                     h.Gust,
+			direction:                h.Direction,
+			temperature:              h.Temperature,
+			apparentTemperature:      h.ApparentTemperature,
+			precipitation:            h.Precipitation,
+			precipitationProbability: h.PrecipitationProbability,
+			past:                     past,
+		}
+		entries = append(entries, &e)
+	}
+	return entries, f.Timezone, fresh, nil
+}
+
+// parseShow reads the comma separated list of optional datasets to show in
+// the chart, e.g. show=temp.
+func parseShow(req *fsthttp.Request) map[string]bool {
+	show := map[string]bool{}
+	for _, s := range strings.Split(req.URL.Query().Get("show"), ",") {
+		if s != "" {
+			show[strings.ToLower(strings.TrimSpace(s))] = true
+		}
+	}
+	return show
+}
+
+// parseHeight reads the height of the wind in meters, Open-Meteo has winds
+// at 10, 80, 120 and 180 m.
+func parseHeight(req *fsthttp.Request) (int, error) {
+	h := req.URL.Query().Get("height")
+	switch h {
+	case "", "10":
+		return 10, nil
+	case "80", "120", "180":
+		return strconv.Atoi(h)
+	}
+	return 0, fmt.Errorf("invalid height %q, use one of 10, 80, 120, 180", h)
+}
+
+// maxPastDays is the number of days before today that can be included.
+const maxPastDays = 7
+
+// parsePastDays reads the number of days before today to include, the
+// default is none.
+func parsePastDays(req *fsthttp.Request) (int, error) {
+	p := req.URL.Query().Get("past_days")
+	if p == "" {
+		return 0, nil
+	}
+	days, err := strconv.Atoi(p)
+	if err != nil || days < 0 || days > maxPastDays {
+		return 0, fmt.Errorf("invalid past_days %q, must be between 0 and %d", p, maxPastDays)
+	}
+	return days, nil
+}
+
+// maxDays is the longest forecast Open-Meteo provides.
+const maxDays = 16
+
+// parseWindow reads the forecast window from the days or hours query
+// parameters, the default is three days.
+func parseWindow(ctx context.Context, req *fsthttp.Request) (int, int, error) {
+	q := req.URL.Query()
+	if d := q.Get("days"); d != "" {
+		days, err := strconv.Atoi(d)
+		if err != nil || days < 1 || days > maxDays {
+			return 0, 0, fmt.Errorf("invalid days %q, must be between 1 and %d", d, maxDays)
+		}
+		return days, 0, nil
+	}
+	if h := q.Get("hours"); h != "" {
+		hours, err := strconv.Atoi(h)
+		if err != nil || hours < 1 || hours > (maxDays-1)*24 {
+			return 0, 0, fmt.Errorf("invalid hours %q, must be between 1 and %d", h, (maxDays-1)*24)
+		}
+		// The forecast starts at midnight, fetch an extra day to cover
+		// the rest of today.
+		days := hours/24 + 2
+		if days > maxDays {
+			days = maxDays
+		}
+		return days, hours, nil
+	}
+	return configInt(ctx, "default_days", 3), 0, nil
+}
+
+// mergePrices sets the prices of the entries at the same hours.
+func mergePrices(entries, prices []*entry) {
+	hour := func(e *entry) time.Time { return e.hour }
+	merge.ByHour(entries, prices, hour, hour, func(e, p *entry) {
+		e.price = p.price
+		e.hasPrice = true
+	})
+}
+
+func fetchPrices(ctx context.Context, region string) ([]*entry, error) {
+	defer timed(ctx, "prices")()
+	today := time.Now().In(stockholm)
+	tomorrow := today.AddDate(0, 0, 1)
+	eToday, err := fetchPrice(ctx, region, today)
+	if err != nil {
+		return nil, err
+	}
+	eTomorrow, err := fetchPrice(ctx, region, tomorrow)
+	if noPrices(err) {
+		return eToday, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(eToday, eTomorrow...), nil
+}
+
+func fetchPrice(ctx context.Context, region string, t time.Time) ([]*entry, error) {
+	return providerFor(region).day(ctx, region, t)
+}
+
+// parseCoordinates parses and validates the lat and long query parameters,
+// defaulting to the given location. The coordinates are formatted
+// with the number of decimals given by the precision parameter, two decimals
+// is about a kilometer which is finer than the forecast grid.
+func parseCoordinates(req *fsthttp.Request, defLat, defLong float64) (string, string, error) {
+	q := req.URL.Query()
+	precision := 2
+	if p := q.Get("precision"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || n > 6 {
+			return "", "", fmt.Errorf("invalid precision %q, must be between 0 and 6", p)
+		}
+		precision = n
+	}
+	lat, long := defLat, defLong
+	if q.Get("lat") != "" || q.Get("long") != "" {
+		var err error
+		lat, err = strconv.ParseFloat(q.Get("lat"), 64)
+		if err != nil || !(lat >= -90 && lat <= 90) {
+			return "", "", fmt.Errorf("invalid lat %q, must be between -90 and 90", q.Get("lat"))
+		}
+		long, err = strconv.ParseFloat(q.Get("long"), 64)
+		if err != nil || !(long >= -180 && long <= 180) {
+			return "", "", fmt.Errorf("invalid long %q, must be between -180 and 180", q.Get("long"))
+		}
+	}
+	return strconv.FormatFloat(lat, 'f', precision, 64), strconv.FormatFloat(long, 'f', precision, 64), nil
+}
+
+// parseRegion reads the price zone from the zone or region parameter,
+// defaulting to the default_region setting or the Swedish region of the
+// given latitude.
+func parseRegion(req *fsthttp.Request, lat string) (string, error) {
+	region := strings.ToUpper(req.URL.Query().Get("zone"))
+	if region == "" {
+		region = strings.ToUpper(req.URL.Query().Get("region"))
+	}
+	if region == "" {
+		region = strings.ToUpper(config("default_region", ""))
+	}
+	if region == "" {
+		f, err := strconv.ParseFloat(lat, 64)
+		if err != nil {
+			return "SE4", nil
+		}
+		return regionFor(f), nil
+	}
+	if validRegion(region) {
+		return region, nil
+	}
+	return "", fmt.Errorf("unknown zone %q, use one of %s", region, strings.Join(zones(), ", "))
+}
+
+func validRegion(region string) bool {
+	return contains(zones(), region)
+}
+
+// regionFor maps a latitude to a price area. The borders between the areas
+// run roughly east-west so the latitude is a good enough approximation,
+// locations south of Sweden get SE4.
+func regionFor(lat float64) string {
+	switch {
+	case lat >= 64.3: // North of Skellefteå
+		return "SE1"
+	case lat >= 61.0: // North of Dalälven
+		return "SE2"
+	case lat >= 57.15: // North of Småland and Halland
+		return "SE3"
+	default:
+		return "SE4"
+	}
+}
+
+// Entry is the JSON representation of an hourly entry.
+type Entry struct {
+	Hour                     string    `json:"hour"`
+	Speed                    float64   `json:"speed"`
+	Gust                     float64   `json:"gust"`
+	Direction                float64   `json:"direction"`
+	Temperature              float64   `json:"temperature"`
+	ApparentTemperature      float64   `json:"apparent_temperature"`
+	Precipitation            float64   `json:"precipitation"`
+	PrecipitationProbability float64   `json:"precipitation_probability"`
+	Price                    *float64  `json:"price"`
+	Score                    *float64  `json:"score"`
+	Past                     bool      `json:"past,omitempty"`
+	Session                  *float64  `json:"session,omitempty"`
+	Ensemble                 *Ensemble `json:"ensemble,omitempty"`
+	Marine                   *Marine   `json:"marine,omitempty"`
+}
+
+// Location is the JSON representation of the forecast location.
+type Location struct {
+	Name      string  `json:"name,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Units describes the units of the Entry fields.
+type Units struct {
+	Speed                    string `json:"speed"`
+	Gust                     string `json:"gust"`
+	Direction                string `json:"direction"`
+	Temperature              string `json:"temperature"`
+	Precipitation            string `json:"precipitation"`
+	PrecipitationProbability string `json:"precipitation_probability"`
+	Price                    string `json:"price"`
+}
+
+// Response is the JSON envelope returned by /wind.json.
+type Response struct {
+	Location    Location  `json:"location"`
+	Units       Units     `json:"units"`
+	Tariff      *Tariff   `json:"tariff,omitempty"`
+	Region      string    `json:"region"`
+	Height      int       `json:"height"`
+	Timezone    string    `json:"timezone"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Stats       *Stats    `json:"stats"`
+	Warnings    []string  `json:"warnings,omitempty"`
+	Stale       bool      `json:"stale,omitempty"`
+	Entries     []Entry   `json:"entries"`
+	Debug       *Debug    `json:"_debug,omitempty"`
+}
+
+func toJSON(f *forecast) string {
+	b, _ := json.MarshalIndent(toResponse(f), "", "  ")
+	return string(b)
+}
+
+func toResponse(f *forecast) Response {
+	r := Response{
+		Units: Units{
+			Speed:                    f.unit.label,
+			Gust:                     f.unit.label,
+			Direction:                "°",
+			Temperature:              "°C",
+			Precipitation:            "mm",
+			PrecipitationProbability: "%",
+			Price:                    f.currency.priceUnit(),
+		},
+		Tariff:      f.tariff,
+		Region:      f.region,
+		Height:      f.height,
+		Timezone:    f.timezone.String(),
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		Stats:       toStats(f),
+		Warnings:    f.warnings,
+		Stale:       f.stale,
+		Debug:       f.debugSection(),
+		Entries: mapSlice(f.entries, func(e *entry) Entry {
+			en := toEntry(e, f.timezone)
+			if f.spot != nil {
+				s := math.Round(e.session)
+				en.Session = &s
+			}
+			return en
+		}),
+	}
+	r.Location.Name = f.name
+	if r.Location.Name == "" {
+		r.Location.Name = f.near
+	}
+	r.Location.Latitude, _ = strconv.ParseFloat(f.lat, 64)
+	r.Location.Longitude, _ = strconv.ParseFloat(f.long, 64)
+	return r
+}
+
+func toEntry(e *entry, tz *time.Location) Entry {
+	return Entry{
+		Hour:                     e.hour.In(tz).Format(time.RFC3339),
+		Speed:                    round(e.speed, 2),
+		Gust:                     round(e.gust, 2),
+		Direction:                e.direction,
+		Temperature:              e.temperature,
+		ApparentTemperature:      e.apparentTemperature,
+		Precipitation:            e.precipitation,
+		PrecipitationProbability: e.precipitationProbability,
+		Price:                    price(e),
+		Score:                    score(e),
+		Past:                     e.past,
+		Ensemble:                 toEnsemble(e.ensemble),
+		Marine:                   toMarine(e.marine),
+	}
+}
+
+func round(f float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(f*p) / p
+}
+
+func toCSV(f *forecast) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write([]string{"hour", "speed", "gust", "direction", "temperature", "apparent_temperature", "precipitation", "precipitation_probability", "price"})
+	for _, e := range f.entries {
+		price := ""
+		if e.hasPrice {
+			price = fmt.Sprintf("%.2f", e.price)
+		}
+		w.Write([]string{
+			e.hour.In(f.timezone).Format(time.RFC3339),
+			fmt.Sprintf("%.2f", e.speed),
+			fmt.Sprintf("%.2f", e.gust),
+			fmt.Sprintf("%.0f", e.direction),
+			fmt.Sprintf("%.1f", e.temperature),
+			fmt.Sprintf("%.1f", e.apparentTemperature),
+			fmt.Sprintf("%.1f", e.precipitation),
+			fmt.Sprintf("%.0f", e.precipitationProbability),
+			price,
+		})
+	}
+	w.Flush()
+	return b.String()
+}
+
+// price returns the price of an entry, nil if there is no price for the
+// hour.
+func price(e *entry) *float64 {
+	if !e.hasPrice {
+		return nil
+	}
+	p := round(e.price, 4)
+	return &p
+}
+
+// chart is the data rendered by render/templates/wind.html.
+type chart struct {
+	Lang     string
+	Title    string
+	Warnings []string
+	Debug    *Debug
+	// Place, Lat and Long are used by the form saving the location.
+	Place     string
+	Lat, Long string
+	Unit      string
+	// PriceLabel names the price and its unit, e.g. Price (SEK/kWh).
+	PriceLabel string
+	Timezone   string
+	Times      []string
+	// Hours are the hours in RFC 3339, the unique labels of wind.html.
+	Hours      []string
+	Speeds     []float64
+	Gusts      []float64
+	Prices     []*float64
+	Directions []float64
+	// Good is 1 for hours with a good score and is rendered as a band.
+	Good []*float64
+	// Past is 1 for hours before now and is rendered as a dimming band.
+	Past []*float64
+	// SessionColors color the points by session score when there is a
+	// spot.
+	SessionColors []string
+	Stats         *Stats
+	// Precipitations are rendered as bars behind the lines.
+	Precipitations []float64
+	// P10s and P90s are the ensemble spread, only set when shown.
+	P10s []*float64
+	P90s []*float64
+	// Temperatures and ApparentTemperatures are only set when shown.
+	Temperatures         []float64
+	ApparentTemperatures []float64
+	// Preview is the card shown when the page is shared.
+	Preview *preview
+	// Refresh is set when the page updates itself.
+	Refresh *refresh
+	// Theme is light, dark or auto, the chart uses the Palettes of it.
+	Theme    string
+	Palettes map[string]palette
+	// Rows are the hours of the table under the chart, for screen readers
+	// and browsers without JavaScript.
+	Rows []tableRow
+	// Now is the index of the current hour, -1 when it is not shown. Gusty
+	// is 1 for hours with gusts above MaxGust and Cheap for the cheapest
+	// window of ChargeHours, both rendered as bands.
+	Now         int
+	MaxGust     float64
+	Gusty       []*float64
+	Cheap       []*float64
+	ChargeHours int
+	// PriceColors color the price bars by the PriceBands, the page colors
+	// them with the PriceBandColors when it updates itself.
+	PriceBands      priceBands
+	PriceColors     []string
+	PriceBandColors map[string]string
+}
+
+// tableRow is an hour of the table under the chart. Hour is the time in
+// RFC 3339, Time the label and Unix sorts the hours. Price is nil when
+// there is no price for the hour.
+type tableRow struct {
+	Hour          string
+	Time          string
+	Unix          int64
+	Speed         float64
+	Gust          float64
+	Direction     float64
+	Compass       string
+	Precipitation float64
+	Price         *float64
+	Temperature   float64
+}
+
+// toHTML renders the chart page with the options and a preview card for
+// sharing the page on base, e.g. https://windy.edgecompute.app.
+func toHTML(f *forecast, g *geo.Geo, base string, o chartOptions) (string, error) {
+	c := toChart(f, render.Tf(o.lang, "%s, prices for %s", f.localTitle(g, o.lang), f.region), o.lang)
+	c.mark(f, o.maxGust)
+	c.colorPrices(f, o.bands)
+	c.Preview = toPreview(f, base)
+	c.Refresh = o.refresh
+	c.Theme = o.theme
+	c.Palettes = palettes
+	return render.Render("wind.html", c)
+}
+
+// priceLabel names the prices of the forecast in the language, consumer
+// prices when there is a tariff.
+func priceLabel(f *forecast, lang string) string {
+	if f.tariff != nil {
+		return render.Tf(lang, "Consumer price (%s)", f.currency.priceUnit())
+	}
+	return render.Tf(lang, "Price (%s)", f.currency.priceUnit())
+}
+
+// toChart returns the chart of the forecast with the labels in the
+// language.
+func toChart(f *forecast, title, lang string) chart {
+	entries := f.entries
+	c := chart{
+		Lang:       lang,
+		Title:      title,
+		Warnings:   f.warnings,
+		Debug:      f.debugSection(),
+		Place:      f.name,
+		Lat:        f.lat,
+		Long:       f.long,
+		Stats:      toStats(f),
+		Unit:       render.T(lang, f.unit.label),
+		PriceLabel: priceLabel(f, lang),
+		Timezone:   f.timezone.String(),
+		Times:      mapSlice(entries, hourLabel(f.timezone)),
+		Hours: mapSlice(entries, func(e *entry) string {
+			return e.hour.In(f.timezone).Format(time.RFC3339)
+		}),
+		Speeds: mapSlice(entries, func(e *entry) float64 {
+			return round(e.speed, 2)
+		}),
+		Gusts: mapSlice(entries, func(e *entry) float64 {
+			return round(e.gust, 2)
+		}),
+		Prices: mapSlice(entries, price),
+		Good: mapSlice(entries, func(e *entry) *float64 {
+			if !e.hasPrice || e.score < goodScore {
+				return nil
+			}
+			one := 1.0
+			return &one
+		}),
+		Past: mapSlice(entries, func(e *entry) *float64 {
+			if !e.past {
+				return nil
+			}
+			one := 1.0
+			return &one
+		}),
+		Precipitations: mapSlice(entries, func(e *entry) float64 {
+			return e.precipitation
+		}),
+		// Wind direction is where the wind comes from, rotate the arrows to
+		// point where it is going.
+		Directions: mapSlice(entries, func(e *entry) float64 {
+			return math.Mod(e.direction+180, 360)
+		}),
+	}
+	if c.Place == "" {
+		c.Place = f.near
+	}
+	if f.noWind {
+		c.Speeds, c.Gusts = []float64{}, []float64{}
+	}
+	c.Rows = mapSlice(entries, func(e *entry) tableRow {
+		t := e.hour.In(f.timezone)
+		return tableRow{
+			Hour:          t.Format(time.RFC3339),
+			Time:          render.Date(lang, t, "Mon 2 Jan 15:04"),
+			Unix:          t.Unix(),
+			Speed:         e.speed,
+			Gust:          e.gust,
+			Direction:     e.direction,
+			Compass:       render.Compass(e.direction),
+			Precipitation: e.precipitation,
+			Price:         price(e),
+			Temperature:   e.temperature,
+		}
+	})
+	if f.spot != nil {
+		c.SessionColors = mapSlice(entries, sessionColor)
+	}
+	if f.show["ensemble"] {
+		c.P10s = mapSlice(entries, ensembleValue(func(s *spread) float64 { return s.p10 }))
+		c.P90s = mapSlice(entries, ensembleValue(func(s *spread) float64 { return s.p90 }))
+	}
+	if f.show["temp"] {
+		c.Temperatures = mapSlice(entries, func(e *entry) float64 {
+			return e.temperature
+		})
+		c.ApparentTemperatures = mapSlice(entries, func(e *entry) float64 {
+			return e.apparentTemperature
+		})
+	}
+	return c
+}
+
+// hourLabel returns a function formatting the hour of an entry as a chart
+// label, midnight is labeled with the date.
+func hourLabel(tz *time.Location) func(*entry) string {
+	return func(e *entry) string {
+		t := e.hour.In(tz)
+		if t.Hour() == 0 {
+			return t.Format("2006-01-02")
+		}
+		return t.Format("15:04")
+	}
+}
+
+// title returns the title of the forecast, using the place name when the
+// location was given by name.
+func (f *forecast) title(g *geo.Geo) string {
+	return f.localTitle(g, "en")
+}
+
+// localTitle returns the title of the forecast in the language.
+func (f *forecast) localTitle(g *geo.Geo, lang string) string {
+	t := title(g, f.lat, f.long, lang)
+	if f.name != "" {
+		t = render.Tf(lang, "Winds in %s (lat: %s, long: %s)", f.name, f.lat, f.long)
+	} else if f.near != "" {
+		t = render.Tf(lang, "Winds near %s (lat: %s, long: %s)", f.near, f.lat, f.long)
+	}
+	if f.height > 10 {
+		t = render.Tf(lang, "%s at %d m", t, f.height)
+	}
+	return t
+}
+
+func title(g *geo.Geo, lat, long, lang string) string {
+	if lat != "" && long != "" {
+		return render.Tf(lang, "Winds at browser location (lat: %s, long: %s)", lat, long)
+	}
+	return render.Tf(lang, "Winds in %s, %s (lat: %.2f, long: %.2f)",
+		strings.Title(g.City), strings.Title(g.CountryName), g.Latitude, g.Longitude,
+	)
+}
+
+func rootHTML(g *geo.Geo, favorites []Favorite) (string, error) {
+	return render.Render("root.html", struct {
+		Title     string
+		Favorites []Favorite
+	}{title(g, "", "", "en"), favorites})
+}
+
+func mapSlice[T any, M any](a []T, f func(T) M) []M {
+	n := make([]M, len(a))
+	for i, e := range a {
+		n[i] = f(e)
+	}
+	return n
+}
@@ -0,0 +1,154 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"compute-starter-kit-go/openmeteo"
+	"compute-starter-kit-go/render"
+	"github.com/buger/jsonparser"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+	"github.com/fastly/compute-sdk-go/geo"
+)
+
+// marine is the sea state for an hour, the values are nil for locations
+// without marine data, e.g. inland.
+type marine struct {
+	waveHeight     *float64
+	wavePeriod     *float64
+	waveDirection  *float64
+	swellHeight    *float64
+	swellPeriod    *float64
+	swellDirection *float64
+}
+
+// Marine is the JSON representation of the sea state, heights are in m,
+// periods in s and directions in degrees.
+type Marine struct {
+	WaveHeight     *float64 `json:"wave_height"`
+	WavePeriod     *float64 `json:"wave_period"`
+	WaveDirection  *float64 `json:"wave_direction"`
+	SwellHeight    *float64 `json:"swell_wave_height"`
+	SwellPeriod    *float64 `json:"swell_wave_period"`
+	SwellDirection *float64 `json:"swell_wave_direction"`
+}
+
+func fetchMarine(ctx context.Context, lat, long string, days int) (map[time.Time]*marine, error) {
+	defer timed(ctx, "marine")()
+	body, err := sendMarineRequest(ctx, lat, long, days)
+	if err != nil {
+		return nil, err
+	}
+	times := openmeteo.Times(body, "hourly", "time")
+	heights := openmeteo.OptionalFloats(body, "hourly", "wave_height")
+	periods := openmeteo.OptionalFloats(body, "hourly", "wave_period")
+	directions := openmeteo.OptionalFloats(body, "hourly", "wave_direction")
+	swellHeights := openmeteo.OptionalFloats(body, "hourly", "swell_wave_height")
+	swellPeriods := openmeteo.OptionalFloats(body, "hourly", "swell_wave_period")
+	swellDirections := openmeteo.OptionalFloats(body, "hourly", "swell_wave_direction")
+	m := map[time.Time]*marine{}
+	for i, t := range times {
+		m[t] = &marine{
+			waveHeight:     openmeteo.At(heights, i),
+			wavePeriod:     openmeteo.At(periods, i),
+			waveDirection:  openmeteo.At(directions, i),
+			swellHeight:    openmeteo.At(swellHeights, i),
+			swellPeriod:    openmeteo.At(swellPeriods, i),
+			swellDirection: openmeteo.At(swellDirections, i),
+		}
+	}
+	return m, nil
+}
+
+func sendMarineRequest(ctx context.Context, lat, long string, days int) ([]byte, error) {
+	u := fmt.Sprintf("https://marine-api.open-meteo.com/v1/marine?latitude=%s&longitude=%s&timezone=auto&timeformat=unixtime&forecast_days=%d&hourly=wave_height,wave_period,wave_direction,swell_wave_height,swell_wave_period,swell_wave_direction", lat, long, days)
+	logln(ctx, u)
+	req, _ := newUpstreamRequest(ctx, "GET", u)
+	req.CacheOptions.TTL = 60 * 60 * 1 // 1 hour
+	resp, err := sendUpstream(ctx, req, "open-meteo-marine")
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != fsthttp.StatusOK {
+		reason, _ := jsonparser.GetString(body, "reason")
+		return nil, fmt.Errorf("marine forecast unavailable: %s", reason)
+	}
+	return body, nil
+}
+
+func mergeMarine(entries []*entry, m map[time.Time]*marine) {
+	for _, e := range entries {
+		e.marine = m[e.hour]
+	}
+}
+
+func toMarine(m *marine) *Marine {
+	if m == nil {
+		return nil
+	}
+	return &Marine{
+		WaveHeight:     m.waveHeight,
+		WavePeriod:     m.wavePeriod,
+		WaveDirection:  m.waveDirection,
+		SwellHeight:    m.swellHeight,
+		SwellPeriod:    m.swellPeriod,
+		SwellDirection: m.swellDirection,
+	}
+}
+
+// marineChart is the data rendered by render/templates/marine.html.
+type marineChart struct {
+	Title        string
+	Warnings     []string
+	Unit         string
+	Timezone     string
+	Times        []string
+	Speeds       []float64
+	Gusts        []float64
+	WaveHeights  []*float64
+	SwellHeights []*float64
+	WavePeriods  []*float64
+}
+
+func toMarineHTML(f *forecast, g *geo.Geo) (string, error) {
+	c := marineChart{
+		Title:    fmt.Sprintf("Sea state, %s", f.title(g)),
+		Warnings: f.warnings,
+		Unit:     f.unit.label,
+		Timezone: f.timezone.String(),
+		Times:    mapSlice(f.entries, hourLabel(f.timezone)),
+		Speeds: mapSlice(f.entries, func(e *entry) float64 {
+			return round(e.speed, 2)
+		}),
+		Gusts: mapSlice(f.entries, func(e *entry) float64 {
+			return round(e.gust, 2)
+		}),
+		WaveHeights: mapSlice(f.entries, marineValue(func(m *marine) *float64 {
+			return m.waveHeight
+		})),
+		SwellHeights: mapSlice(f.entries, marineValue(func(m *marine) *float64 {
+			return m.swellHeight
+		})),
+		WavePeriods: mapSlice(f.entries, marineValue(func(m *marine) *float64 {
+			return m.wavePeriod
+		})),
+	}
+	return render.Render("marine.html", c)
+}
+
+// marineValue returns a function getting a marine value from an entry, nil
+// if the entry has no marine data.
+func marineValue(get func(*marine) *float64) func(*entry) *float64 {
+	return func(e *entry) *float64 {
+		if e.marine == nil {
+			return nil
+		}
+		return get(e.marine)
+	}
+}
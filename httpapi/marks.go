@@ -0,0 +1,147 @@
+package httpapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// parseMaxGust reads the max_gust parameter, the gusts in the unit of the
+// forecast shaded on the chart, by default strongWind m/s.
+func parseMaxGust(req *fsthttp.Request, u unit) (float64, error) {
+	s := req.URL.Query().Get("max_gust")
+	if s == "" {
+		return round(u.convert(strongWind), 1), nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid max_gust %q, use a wind speed in %s", s, u.label)
+	}
+	return v, nil
+}
+
+// mark adds the current hour, the hours with gusts above maxGust and the
+// cheapest window of chargeHours to the chart.
+func (c *chart) mark(f *forecast, maxGust float64) {
+	one := 1.0
+	c.Now = -1
+	for i, e := range f.entries {
+		if !e.past {
+			if !e.hour.After(time.Now()) {
+				c.Now = i
+			}
+			break
+		}
+	}
+	c.MaxGust = maxGust
+	c.Gusty = mapSlice(f.entries, func(e *entry) *float64 {
+		if f.noWind || e.gust <= maxGust {
+			return nil
+		}
+		return &one
+	})
+	cheap := map[time.Time]bool{}
+	for _, e := range cheapWindow(f, chargeHours) {
+		cheap[e.hour] = true
+	}
+	c.ChargeHours = chargeHours
+	c.Cheap = mapSlice(f.entries, func(e *entry) *float64 {
+		if !cheap[e.hour] {
+			return nil
+		}
+		return &one
+	})
+}
+
+// chartOptions are the parameters of the chart page.
+type chartOptions struct {
+	lang    string
+	refresh *refresh
+	theme   string
+	maxGust float64
+	bands   priceBands
+}
+
+// parseChartOptions reads the parameters of the chart page, with gusts and
+// prices in the units of the forecast.
+func parseChartOptions(req *fsthttp.Request, f *forecast) (chartOptions, error) {
+	var o chartOptions
+	var err error
+	if o.lang, err = parseLang(req); err != nil {
+		return o, err
+	}
+	if o.refresh, err = parseRefresh(req); err != nil {
+		return o, err
+	}
+	if o.theme, err = parseTheme(req); err != nil {
+		return o, err
+	}
+	if o.maxGust, err = parseMaxGust(req, f.unit); err != nil {
+		return o, err
+	}
+	if o.bands, err = parsePriceBands(req, f); err != nil {
+		return o, err
+	}
+	return o, nil
+}
+
+// priceBands are the prices per kWh up to which an hour is cheap and
+// from which it is expensive.
+type priceBands struct {
+	Cheap     float64
+	Expensive float64
+}
+
+// priceColors are the colors of the price bars by band.
+var priceColors = map[string]string{
+	"cheap":     "rgba(75, 192, 75, 0.5)",
+	"normal":    "rgba(255, 206, 86, 0.5)",
+	"expensive": "rgba(255, 99, 71, 0.5)",
+}
+
+// parsePriceBands reads the price_bands parameter, the cheap and expensive
+// prices of the forecast, e.g. 0.5,1.5. The default is the price_bands
+// config, spot prices in SEK converted like the prices of the forecast.
+func parsePriceBands(req *fsthttp.Request, f *forecast) (priceBands, error) {
+	s := req.URL.Query().Get("price_bands")
+	spot := s == ""
+	if spot {
+		s = config("price_bands", "0.5,1.5")
+	}
+	cheap, expensive, ok := strings.Cut(s, ",")
+	b := priceBands{}
+	var err1, err2 error
+	b.Cheap, err1 = strconv.ParseFloat(cheap, 64)
+	b.Expensive, err2 = strconv.ParseFloat(expensive, 64)
+	if !ok || err1 != nil || err2 != nil || b.Cheap > b.Expensive {
+		return priceBands{}, fmt.Errorf("invalid price_bands %q, use the cheap and expensive prices per kWh, e.g. 0.5,1.5", s)
+	}
+	if spot {
+		b.Cheap = round(f.tariff.apply(b.Cheap)*f.currency.rate, 4)
+		b.Expensive = round(f.tariff.apply(b.Expensive)*f.currency.rate, 4)
+	}
+	return b, nil
+}
+
+// band returns the band of the price, cheap, normal or expensive.
+func (b priceBands) band(price float64) string {
+	switch {
+	case price <= b.Cheap:
+		return "cheap"
+	case price >= b.Expensive:
+		return "expensive"
+	}
+	return "normal"
+}
+
+// colorPrices colors the price bars of the chart by the bands.
+func (c *chart) colorPrices(f *forecast, b priceBands) {
+	c.PriceBands = b
+	c.PriceBandColors = priceColors
+	c.PriceColors = mapSlice(f.entries, func(e *entry) string {
+		return priceColors[b.band(e.price)]
+	})
+}
@@ -0,0 +1,72 @@
+package httpapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// metric is a gauge of the Prometheus exposition format.
+type metric struct {
+	name, help string
+	value      func(e *entry) (float64, bool)
+}
+
+// currentMetrics are the gauges of /current/metrics, in SI units as
+// Prometheus recommends.
+var currentMetrics = []metric{
+	{"windy_wind_speed_meters_per_second", "Forecast wind speed of the current hour.", func(e *entry) (float64, bool) { return e.speed, true }},
+	{"windy_wind_gust_meters_per_second", "Forecast wind gusts of the current hour.", func(e *entry) (float64, bool) { return e.gust, true }},
+	{"windy_wind_direction_degrees", "Forecast direction the wind comes from in the current hour.", func(e *entry) (float64, bool) { return e.direction, true }},
+	{"windy_temperature_celsius", "Forecast temperature of the current hour.", func(e *entry) (float64, bool) { return e.temperature, true }},
+	{"windy_price_sek_per_kwh", "Electricity price of the current hour.", func(e *entry) (float64, bool) { return e.price, e.hasPrice }},
+}
+
+// currentEntry returns the entry of the current hour, the first that is
+// not in the past, nil if there is none.
+func currentEntry(f *forecast) *entry {
+	for _, e := range f.entries {
+		if !e.past {
+			return e
+		}
+	}
+	return nil
+}
+
+// toMetrics returns the current hour of the forecast in the Prometheus text
+// exposition format, labelled with the location. The forecast must be in
+// m/s.
+func toMetrics(f *forecast) string {
+	labels := map[string]string{"lat": f.lat, "long": f.long, "region": f.region}
+	if f.name != "" {
+		labels["name"] = f.name
+	}
+	var b strings.Builder
+	e := currentEntry(f)
+	if e == nil {
+		return ""
+	}
+	for _, m := range currentMetrics {
+		v, ok := m.value(e)
+		if !ok || f.noWind && m.name != "windy_price_sek_per_kwh" {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s%s %g\n", m.name, m.help, m.name, m.name, formatLabels(labels), v)
+	}
+	return b.String()
+}
+
+// formatLabels formats the labels sorted by name, escaping the values.
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for n := range labels {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = fmt.Sprintf(`%s="%s"`, n, escape.Replace(labels[n]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
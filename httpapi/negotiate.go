@@ -0,0 +1,86 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// mediaTypes are the formats of the forecast by media type, in order of
+// preference when the Accept header has wildcards. Formats without a media
+// type are only chosen by the format parameter.
+var mediaTypes = []struct {
+	mediaType string
+	format    string
+}{
+	{"text/html", "html"},
+	{"application/json", "json"},
+	{"text/csv", "csv"},
+	{"text/plain", "txt"},
+	{"application/xml", "xml"},
+	{"application/geo+json", "geojson"},
+	{"image/svg+xml", "svg"},
+	{"image/png", "png"},
+	{"", "influx"},
+}
+
+// errNotAcceptable is returned when none of the formats are acceptable.
+var errNotAcceptable = errors.New("not acceptable, use one of text/html, application/json, text/csv, text/plain, application/xml, application/geo+json, image/svg+xml or image/png")
+
+// negotiate returns the format of a request without an extension, e.g.
+// /wind. The format query parameter overrides the Accept header, which
+// defaults to HTML, or to text for curl and wget.
+func negotiate(req *fsthttp.Request) (string, error) {
+	if f := req.URL.Query().Get("format"); f != "" {
+		for _, m := range mediaTypes {
+			if f == m.format {
+				return f, nil
+			}
+		}
+		return "", fmt.Errorf("unknown format %q, use one of html, json, csv, txt, xml, geojson, svg, png or influx", f)
+	}
+	accept := req.Header.Get("Accept")
+	if terminalClient(req) && (accept == "" || accept == "*/*") {
+		return "txt", nil
+	}
+	if accept == "" {
+		return "html", nil
+	}
+	format, best := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok && k == "q" {
+				q, _ = strconv.ParseFloat(v, 64)
+			}
+		}
+		if q <= best {
+			continue
+		}
+		for _, m := range mediaTypes {
+			if m.mediaType != "" && matchMediaType(mediaType, m.mediaType) {
+				format, best = m.format, q
+				break
+			}
+		}
+	}
+	if format == "" {
+		return "", errNotAcceptable
+	}
+	return format, nil
+}
+
+// matchMediaType reports whether the accepted media type, possibly with
+// wildcards, matches the media type.
+func matchMediaType(accepted, mediaType string) bool {
+	if accepted == "*/*" || accepted == mediaType {
+		return true
+	}
+	typ, sub, _ := strings.Cut(accepted, "/")
+	return sub == "*" && strings.HasPrefix(mediaType, typ+"/")
+}
@@ -0,0 +1,154 @@
+//go:build nethttp
+
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+	"github.com/fastly/compute-sdk-go/geo"
+	"github.com/fastly/compute-sdk-go/objectstore"
+)
+
+// Handler returns the handlers as a net/http handler, for running windy
+// as a plain Go server or testing it with httptest. The Fastly services are
+// replaced:
+//
+//   - upstream requests are sent directly, without caching
+//   - the location of the client IP is default_lat and default_long
+//   - the KV Store is kept in memory
+//   - secrets are read from WINDY_SECRET_<NAME> environment variables
+//
+// Settings and secrets are read for each request rather than cached.
+func Handler() http.Handler {
+	cacheSettings = false
+	sendRequest = sendHTTP
+	lookupIP = defaultLocation
+	kv := &memoryKV{values: map[string]string{}}
+	openKV = func() (kvStore, error) { return kv, nil }
+	readSecret = envSecret
+	h := handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			remote = r.RemoteAddr
+		}
+		req := &fsthttp.Request{
+			Method:     r.Method,
+			URL:        r.URL,
+			Proto:      r.Proto,
+			ProtoMajor: r.ProtoMajor,
+			ProtoMinor: r.ProtoMinor,
+			Header:     fsthttp.Header(r.Header),
+			Body:       r.Body,
+			Host:       r.Host,
+			RemoteAddr: remote,
+		}
+		h(r.Context(), responseWriter{w}, req)
+	})
+}
+
+// responseWriter adapts a net/http ResponseWriter.
+type responseWriter struct {
+	http.ResponseWriter
+}
+
+func (w responseWriter) Header() fsthttp.Header {
+	return fsthttp.Header(w.ResponseWriter.Header())
+}
+
+func (w responseWriter) Close() error {
+	return nil
+}
+
+// SetManualFramingMode is a no-op, net/http sets the framing headers.
+func (w responseWriter) SetManualFramingMode(bool) {}
+
+// sendHTTP sends the request with net/http, the backend is ignored as the
+// URLs are absolute.
+func sendHTTP(ctx context.Context, req *fsthttp.Request, backend string) (*fsthttp.Response, error) {
+	hreq, err := http.NewRequestWithContext(ctx, req.Method, req.URL.String(), req.Body)
+	if err != nil {
+		return nil, err
+	}
+	hreq.Header = http.Header(req.Header)
+	resp, err := http.DefaultClient.Do(hreq)
+	if err != nil {
+		return nil, err
+	}
+	return &fsthttp.Response{
+		Request:    req,
+		Backend:    backend,
+		StatusCode: resp.StatusCode,
+		Header:     fsthttp.Header(resp.Header),
+		Body:       resp.Body,
+	}, nil
+}
+
+// defaultLocation is the location of all client IPs, Lomma unless
+// configured by the default_lat and default_long settings.
+func defaultLocation(ip net.IP) (*geo.Geo, error) {
+	lat, err := strconv.ParseFloat(config("default_lat", "55.67"), 64)
+	if err != nil {
+		return nil, err
+	}
+	long, err := strconv.ParseFloat(config("default_long", "13.06"), 64)
+	if err != nil {
+		return nil, err
+	}
+	return &geo.Geo{Latitude: lat, Longitude: long}, nil
+}
+
+// envSecret reads the secret from the WINDY_SECRET_<NAME> environment
+// variable, e.g. WINDY_SECRET_COOKIE_KEY.
+func envSecret(name string) ([]byte, error) {
+	v := os.Getenv("WINDY_SECRET_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")))
+	if v == "" {
+		return nil, objectstore.ErrKeyNotFound
+	}
+	return []byte(v), nil
+}
+
+// memoryKV is a KV Store in memory.
+type memoryKV struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+type memoryEntry struct {
+	*strings.Reader
+	value string
+}
+
+func (e memoryEntry) String() string {
+	return e.value
+}
+
+func (s *memoryKV) Lookup(key string) (kvEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[key]
+	if !ok {
+		return nil, objectstore.ErrKeyNotFound
+	}
+	return memoryEntry{strings.NewReader(v), v}, nil
+}
+
+func (s *memoryKV) Insert(key string, value io.Reader) error {
+	var b bytes.Buffer
+	if _, err := b.ReadFrom(value); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = b.String()
+	return nil
+}
@@ -0,0 +1,291 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// object is a JSON object of the OpenAPI document.
+type object map[string]interface{}
+
+// schemas generates the OpenAPI schemas of the JSON types from their
+// struct fields and tags, so the document follows the code. Fields without
+// omitempty are required, pointers may be null.
+type schemas map[string]object
+
+func (s schemas) of(t reflect.Type) object {
+	switch t.Kind() {
+	case reflect.Ptr:
+		e := s.of(t.Elem())
+		if _, ok := e["$ref"]; ok {
+			return object{"allOf": []object{e}, "nullable": true}
+		}
+		e["nullable"] = true
+		return e
+	case reflect.Slice:
+		return object{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return object{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.String:
+		return object{"type": "string"}
+	case reflect.Float32, reflect.Float64:
+		return object{"type": "number"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return object{"type": "integer"}
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Struct:
+		if t == reflect.TypeOf(time.Time{}) {
+			return object{"type": "string", "format": "date-time"}
+		}
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		if _, ok := s[name]; !ok {
+			// Reserve the name first in case the type refers to itself.
+			s[name] = object{}
+			props, required := object{}, []string{}
+			s.fields(t, props, &required)
+			schema := object{"type": "object", "properties": props}
+			if len(required) > 0 {
+				schema["required"] = required
+			}
+			s[name] = schema
+		}
+		return object{"$ref": "#/components/schemas/" + name}
+	}
+	return object{}
+}
+
+// fields adds the JSON fields of the struct, embedded structs are inlined
+// like encoding/json does. The fields of embedded pointers are missing when
+// they are nil so they are not required.
+func (s schemas) fields(t reflect.Type, props object, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			if f.Type.Kind() == reflect.Ptr {
+				s.fields(f.Type.Elem(), props, &[]string{})
+				continue
+			}
+			s.fields(f.Type, props, required)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = s.of(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// queryParam is a query parameter of the OpenAPI document.
+func queryParam(name, typ, description string, enum ...string) object {
+	schema := object{"type": typ}
+	if len(enum) > 0 {
+		schema["enum"] = enum
+	}
+	return object{"name": name, "in": "query", "description": description, "schema": schema}
+}
+
+// parameters are the shared query parameters, referenced by name.
+var parameters = map[string]object{
+	"lat":             queryParam("lat", "number", "Latitude, defaults to the location of the client IP."),
+	"long":            queryParam("long", "number", "Longitude, defaults to the location of the client IP."),
+	"city":            queryParam("city", "string", "Place name to use instead of the coordinates."),
+	"country":         queryParam("country", "string", "Country code narrowing down the city, e.g. SE."),
+	"spot":            queryParam("spot", "string", "Spot id to use instead of the coordinates, e.g. lomma."),
+	"precision":       queryParam("precision", "integer", "Number of decimals of the coordinates, 0-6, default 2."),
+	"unit":            queryParam("unit", "string", "Wind speed unit, default ms.", "ms", "kn", "kmh", "mph", "bft"),
+	"height":          queryParam("height", "integer", "Height of the wind in meters, 10, 80, 120 or 180, default 10."),
+	"days":            queryParam("days", "integer", "Number of days, 1-16, default 3."),
+	"past_days":       queryParam("past_days", "integer", "Days before today to include, 0-7."),
+	"hours":           queryParam("hours", "integer", "Number of hours, starting with the current hour."),
+	"from":            queryParam("from", "string", "Start of the range, RFC3339, YYYY-MM-DD, today or tomorrow."),
+	"to":              queryParam("to", "string", "End of the range, RFC3339, YYYY-MM-DD, today or tomorrow."),
+	"resolution":      queryParam("resolution", "string", "Aggregate the hours into buckets.", "1h", "2h", "3h", "6h", "12h"),
+	"smooth":          queryParam("smooth", "integer", "Moving average of the wind over the number of hours."),
+	"show":            queryParam("show", "string", "Comma separated optional datasets, temp and ensemble."),
+	"region":          queryParam("region", "string", "Electricity price area, defaults to the area of the location.", "SE1", "SE2", "SE3", "SE4"),
+	"zone":            queryParam("zone", "string", "Bidding zone of the prices instead of the region, e.g. NO2, DK1, FI, DE or AT."),
+	"currency":        queryParam("currency", "string", "Currency of the prices, default SEK.", "SEK", "EUR", "NOK", "DKK"),
+	"price":           queryParam("price", "string", "Spot prices or consumer prices with the tariff, default spot.", "spot", "consumer"),
+	"vat":             queryParam("vat", "number", "VAT of consumer prices in percent, default that of the zone, e.g. 25 in Sweden."),
+	"energy_tax":      queryParam("energy_tax", "number", "Energy tax of consumer prices per kWh in the currency of the zone, default that of the zone, e.g. 0.439 SEK in Sweden."),
+	"certificate_fee": queryParam("certificate_fee", "number", "Certificate fee of consumer prices per kWh in the currency of the zone, default 0."),
+	"grid_fee":        queryParam("grid_fee", "number", "Grid transfer fee of consumer prices per kWh in the currency of the zone, default 0."),
+	"debug":           queryParam("debug", "string", "1 adds the _debug section, requires the X-Debug-Token header.", "1"),
+}
+
+// forecastParams are the parameters of the forecast formats.
+var forecastParams = []string{"lat", "long", "city", "country", "spot", "precision", "unit", "height", "days", "past_days", "hours", "from", "to", "resolution", "smooth", "show", "region", "zone", "currency", "price", "vat", "energy_tax", "certificate_fee", "grid_fee"}
+
+// refs returns references to the shared parameters.
+func refs(names ...string) []object {
+	params := make([]object, len(names))
+	for i, n := range names {
+		params[i] = object{"$ref": "#/components/parameters/" + n}
+	}
+	return params
+}
+
+// operation describes an endpoint responding with JSON of the type of v.
+func (s schemas) operation(summary string, params []object, v interface{}) object {
+	return object{
+		"summary":    summary,
+		"parameters": params,
+		"responses": object{
+			"200": object{
+				"description": "OK",
+				"content":     object{"application/json": object{"schema": s.of(reflect.TypeOf(v))}},
+			},
+			"default": object{"$ref": "#/components/responses/Problem"},
+		},
+	}
+}
+
+// openAPI returns the OpenAPI 3 document of the JSON endpoints.
+func openAPI() object {
+	s := schemas{}
+	s.of(reflect.TypeOf(Problem{}))
+	idParam := object{"name": "id", "in": "path", "required": true, "schema": object{"type": "string"}}
+	batch := s.operation("Forecasts for up to 20 locations", refs("unit", "days", "hours"), []BatchResult{})
+	batch["requestBody"] = object{
+		"required": true,
+		"content":  object{"application/json": object{"schema": s.of(reflect.TypeOf([]batchLocation{}))}},
+	}
+	putSpot := s.operation("Register or update a spot", []object{idParam}, Spot{})
+	putSpot["requestBody"] = object{
+		"required": true,
+		"content":  object{"application/json": object{"schema": s.of(reflect.TypeOf(Spot{}))}},
+	}
+	putSpot["security"] = []object{{"bearer": []string{}}}
+	deleteSpot := object{
+		"summary":    "Remove a registered spot",
+		"parameters": []object{idParam},
+		"security":   []object{{"bearer": []string{}}},
+		"responses": object{
+			"204":     object{"description": "Removed"},
+			"default": object{"$ref": "#/components/responses/Problem"},
+		},
+	}
+	alertBody := object{
+		"required": true,
+		"content":  object{"application/json": object{"schema": s.of(reflect.TypeOf(Alert{}))}},
+	}
+	postAlert := s.operation("Register an alert", nil, Alert{})
+	postAlert["requestBody"] = alertBody
+	postAlert["security"] = []object{{"bearer": []string{}}}
+	putAlert := s.operation("Update an alert", []object{idParam}, Alert{})
+	putAlert["requestBody"] = alertBody
+	deleteAlert := object{
+		"summary":    "Remove an alert",
+		"parameters": []object{idParam},
+		"responses": object{
+			"204":     object{"description": "Removed"},
+			"default": object{"$ref": "#/components/responses/Problem"},
+		},
+	}
+	paths := object{
+		"/wind.json":       object{"get": s.operation("Hourly wind forecast and electricity prices", refs(append(forecastParams, "debug")...), Response{})},
+		"/wind/daily.json": object{"get": s.operation("Daily summary of the forecast", refs(forecastParams...), DailyResponse{})},
+		"/wind.geojson":    object{"get": s.operation("Hourly forecast as a GeoJSON feature of the location", refs(forecastParams...), GeoJSON{})},
+		"/marine.json":     object{"get": s.operation("Hourly forecast with the sea state", refs(forecastParams...), Response{})},
+		"/wind/batch.json": object{"post": batch},
+		"/prices.json": object{"get": s.operation("Electricity prices per hour", []object{
+			queryParam("regions", "string", "Comma separated price areas, e.g. SE3,SE4."),
+			queryParam("region", "string", "Price area, default SE4."),
+			queryParam("date", "string", "Date, YYYY-MM-DD, default today and tomorrow."),
+			refs("currency")[0],
+		}, PricesResponse{})},
+		"/cheapest.json": object{"get": s.operation("Cheapest windows of consecutive hours", []object{
+			queryParam("region", "string", "Price area, default SE4."),
+			refs("zone")[0],
+			queryParam("hours", "integer", "Length of the window, 1-24, default 3."),
+			queryParam("count", "integer", "Number of non-overlapping windows, 1-10, default 1."),
+			queryParam("after", "integer", "Earliest hour of the day, 0-23."),
+			queryParam("before", "integer", "Latest hour of the day, 1-24."),
+			refs("currency")[0],
+		}, CheapestResponse{})},
+		"/ha.json": object{"get": s.operation("Current and next hour and the cheapest window for Home Assistant", append(refs(forecastParams...),
+			queryParam("window", "integer", "Length of the cheap window, 1-24, default 3.")), HAResponse{})},
+		"/oembed": object{"get": s.operation("oEmbed of a forecast page", []object{
+			queryParam("url", "string", "URL of the forecast page, e.g. https://windy.edgecompute.app/wind/lomma."),
+			queryParam("maxwidth", "integer", "Maximum width of the iframe."),
+			queryParam("maxheight", "integer", "Maximum height of the iframe."),
+			queryParam("format", "string", "Only json is supported."),
+		}, OEmbed{})},
+		"/accuracy.json": object{"get": s.operation("Accuracy of the stored forecasts", append(refs("lat", "long", "spot"),
+			queryParam("days", "integer", "Number of days of snapshots, 1-14, default 14.")), AccuracyResponse{})},
+		"/score.json":  object{"get": s.operation("Session score of each hour at a spot", refs("spot", "unit", "days", "hours"), ScoreResponse{})},
+		"/spots":       object{"get": s.operation("All spots", nil, []Spot{})},
+		"/spots/{id}":  object{"get": s.operation("A spot", []object{idParam}, Spot{}), "put": putSpot, "delete": deleteSpot},
+		"/alerts":      object{"post": postAlert},
+		"/alerts/{id}": object{"get": s.operation("An alert", []object{idParam}, Alert{}), "put": putAlert, "delete": deleteAlert},
+		"/readyz":      object{"get": s.operation("Status of the upstreams", nil, ReadyResponse{})},
+		"/version":     object{"get": s.operation("Version of the service", nil, VersionResponse{})},
+		"/openapi.json": object{"get": object{
+			"summary":   "This document",
+			"responses": object{"200": object{"description": "OK"}},
+		}},
+	}
+	params := object{}
+	for name, p := range parameters {
+		params[name] = p
+	}
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":       "Windy",
+			"description": "Wind forecasts merged with Swedish electricity prices. With API keys required the JSON endpoints take a key as bearer token.",
+			"version":     gitSHA,
+		},
+		"servers": []object{{"url": "https://windy.edgecompute.app"}},
+		"paths":   paths,
+		"components": object{
+			"schemas":    s,
+			"parameters": params,
+			"responses": object{
+				"Problem": object{
+					"description": "Error",
+					"content":     object{"application/problem+json": object{"schema": object{"$ref": "#/components/schemas/Problem"}}},
+				},
+			},
+			"securitySchemes": object{
+				"bearer": object{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// handleOpenAPI handles /openapi.json.
+func handleOpenAPI(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	b, _ := json.MarshalIndent(openAPI(), "", "  ")
+	rw.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(rw, "%s\n", b)
+}
+
+// handleDocs handles /docs with Swagger UI for /openapi.json.
+func handleDocs(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	html, err := render.Render("docs.html", nil)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(rw, html)
+}
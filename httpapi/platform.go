@@ -0,0 +1,70 @@
+package httpapi
+
+import (
+	"context"
+	"io"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+	"github.com/fastly/compute-sdk-go/geo"
+	"github.com/fastly/compute-sdk-go/objectstore"
+	"github.com/fastly/compute-sdk-go/secretstore"
+)
+
+// The platform services used by the handlers. They are the Fastly ones,
+// the net/http server replaces them, see nethttp.go.
+var (
+	// sendRequest sends a request to the backend.
+	sendRequest = func(ctx context.Context, req *fsthttp.Request, backend string) (*fsthttp.Response, error) {
+		return req.Send(ctx, backend)
+	}
+	// lookupIP looks up the location of an IP.
+	lookupIP = geo.Lookup
+	// openKV opens the windy KV Store.
+	openKV = func() (kvStore, error) {
+		store, err := objectstore.Open(storeName)
+		if err != nil {
+			return nil, err
+		}
+		return fastlyKV{store}, nil
+	}
+	// readSecret reads a secret from the windy Secret Store.
+	readSecret = func(name string) ([]byte, error) {
+		store, err := secretstore.Open(storeName)
+		if err != nil {
+			return nil, err
+		}
+		sec, err := store.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		return sec.Plaintext()
+	}
+)
+
+// kvEntry is a value of the KV Store.
+type kvEntry interface {
+	io.Reader
+	String() string
+}
+
+// kvStore is the KV Store, missing keys are objectstore.ErrKeyNotFound.
+type kvStore interface {
+	Lookup(key string) (kvEntry, error)
+	Insert(key string, value io.Reader) error
+}
+
+type fastlyKV struct {
+	store *objectstore.Store
+}
+
+func (s fastlyKV) Lookup(key string) (kvEntry, error) {
+	e, err := s.store.Lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (s fastlyKV) Insert(key string, value io.Reader) error {
+	return s.store.Insert(key, value)
+}
@@ -0,0 +1,164 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"compute-starter-kit-go/elpris"
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// Prefs are the preferences of a visitor, stored in a signed cookie and
+// used as defaults for the query parameters of all views. The cookie is
+// readable by scripts, render/templates/theme.html applies the theme.
+type Prefs struct {
+	Unit   string `json:"unit,omitempty"`
+	Region string `json:"region,omitempty"`
+	Theme  string `json:"theme,omitempty"`
+	Lang   string `json:"lang,omitempty"`
+	Days   int    `json:"days,omitempty"`
+}
+
+var themes = []string{"light", "dark"}
+
+// handlePrefs handles /prefs, GET renders the preferences form and POST
+// saves the preferences in the prefs cookie.
+func handlePrefs(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	switch req.Method {
+	case "GET", "HEAD":
+		html, err := render.Render("prefs.html", struct {
+			Prefs
+			Units     []string
+			Regions   []string
+			Themes    []string
+			Languages []string
+			MaxDays   int
+		}{readPrefs(ctx, req), []string{"ms", "kn", "kmh", "mph", "bft"}, elpris.Regions, themes, render.Languages, maxDays})
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusInternalServerError, err)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(rw, html)
+	case "POST":
+		body, err := io.ReadAll(io.LimitReader(req.Body, 8*1024))
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadRequest, err)
+			return
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadRequest, err)
+			return
+		}
+		p, err := parsePrefs(form)
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadRequest, err)
+			return
+		}
+		key, err := cookieKey()
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusInternalServerError, err)
+			return
+		}
+		b, _ := json.Marshal(p)
+		value := base64.RawURLEncoding.EncodeToString(b)
+		rw.Header().Add("Set-Cookie", fmt.Sprintf("prefs=%s.%s; Path=/; Max-Age=31536000; SameSite=Lax", value, sign(value, key)))
+		rw.Header().Set("Location", "/")
+		rw.WriteHeader(fsthttp.StatusSeeOther)
+	}
+}
+
+// parsePrefs validates the posted preferences, empty values mean no
+// preference.
+func parsePrefs(form url.Values) (Prefs, error) {
+	p := Prefs{
+		Unit:   strings.ToLower(form.Get("unit")),
+		Region: strings.ToUpper(form.Get("region")),
+		Theme:  strings.ToLower(form.Get("theme")),
+		Lang:   strings.ToLower(form.Get("lang")),
+	}
+	if _, ok := units[p.Unit]; p.Unit != "" && !ok {
+		return Prefs{}, fmt.Errorf("unknown unit %q, use one of ms, kn, kmh, mph, bft", p.Unit)
+	}
+	if p.Region != "" && !validRegion(p.Region) {
+		return Prefs{}, fmt.Errorf("unknown region %q, use one of %s", p.Region, strings.Join(elpris.Regions, ", "))
+	}
+	if p.Theme != "" && !contains(themes, p.Theme) {
+		return Prefs{}, fmt.Errorf("unknown theme %q, use one of %s", p.Theme, strings.Join(themes, ", "))
+	}
+	if p.Lang != "" && !contains(render.Languages, p.Lang) {
+		return Prefs{}, fmt.Errorf("unknown lang %q, use one of %s", p.Lang, strings.Join(render.Languages, ", "))
+	}
+	if d := form.Get("days"); d != "" {
+		days, err := strconv.Atoi(d)
+		if err != nil || days < 1 || days > maxDays {
+			return Prefs{}, fmt.Errorf("invalid days %q, must be between 1 and %d", d, maxDays)
+		}
+		p.Days = days
+	}
+	return p, nil
+}
+
+// readPrefs returns the preferences in the prefs cookie, the zero Prefs if
+// there is none or the signature is invalid.
+func readPrefs(ctx context.Context, req *fsthttp.Request) Prefs {
+	value, sig, ok := strings.Cut(cookie(req, "prefs"), ".")
+	if !ok {
+		return Prefs{}
+	}
+	key, err := cookieKey()
+	if err != nil {
+		logln(ctx, "prefs:", err)
+		return Prefs{}
+	}
+	if !hmac.Equal([]byte(sig), []byte(sign(value, key))) {
+		return Prefs{}
+	}
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return Prefs{}
+	}
+	var p Prefs
+	json.Unmarshal(b, &p)
+	return p
+}
+
+// applyPrefs adds the preferences to the query of the request, parameters
+// in the query take precedence.
+func applyPrefs(req *fsthttp.Request, p Prefs) {
+	if p == (Prefs{}) {
+		return
+	}
+	q := req.URL.Query()
+	setDefault := func(name, value string) {
+		if value != "" && q.Get(name) == "" {
+			q.Set(name, value)
+		}
+	}
+	setDefault("unit", p.Unit)
+	setDefault("region", p.Region)
+	setDefault("lang", p.Lang)
+	setDefault("theme", p.Theme)
+	if p.Days > 0 && q.Get("hours") == "" {
+		setDefault("days", strconv.Itoa(p.Days))
+	}
+	req.URL.RawQuery = q.Encode()
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+)
+
+// preview is the Open Graph and Twitter card of a forecast page, shown
+// when a link to it is shared.
+type preview struct {
+	URL         string
+	Image       string
+	Description string
+	// OEmbed is the oEmbed discovery link of the page.
+	OEmbed string
+}
+
+// toPreview returns the card of the forecast on base, e.g.
+// https://windy.edgecompute.app, with the chart PNG as image.
+func toPreview(f *forecast, base string) *preview {
+	location := url.Values{"lat": {f.lat}, "long": {f.long}, "region": {f.region}, "unit": {f.unit.name}}
+	if f.height > 10 {
+		location.Set("height", strconv.Itoa(f.height))
+	}
+	return &preview{
+		URL:         base + "/wind.html?" + location.Encode(),
+		Image:       base + "/wind.png?" + location.Encode(),
+		Description: summary(f),
+		OEmbed:      base + "/oembed?url=" + url.QueryEscape(base+"/wind.html?"+location.Encode()),
+	}
+}
+
+// summary describes the current hour and the next 24 hours of the
+// forecast in a sentence or two.
+func summary(f *forecast) string {
+	e := currentEntry(f)
+	if e == nil {
+		return "No forecast available."
+	}
+	s := ""
+	if !f.noWind {
+		maxSpeed, maxGust := 0.0, 0.0
+		for _, n := range f.entries {
+			if !n.past && n.hour.Sub(e.hour).Hours() < 24 {
+				maxSpeed, maxGust = math.Max(maxSpeed, n.speed), math.Max(maxGust, n.gust)
+			}
+		}
+		l := f.unit.label
+		s = fmt.Sprintf("Now %.1f %s, gusts %.1f %s. Up to %.1f %s, gusts %.1f %s, in the next 24 hours.",
+			e.speed, l, e.gust, l, maxSpeed, l, maxGust, l)
+	}
+	if e.hasPrice {
+		if s != "" {
+			s += " "
+		}
+		s += fmt.Sprintf("Electricity %.2f %s in %s.", e.price, f.currency.priceUnit(), f.region)
+	}
+	return s
+}
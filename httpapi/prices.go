@@ -0,0 +1,225 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"compute-starter-kit-go/elpris"
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// stockholm is the timezone of the electricity prices.
+var stockholm, _ = time.LoadLocation("Europe/Stockholm")
+
+// Price is the JSON representation of the price of an hour.
+type Price struct {
+	Hour  string  `json:"hour"`
+	Price float64 `json:"price"`
+}
+
+// RegionPrices are the prices of a region.
+type RegionPrices struct {
+	Region  string       `json:"region"`
+	Summary []DaySummary `json:"summary"`
+	Prices  []Price      `json:"prices"`
+}
+
+// DaySummary is the lowest, highest and average price of a day.
+type DaySummary struct {
+	Date    string  `json:"date"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Average float64 `json:"average"`
+}
+
+// PricesResponse is the JSON envelope returned by /prices.json.
+type PricesResponse struct {
+	Unit        string         `json:"unit"`
+	Timezone    string         `json:"timezone"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Regions     []RegionPrices `json:"regions"`
+}
+
+// priceChart is the data rendered by render/templates/prices.html.
+type priceChart struct {
+	Title    string
+	Timezone string
+	Times    []string
+	Regions  []RegionPrices
+	Unit     string
+}
+
+// handlePrices handles /prices.json and /prices.html with the prices of the
+// regions given by the regions parameter, all regions by default. The prices
+// are for today and tomorrow unless a date is given.
+func handlePrices(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	rs, err := parseRegions(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	date, err := parseDate(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	cur, err := parseCurrency(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	prices, err := fetchRegions(ctx, rs, date)
+	if noPrices(err) {
+		writeError(rw, req, fsthttp.StatusNotFound, fmt.Errorf("no prices published for %s", date.Format("2006-01-02")))
+		return
+	}
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadGateway, err)
+		return
+	}
+	c, err := fetchCurrency(ctx, cur)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadGateway, err)
+		return
+	}
+	regionPrices := make([]RegionPrices, len(rs))
+	for i, r := range rs {
+		convertEntries(prices[i], c)
+		regionPrices[i] = toRegionPrices(r, prices[i])
+	}
+	if req.URL.Path == "/prices.html" {
+		title := fmt.Sprintf("Electricity prices for %s", strings.Join(rs, ", "))
+		if !date.IsZero() {
+			title = fmt.Sprintf("%s on %s", title, date.Format("2006-01-02"))
+		}
+		c := priceChart{
+			Title:    title,
+			Timezone: stockholm.String(),
+			Times:    mapSlice(prices[0], hourLabel(stockholm)),
+			Regions:  regionPrices,
+			Unit:     c.priceUnit(),
+		}
+		html, err := render.Render("prices.html", c)
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusInternalServerError, err)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(rw, html)
+		return
+	}
+	b, _ := json.MarshalIndent(PricesResponse{
+		Unit:        c.priceUnit(),
+		Timezone:    stockholm.String(),
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		Regions:     regionPrices,
+	}, "", "  ")
+	rw.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(rw, "%s\n", b)
+}
+
+// fetchRegions fetches the prices of the regions concurrently, for today
+// and tomorrow or for the date unless it is zero.
+func fetchRegions(ctx context.Context, rs []string, date time.Time) ([][]*entry, error) {
+	prices := make([][]*entry, len(rs))
+	errs := make([]error, len(rs))
+	var wg sync.WaitGroup
+	for i, r := range rs {
+		wg.Add(1)
+		go func(i int, r string) {
+			defer wg.Done()
+			defer func() {
+				if v := recover(); v != nil {
+					errs[i] = panicError(ctx, v)
+				}
+			}()
+			if date.IsZero() {
+				prices[i], errs[i] = fetchPrices(ctx, r)
+				return
+			}
+			prices[i], errs[i] = fetchPrice(ctx, r, date)
+		}(i, r)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return prices, nil
+}
+
+func toRegionPrices(r string, prices []*entry) RegionPrices {
+	return RegionPrices{
+		Region:  r,
+		Summary: summarize(prices),
+		Prices: mapSlice(prices, func(e *entry) Price {
+			return Price{Hour: e.hour.In(stockholm).Format(time.RFC3339), Price: round(e.price, 4)}
+		}),
+	}
+}
+
+// summarize computes the daily summaries of the prices.
+func summarize(prices []*entry) []DaySummary {
+	days := []DaySummary{}
+	count := 0
+	for _, p := range prices {
+		date := p.hour.In(stockholm).Format("2006-01-02")
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, DaySummary{Date: date, Min: p.price, Max: p.price})
+			count = 0
+		}
+		d := &days[len(days)-1]
+		d.Min = math.Min(d.Min, p.price)
+		d.Max = math.Max(d.Max, p.price)
+		d.Average = (d.Average*float64(count) + p.price) / float64(count+1)
+		count++
+	}
+	for i := range days {
+		days[i].Min = round(days[i].Min, 4)
+		days[i].Max = round(days[i].Max, 4)
+		days[i].Average = round(days[i].Average, 4)
+	}
+	return days
+}
+
+// parseDate reads the date parameter, e.g. date=2023-02-15, the zero time
+// if not given.
+func parseDate(req *fsthttp.Request) (time.Time, error) {
+	d := req.URL.Query().Get("date")
+	if d == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", d, stockholm)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", d)
+	}
+	return t, nil
+}
+
+// parseRegions reads the comma separated regions parameter, e.g.
+// regions=SE3,SE4, or the single region parameter.
+func parseRegions(req *fsthttp.Request) ([]string, error) {
+	q := req.URL.Query().Get("regions")
+	if q == "" {
+		q = req.URL.Query().Get("region")
+	}
+	if q == "" {
+		return elpris.Regions, nil
+	}
+	rs := []string{}
+	for _, r := range strings.Split(q, ",") {
+		r = strings.ToUpper(strings.TrimSpace(r))
+		if !validRegion(r) {
+			return nil, fmt.Errorf("unknown region %q, use one of %s", r, strings.Join(zones(), ", "))
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
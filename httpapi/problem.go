@@ -0,0 +1,80 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// Problem is an RFC 7807 problem details response.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance"`
+	RequestID string `json:"request_id"`
+}
+
+// statusTitles are the titles of the problems by status.
+var statusTitles = map[int]string{
+	fsthttp.StatusBadRequest:          "Bad Request",
+	fsthttp.StatusUnauthorized:        "Unauthorized",
+	fsthttp.StatusForbidden:           "Forbidden",
+	fsthttp.StatusNotFound:            "Not Found",
+	fsthttp.StatusMethodNotAllowed:    "Method Not Allowed",
+	fsthttp.StatusNotAcceptable:       "Not Acceptable",
+	fsthttp.StatusTooManyRequests:     "Too Many Requests",
+	fsthttp.StatusInternalServerError: "Internal Server Error",
+	fsthttp.StatusNotImplemented:      "Not Implemented",
+	fsthttp.StatusBadGateway:          "Bad Gateway",
+	fsthttp.StatusGatewayTimeout:      "Gateway Timeout",
+}
+
+// writeError responds with the error as problem+json, or as an HTML page
+// to browsers. Client errors, timeouts and unimplemented formats include
+// the error as detail, other server errors are logged with the request ID
+// and get a generic detail so upstream errors don't leak.
+func writeError(rw fsthttp.ResponseWriter, req *fsthttp.Request, status int, err error) {
+	p := Problem{
+		Type:      "about:blank",
+		Title:     statusTitles[status],
+		Status:    status,
+		Detail:    err.Error(),
+		Instance:  req.URL.Path,
+		RequestID: req.Header.Get("X-Request-ID"),
+	}
+	if status >= 500 && status != fsthttp.StatusGatewayTimeout && status != fsthttp.StatusNotImplemented {
+		logID(p.RequestID, "error", req.URL.Path, err)
+		p.Detail = "Something went wrong, please try again later."
+		if status == fsthttp.StatusBadGateway {
+			p.Detail = "An upstream service failed, please try again later."
+		}
+	}
+	if strings.Contains(req.Header.Get("Accept"), "text/html") {
+		lang := pageLang(req)
+		page := p
+		page.Title = render.T(lang, p.Title)
+		if status >= 500 {
+			page.Detail = render.T(lang, p.Detail)
+		}
+		html, err := render.Render("error.html", struct {
+			Problem
+			Lang string
+		}{page, lang})
+		if err == nil {
+			rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+			rw.Header().Add("Vary", "Accept-Language")
+			rw.WriteHeader(status)
+			fmt.Fprint(rw, html)
+			return
+		}
+	}
+	b, _ := json.MarshalIndent(p, "", "  ")
+	rw.Header().Set("Content-Type", "application/problem+json")
+	rw.WriteHeader(status)
+	fmt.Fprintf(rw, "%s\n", b)
+}
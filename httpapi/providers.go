@@ -0,0 +1,117 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"compute-starter-kit-go/awattar"
+	"compute-starter-kit-go/elpris"
+	"compute-starter-kit-go/entsoe"
+)
+
+// priceProvider fetches the prices of a zone on a day, the day is in
+// Swedish time and the prices are in SEK/kWh.
+type priceProvider interface {
+	day(ctx context.Context, zone string, day time.Time) ([]*entry, error)
+}
+
+// providerFor returns the provider of the zone, elprisetjustnu.se for the
+// Swedish zones, aWATTar for Germany and Austria and the ENTSO-E
+// Transparency Platform for the rest.
+func providerFor(zone string) priceProvider {
+	if contains(elpris.Regions, zone) {
+		return elprisProvider{}
+	}
+	if _, ok := awattar.Hosts[zone]; ok {
+		return awattarProvider{}
+	}
+	return entsoeProvider{}
+}
+
+// zones are the names of the price zones, the Swedish ones first.
+func zones() []string {
+	others := entsoe.ZoneNames()
+	for z := range awattar.Hosts {
+		others = append(others, z)
+	}
+	sort.Strings(others)
+	zs := append([]string{}, elpris.Regions...)
+	for _, z := range others {
+		if !contains(zs, z) {
+			zs = append(zs, z)
+		}
+	}
+	return zs
+}
+
+// noPrices reports whether the error is from a provider that has not
+// published the prices of the day yet.
+func noPrices(err error) bool {
+	return errors.Is(err, elpris.ErrNoPrices) || errors.Is(err, entsoe.ErrNoPrices) || errors.Is(err, awattar.ErrNoPrices)
+}
+
+type elprisProvider struct{}
+
+func (elprisProvider) day(ctx context.Context, zone string, day time.Time) ([]*entry, error) {
+	prices, err := priceClient.Day(ctx, zone, day)
+	if err != nil {
+		return nil, err
+	}
+	return mapSlice(prices, func(p elpris.Price) *entry {
+		return &entry{hour: p.Start, price: p.SEKPerKWh}
+	}), nil
+}
+
+// sekPerKWh returns a function converting EUR/MWh to SEK/kWh with the
+// exchange rate of the day.
+func sekPerKWh(ctx context.Context) (func(eurPerMWh float64) float64, error) {
+	eur, err := fetchCurrency(ctx, "EUR")
+	if err != nil {
+		return nil, err
+	}
+	return func(eurPerMWh float64) float64 {
+		return eurPerMWh / 1000 / eur.rate
+	}, nil
+}
+
+// entsoeProvider has the prices of ENTSO-E in EUR/MWh. The API token is
+// the entsoe-token secret.
+type entsoeProvider struct{}
+
+func (entsoeProvider) day(ctx context.Context, zone string, day time.Time) ([]*entry, error) {
+	token, err := secret("entsoe-token")
+	if err != nil {
+		return nil, err
+	}
+	c := &entsoe.Client{Transport: transport{}, Token: string(token)}
+	prices, err := c.Day(ctx, zone, day)
+	if err != nil {
+		return nil, err
+	}
+	convert, err := sekPerKWh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mapSlice(prices, func(p entsoe.Price) *entry {
+		return &entry{hour: p.Start, price: convert(p.EURPerMWh)}
+	}), nil
+}
+
+// awattarProvider has the EPEX Spot prices of aWATTar in EUR/MWh.
+type awattarProvider struct{}
+
+func (awattarProvider) day(ctx context.Context, zone string, day time.Time) ([]*entry, error) {
+	prices, err := awattarClient.Day(ctx, zone, day)
+	if err != nil {
+		return nil, err
+	}
+	convert, err := sekPerKWh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mapSlice(prices, func(p awattar.Price) *entry {
+		return &entry{hour: p.Start, price: convert(p.EURPerMWh)}
+	}), nil
+}
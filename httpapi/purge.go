@@ -0,0 +1,63 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// validSurrogateKey matches the surrogate keys set by surrogateKeys.
+var validSurrogateKey = regexp.MustCompile(`^[A-Za-z0-9.,_-]{1,100}$`)
+
+// surrogateKeys tags a forecast response so it can be purged by region,
+// e.g. region-SE4 when the prices are corrected, or by location bucket.
+func surrogateKeys(f *forecast) string {
+	return fmt.Sprintf("wind region-%s loc-%s,%s", f.region, bucket(f.lat), bucket(f.long))
+}
+
+// handlePurge handles POST /admin/purge?key=region-SE4 and purges the
+// responses tagged with the surrogate key through the Fastly API. It
+// requires the admin-token secret as bearer token, the API token is the
+// fastly-api-token secret.
+func handlePurge(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	if !checkAdmin(rw, req) {
+		return
+	}
+	key := req.URL.Query().Get("key")
+	if !validSurrogateKey.MatchString(key) {
+		writeError(rw, req, fsthttp.StatusBadRequest, fmt.Errorf("invalid key %q", key))
+		return
+	}
+	apiToken, err := secret("fastly-api-token")
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	u := fmt.Sprintf("https://api.fastly.com/service/%s/purge/%s", os.Getenv("FASTLY_SERVICE_ID"), url.PathEscape(key))
+	preq, _ := newUpstreamRequest(ctx, "POST", u)
+	preq.Header.Set("Fastly-Key", string(apiToken))
+	preq.Header.Set("Accept", "application/json")
+	preq.CacheOptions.Pass = true
+	resp, err := sendUpstream(ctx, preq, config("backend_fastly-api", "fastly-api"))
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadGateway, err)
+		return
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadGateway, err)
+		return
+	}
+	if resp.StatusCode != fsthttp.StatusOK {
+		writeError(rw, req, fsthttp.StatusBadGateway, fmt.Errorf("purge of %s failed with status %d: %s", key, resp.StatusCode, body))
+		return
+	}
+	logln(ctx, "purged", key)
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(body)
+}
@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// validNtfyTopic matches the topics of ntfy.sh.
+var validNtfyTopic = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// validPushoverUser matches Pushover user and group keys.
+var validPushoverUser = regexp.MustCompile(`^[A-Za-z0-9]{30}$`)
+
+// notify delivers the windows of the alert to its callback, ntfy topic or
+// Pushover user.
+func notify(ctx context.Context, a Alert, windows []AlertWindow, forecast string) error {
+	switch {
+	case a.Ntfy != "":
+		return postNtfy(ctx, a, windows, forecast)
+	case a.Pushover != "":
+		return postPushover(ctx, a, windows, forecast)
+	}
+	return postAlert(ctx, a, windows, forecast)
+}
+
+// postNtfy publishes the windows to the ntfy topic of the alert, the
+// notification opens the forecast.
+func postNtfy(ctx context.Context, a Alert, windows []AlertWindow, forecast string) error {
+	preq, err := newUpstreamRequest(ctx, "POST", config("ntfy_url", "https://ntfy.sh")+"/"+a.Ntfy)
+	if err != nil {
+		return err
+	}
+	preq.Body = io.NopCloser(strings.NewReader(alertMessage(a, windows)))
+	preq.Header.Set("Title", alertTitle(a))
+	preq.Header.Set("Click", forecast)
+	preq.Header.Set("Tags", "wind_face")
+	preq.CacheOptions.Pass = true
+	return sendPush(ctx, preq, config("backend_ntfy", "ntfy"))
+}
+
+// postPushover sends the windows to the Pushover user of the alert with the
+// application token in the pushover-token secret.
+func postPushover(ctx context.Context, a Alert, windows []AlertWindow, forecast string) error {
+	token, err := secret("pushover-token")
+	if err != nil {
+		return err
+	}
+	form := url.Values{
+		"token":     {string(token)},
+		"user":      {a.Pushover},
+		"title":     {alertTitle(a)},
+		"message":   {alertMessage(a, windows)},
+		"url":       {forecast},
+		"url_title": {"Forecast"},
+	}
+	preq, err := newUpstreamRequest(ctx, "POST", "https://api.pushover.net/1/messages.json")
+	if err != nil {
+		return err
+	}
+	preq.Body = io.NopCloser(bytes.NewReader([]byte(form.Encode())))
+	preq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	preq.CacheOptions.Pass = true
+	return sendPush(ctx, preq, config("backend_pushover", "pushover"))
+}
+
+func sendPush(ctx context.Context, preq *fsthttp.Request, backend string) error {
+	resp, err := sendUpstream(ctx, preq, backend)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s failed with status %d: %s", backend, resp.StatusCode, body)
+	}
+	return nil
+}
+
+// alertTitle returns the title of a notification, e.g. Wind in lomma.
+func alertTitle(a Alert) string {
+	if a.Spot != "" {
+		return "Wind in " + a.Spot
+	}
+	return fmt.Sprintf("Wind at %.2f, %.2f", a.Latitude, a.Longitude)
+}
+
+// alertMessage returns a line for each window, e.g. Sat 13:00-17:00 up to
+// 18.2 knots, gusts 24.1 knots.
+func alertMessage(a Alert, windows []AlertWindow) string {
+	label := units[a.Unit].label
+	lines := []string{}
+	for _, w := range windows {
+		start, _ := time.Parse(time.RFC3339, w.Start)
+		end, _ := time.Parse(time.RFC3339, w.End)
+		line := fmt.Sprintf("%s %s-%s up to %.1f %s, gusts %.1f %s", start.Format("Mon"), start.Format("15:04"), end.Format("15:04"), w.MaxSpeed, label, w.MaxGust, label)
+		if w.AveragePrice != nil {
+			line += fmt.Sprintf(", %.2f %s", *w.AveragePrice, w.PriceUnit)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
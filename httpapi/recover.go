@@ -0,0 +1,40 @@
+package httpapi
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// errPanic is the error of a recovered panic, the details are logged
+// rather than returned.
+type errPanic struct {
+	value interface{}
+}
+
+func (e *errPanic) Error() string {
+	return "internal error"
+}
+
+// panicError logs the recovered value with the stack and returns it as an
+// error.
+func panicError(ctx context.Context, v interface{}) error {
+	logln(ctx, "panic:", v, "\n"+string(debug.Stack()))
+	return &errPanic{value: v}
+}
+
+// recoverPanic responds with a 500 problem when the handler panics, instead
+// of the instance dying mid-response. It must be deferred. If the header is
+// already written the response is cut short.
+func recoverPanic(ctx context.Context, tw *timingWriter, req *fsthttp.Request) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	err := panicError(ctx, v)
+	if tw.wroteHeader {
+		return
+	}
+	writeError(tw, req, fsthttp.StatusInternalServerError, err)
+}
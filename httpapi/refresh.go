@@ -0,0 +1,49 @@
+package httpapi
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// refresh makes the chart page update itself, rendered by
+// render/templates/wind.html.
+type refresh struct {
+	// Seconds is the interval between the updates.
+	Seconds int
+	// URL is the JSON of the forecast the chart is updated from.
+	URL string
+	// GoodScore, GoodSession and FairSession are the thresholds of the
+	// bands and point colors.
+	GoodScore, GoodSession, FairSession float64
+}
+
+// parseRefresh reads the refresh parameter, e.g. 15m, nil if none is
+// given. The page then fetches the JSON of the forecast at the interval,
+// the same path with .json, and updates the chart in place.
+func parseRefresh(req *fsthttp.Request) (*refresh, error) {
+	q := req.URL.Query()
+	s := q.Get("refresh")
+	if s == "" {
+		return nil, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < time.Minute || d > 24*time.Hour {
+		return nil, fmt.Errorf("invalid refresh %q, use a duration from 1m to 24h, e.g. 15m", s)
+	}
+	q.Del("refresh")
+	q.Del("theme")
+	u := strings.TrimSuffix(req.URL.Path, ".html") + ".json"
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return &refresh{
+		Seconds:     int(d.Seconds()),
+		URL:         u,
+		GoodScore:   goodScore,
+		GoodSession: goodSession,
+		FairSession: fairSession,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// validRequestID matches the request IDs accepted from clients and
+// upstream proxies.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{8,64}$`)
+
+// requestState is what is collected while serving a request: its ID, the
+// timed steps, the upstream calls and the newest Date of the upstream
+// responses. It is kept in the context of the request.
+type requestState struct {
+	id string
+
+	mu            sync.Mutex
+	timings       []*timing
+	upstreamCalls []UpstreamCall
+	modified      time.Time
+}
+
+type stateKey struct{}
+
+// withRequestState returns the context with a new state of the request.
+// The ID is taken from the X-Request-ID header when a proxy in front has
+// set one, else the Fastly trace ID, or a random ID when running locally.
+// The header is set to the ID for the handlers that only have the request.
+func withRequestState(ctx context.Context, req *fsthttp.Request) (context.Context, *requestState) {
+	s := &requestState{}
+	switch id := req.Header.Get("X-Request-ID"); {
+	case validRequestID.MatchString(id):
+		s.id = id
+	case os.Getenv("FASTLY_TRACE_ID") != "":
+		s.id = os.Getenv("FASTLY_TRACE_ID")
+	default:
+		s.id = newVisitorID()
+	}
+	req.Header.Set("X-Request-ID", s.id)
+	return context.WithValue(ctx, stateKey{}, s), s
+}
+
+// stateOf returns the state of the request of the context, outside of a
+// request, e.g. in a test, a new state is returned.
+func stateOf(ctx context.Context) *requestState {
+	if s, ok := ctx.Value(stateKey{}).(*requestState); ok {
+		return s
+	}
+	return &requestState{id: newVisitorID()}
+}
+
+// requestID returns the ID of the request.
+func requestID(ctx context.Context) string {
+	return stateOf(ctx).id
+}
+
+// logln logs the arguments prefixed with the request ID.
+func logln(ctx context.Context, a ...interface{}) {
+	logID(requestID(ctx), a...)
+}
+
+// logID logs the arguments prefixed with the ID.
+func logID(id string, a ...interface{}) {
+	fmt.Println(append([]interface{}{"[" + id + "]"}, a...)...)
+}
+
+// newUpstreamRequest creates a request to an upstream, forwarding the
+// request ID.
+func newUpstreamRequest(ctx context.Context, method, u string) (*fsthttp.Request, error) {
+	req, err := fsthttp.NewRequest(method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Request-ID", requestID(ctx))
+	return req, nil
+}
@@ -0,0 +1,103 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// handlerFunc handles a request, params are the path parameters of the
+// matched route.
+type handlerFunc func(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string)
+
+// route is a method and a path pattern. Pattern segments may end with a
+// parameter, e.g. /spots/:id or /wind.:format, matching the rest of the
+// segment.
+type route struct {
+	method  string
+	pattern []string
+	handle  handlerFunc
+}
+
+// router dispatches requests to the first route matching the path and
+// method. Unknown paths get a 404 page, OPTIONS the allowed methods and
+// known paths with the wrong method a 405.
+type router struct {
+	routes []route
+}
+
+func (r *router) handle(method, pattern string, h handlerFunc) {
+	r.routes = append(r.routes, route{method: method, pattern: strings.Split(pattern, "/"), handle: h})
+}
+
+func (r *router) serve(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	segments := strings.Split(req.URL.Path, "/")
+	allowed := []string{}
+	for _, rt := range r.routes {
+		params, ok := match(rt.pattern, segments)
+		if !ok {
+			continue
+		}
+		if rt.method == req.Method || rt.method == "GET" && req.Method == "HEAD" {
+			rt.handle(ctx, rw, req, params)
+			return
+		}
+		allowed = append(allowed, rt.method)
+		if rt.method == "GET" {
+			allowed = append(allowed, "HEAD")
+		}
+	}
+	if len(allowed) > 0 {
+		allow := strings.Join(append(allowed, "OPTIONS"), ", ")
+		rw.Header().Set("Allow", allow)
+		if req.Method == "OPTIONS" {
+			if rw.Header().Get("Access-Control-Allow-Origin") != "" {
+				rw.Header().Set("Access-Control-Allow-Methods", allow)
+			}
+			rw.WriteHeader(fsthttp.StatusNoContent)
+			return
+		}
+		writeError(rw, req, fsthttp.StatusMethodNotAllowed, errors.New("this method is not allowed"))
+		return
+	}
+	notFound(rw, req)
+}
+
+// match matches the path segments against the pattern and returns the
+// path parameters.
+func match(pattern, segments []string) (map[string]string, bool) {
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, p := range pattern {
+		prefix, name, isParam := strings.Cut(p, ":")
+		if !isParam {
+			if p != segments[i] {
+				return nil, false
+			}
+			continue
+		}
+		value := strings.TrimPrefix(segments[i], prefix)
+		if value == segments[i] && prefix != "" || value == "" {
+			return nil, false
+		}
+		params[name] = value
+	}
+	return params, true
+}
+
+// notFound responds with a 404 problem.
+func notFound(rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	writeError(rw, req, fsthttp.StatusNotFound, fmt.Errorf("there is nothing at %s", req.URL.Path))
+}
+
+// plain adapts a handler without path parameters.
+func plain(h func(context.Context, fsthttp.ResponseWriter, *fsthttp.Request)) handlerFunc {
+	return func(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, _ map[string]string) {
+		h(ctx, rw, req)
+	}
+}
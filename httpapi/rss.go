@@ -0,0 +1,83 @@
+package httpapi
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/geo"
+)
+
+// RSS is an RSS 2.0 feed of the daily conditions, /wind.rss.
+type RSS struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel RSSChannel `xml:"channel"`
+}
+
+// RSSChannel is the feed of a location.
+type RSSChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	TTL           int       `xml:"ttl"`
+	Items         []RSSItem `xml:"item"`
+}
+
+// RSSItem summarizes a day, the GUID is the same for the day so readers
+// update it rather than adding a new item.
+type RSSItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        RSSGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+// RSSGUID is the ID of an item, not a link.
+type RSSGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	ID          string `xml:",chardata"`
+}
+
+// toRSS returns a feed with an item per day of the forecast with the max
+// wind and gust and the average price, linking to the hourly forecast of
+// the day on base, e.g. https://windy.edgecompute.app.
+func toRSS(f *forecast, g *geo.Geo, base string) string {
+	location := url.Values{"lat": {f.lat}, "long": {f.long}, "region": {f.region}, "unit": {f.unit.name}}
+	ch := RSSChannel{
+		Title:         fmt.Sprintf("%s, prices for %s", f.title(g), f.region),
+		Link:          base + "/wind/daily.html?" + location.Encode(),
+		Description:   "Daily max wind, max gust and average electricity price",
+		Language:      "en",
+		LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+		TTL:           60,
+	}
+	for _, d := range aggregateDaily(f) {
+		day, _ := time.ParseInLocation("2006-01-02", d.Date, f.timezone)
+		title := fmt.Sprintf("%s: wind up to %.1f %s, gusts %.1f %s", day.Format("Mon 2 Jan"), d.MaxSpeed, f.unit.label, d.MaxGust, f.unit.label)
+		if d.AveragePrice != nil {
+			title += fmt.Sprintf(", %.2f %s", *d.AveragePrice, f.currency.priceUnit())
+		}
+		description := fmt.Sprintf("Wind %.1f-%.1f %s, on average %.1f %s, gusts up to %.1f %s.", d.MinSpeed, d.MaxSpeed, f.unit.label, d.AverageSpeed, f.unit.label, d.MaxGust, f.unit.label)
+		if d.AveragePrice != nil {
+			description += fmt.Sprintf(" Electricity %.2f %s on average.", *d.AveragePrice, f.currency.priceUnit())
+		}
+		q := url.Values{"from": {d.Date}, "to": {d.Date}}
+		for k, v := range location {
+			q[k] = v
+		}
+		ch.Items = append(ch.Items, RSSItem{
+			Title:       title,
+			Link:        base + "/wind.html?" + q.Encode(),
+			Description: description,
+			GUID:        RSSGUID{ID: fmt.Sprintf("windy:%s,%s:%s:%s", f.lat, f.long, f.region, d.Date)},
+			PubDate:     day.Format(time.RFC1123Z),
+		})
+	}
+	b, _ := xml.MarshalIndent(RSS{Version: "2.0", Channel: ch}, "", "  ")
+	return xml.Header + string(b) + "\n"
+}
@@ -0,0 +1,47 @@
+package httpapi
+
+import "math"
+
+// goodScore is the score above which it is a good time to run heavy loads.
+const goodScore = 70
+
+// scoreEntries scores each hour with a price from 0 to 100, combining a
+// low price with strong wind, a proxy for a large share of wind power. Both
+// are relative to the other hours in the forecast.
+func scoreEntries(entries []*entry) {
+	minPrice, maxPrice := math.Inf(1), math.Inf(-1)
+	minSpeed, maxSpeed := math.Inf(1), math.Inf(-1)
+	for _, e := range entries {
+		if !e.hasPrice {
+			continue
+		}
+		minPrice, maxPrice = math.Min(minPrice, e.price), math.Max(maxPrice, e.price)
+		minSpeed, maxSpeed = math.Min(minSpeed, e.speed), math.Max(maxSpeed, e.speed)
+	}
+	for _, e := range entries {
+		if !e.hasPrice {
+			continue
+		}
+		priceScore := normalize(maxPrice-e.price, maxPrice-minPrice)
+		windScore := normalize(e.speed-minSpeed, maxSpeed-minSpeed)
+		e.score = math.Round(50*priceScore + 50*windScore)
+	}
+}
+
+// normalize returns v/span, or 0.5 if all values are equal.
+func normalize(v, span float64) float64 {
+	if span == 0 {
+		return 0.5
+	}
+	return v / span
+}
+
+// score returns the score of an entry, nil if there is no price for the
+// hour.
+func score(e *entry) *float64 {
+	if !e.hasPrice {
+		return nil
+	}
+	s := e.score
+	return &s
+}
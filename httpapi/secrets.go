@@ -0,0 +1,68 @@
+package httpapi
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// secretCache holds the secrets read by the instance, see cacheSettings.
+var (
+	secretCache   = map[string][]byte{}
+	secretCacheMu sync.Mutex
+)
+
+// secret returns the named secret from the windy Secret Store. Credentials
+// of backends and tokens of endpoints belong there, never in the code or
+// the Config Store.
+func secret(name string) ([]byte, error) {
+	if cacheSettings {
+		secretCacheMu.Lock()
+		s, ok := secretCache[name]
+		secretCacheMu.Unlock()
+		if ok {
+			return s, nil
+		}
+	}
+	s, err := readSecret(name)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: %w", name, err)
+	}
+	if len(s) == 0 {
+		return nil, fmt.Errorf("secret %s is empty", name)
+	}
+	if cacheSettings {
+		secretCacheMu.Lock()
+		secretCache[name] = s
+		secretCacheMu.Unlock()
+	}
+	return s, nil
+}
+
+// bearerToken returns the token of the Authorization header, empty unless
+// the header is "Bearer <token>".
+func bearerToken(req *fsthttp.Request) string {
+	h := req.Header.Get("Authorization")
+	if len(h) < len("Bearer ") || !strings.EqualFold(h[:len("Bearer ")], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(h[len("Bearer "):])
+}
+
+// hasToken reports whether the request has the named secret as bearer
+// token. A missing secret denies all requests.
+func hasToken(req *fsthttp.Request, name string) bool {
+	token := bearerToken(req)
+	if token == "" {
+		return false
+	}
+	want, err := secret(name)
+	if err != nil {
+		logID(req.Header.Get("X-Request-ID"), "token:", err)
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), want) == 1
+}
@@ -0,0 +1,150 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// goodSession is the session score above which an hour is worth rigging
+// up for.
+const goodSession = 70
+
+// fairSession is the session score above which an hour is rideable.
+const fairSession = 30
+
+// SessionHour is the session score of an hour at a spot.
+type SessionHour struct {
+	Hour      string  `json:"hour"`
+	Speed     float64 `json:"speed"`
+	Gust      float64 `json:"gust"`
+	Direction float64 `json:"direction"`
+	Score     float64 `json:"score"`
+}
+
+// ScoreResponse is the JSON envelope returned by /score.json.
+type ScoreResponse struct {
+	Spot     Spot          `json:"spot"`
+	Unit     string        `json:"unit"`
+	Timezone string        `json:"timezone"`
+	Best     *SessionHour  `json:"best"`
+	Hours    []SessionHour `json:"hours"`
+}
+
+// handleScore handles /score.json?spot=lomma and responds with the session
+// score of each hour at the spot.
+func handleScore(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	sp, err := parseSpot(ctx, req)
+	if errors.Is(err, errUnknownSpot) {
+		writeError(rw, req, fsthttp.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	if sp == nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, errors.New("spot is required"))
+		return
+	}
+	u, err := parseUnit(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	days, hours, err := parseWindow(ctx, req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	lat, long := strconv.FormatFloat(sp.lat, 'f', 2, 64), strconv.FormatFloat(sp.long, 'f', 2, 64)
+	f, err := fetchForecast(ctx, lat, long, sp.region, days, hours, 0, 10, units["ms"])
+	if err != nil {
+		writeError(rw, req, upstreamStatus(err), err)
+		return
+	}
+	scoreSessions(f.entries, sp)
+	convertUnits(f.entries, u)
+	r := ScoreResponse{
+		Spot:     toSpot(*sp),
+		Unit:     u.label,
+		Timezone: f.timezone.String(),
+	}
+	for _, e := range f.entries {
+		h := SessionHour{
+			Hour:      e.hour.In(f.timezone).Format(time.RFC3339),
+			Speed:     round(e.speed, 2),
+			Gust:      round(e.gust, 2),
+			Direction: e.direction,
+			Score:     e.session,
+		}
+		r.Hours = append(r.Hours, h)
+		if r.Best == nil || h.Score > r.Best.Score {
+			r.Best = &r.Hours[len(r.Hours)-1]
+		}
+	}
+	b, _ := json.MarshalIndent(r, "", "  ")
+	rw.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(rw, "%s\n", b)
+}
+
+// scoreSessions scores each hour from 0 to 100 for kitesurfing or windsurfing
+// at the spot, the entries must be in m/s. The score is the product of the
+// speed, the gust factor and the direction scores, since any of them can
+// ruin a session.
+func scoreSessions(entries []*entry, sp *spot) {
+	for _, e := range entries {
+		e.session = math.Round(100 * speedScore(e.speed) * gustScore(e.speed, e.gust) * directionScore(e.direction, sp))
+	}
+}
+
+// speedScore is 1 between 8 and 14 m/s, falling to 0 at 5 and 20 m/s.
+func speedScore(speed float64) float64 {
+	switch {
+	case speed <= 5 || speed >= 20:
+		return 0
+	case speed < 8:
+		return (speed - 5) / 3
+	case speed > 14:
+		return (20 - speed) / 6
+	}
+	return 1
+}
+
+// gustScore is 1 for a gust factor up to 1.3, falling to 0 at 1.8. Gusty
+// wind is hard to ride.
+func gustScore(speed, gust float64) float64 {
+	if speed == 0 {
+		return 0
+	}
+	return math.Max(0, math.Min(1, (1.8-gust/speed)/0.5))
+}
+
+// directionScore is 1 inside the rideable window of the spot, falling to 0
+// 30° outside of it.
+func directionScore(direction float64, sp *spot) float64 {
+	width := math.Mod(sp.to-sp.from+360, 360)
+	offset := math.Mod(direction-sp.from+360, 360)
+	if offset <= width {
+		return 1
+	}
+	outside := math.Min(offset-width, 360-offset)
+	return math.Max(0, 1-outside/30)
+}
+
+// sessionColor returns the color of the point of an hour in the chart.
+func sessionColor(e *entry) string {
+	switch {
+	case e.session >= goodSession:
+		return "green"
+	case e.session >= fairSession:
+		return "orange"
+	}
+	return "lightgray"
+}
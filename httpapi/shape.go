@@ -0,0 +1,122 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// shape selects the fields of the entries of /wind.json and whether they
+// are columns, an array per field, rather than an array of objects.
+type shape struct {
+	fields []string
+	flat   bool
+}
+
+// parseShape reads the fields parameter, e.g. fields=hour,price, and the
+// flat parameter, nil if neither is given.
+func parseShape(req *fsthttp.Request) (*shape, error) {
+	q := req.URL.Query()
+	if q.Get("fields") == "" && q.Get("flat") == "" {
+		return nil, nil
+	}
+	s := &shape{}
+	switch q.Get("flat") {
+	case "", "0":
+	case "1":
+		s.flat = true
+	default:
+		return nil, fmt.Errorf("invalid flat %q, use 1 or 0", q.Get("flat"))
+	}
+	t := reflect.TypeOf(Entry{})
+	for _, name := range strings.Split(q.Get("fields"), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if _, ok := jsonField(t, name); !ok {
+			return nil, fmt.Errorf("unknown field %q, use the fields of the entries, e.g. hour,speed,price", name)
+		}
+		s.fields = append(s.fields, name)
+	}
+	return s, nil
+}
+
+// key is added to the ETag of the shaped response.
+func (s *shape) key() string {
+	return fmt.Sprintf("fields=%s&flat=%t", strings.Join(s.fields, ","), s.flat)
+}
+
+// toShapedJSON returns the JSON of /wind.json with the entries shaped.
+// Without fields all fields are included, in the flat form except the
+// optional ones without any values.
+func toShapedJSON(f *forecast, s *shape) string {
+	r := toResponse(f)
+	fields := s.fields
+	if len(fields) == 0 {
+		fields = entryFields(r.Entries)
+	}
+	var entries interface{}
+	if s.flat {
+		columns := orderedObject{}
+		for _, name := range fields {
+			column := make([]interface{}, len(r.Entries))
+			for i, e := range r.Entries {
+				column[i] = entryValue(e, name)
+			}
+			columns = append(columns, orderedField{name, column})
+		}
+		entries = columns
+	} else {
+		objects := make([]orderedObject, len(r.Entries))
+		for i, e := range r.Entries {
+			for _, name := range fields {
+				objects[i] = append(objects[i], orderedField{name, entryValue(e, name)})
+			}
+		}
+		entries = objects
+	}
+	o := orderedObject{}
+	v := reflect.ValueOf(r)
+	for i := 0; i < v.NumField(); i++ {
+		name, opts, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if name == "entries" {
+			o = append(o, orderedField{name, entries})
+			continue
+		}
+		if strings.Contains(opts, "omitempty") && v.Field(i).IsZero() {
+			continue
+		}
+		o = append(o, orderedField{name, v.Field(i).Interface()})
+	}
+	b, _ := json.MarshalIndent(o, "", "  ")
+	return string(b)
+}
+
+// entryFields returns the fields of the entries, leaving out the optional
+// fields that no entry has.
+func entryFields(entries []Entry) []string {
+	fields := []string{}
+	t := reflect.TypeOf(Entry{})
+	for i := 0; i < t.NumField(); i++ {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		used := !strings.Contains(opts, "omitempty")
+		for _, e := range entries {
+			if used {
+				break
+			}
+			used = !reflect.ValueOf(e).Field(i).IsZero()
+		}
+		if used {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+func entryValue(e Entry, name string) interface{} {
+	f, _ := jsonField(reflect.TypeOf(e), name)
+	return reflect.ValueOf(e).FieldByIndex(f.Index).Interface()
+}
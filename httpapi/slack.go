@@ -0,0 +1,192 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// slackMaxAge is the maximum age of a signed Slack request, older ones may
+// be replays.
+const slackMaxAge = 5 * time.Minute
+
+// SlackMessage is a Block Kit message responding to a slash command.
+type SlackMessage struct {
+	ResponseType string       `json:"response_type"`
+	Text         string       `json:"text"`
+	Blocks       []SlackBlock `json:"blocks,omitempty"`
+}
+
+// SlackBlock is a block of a Block Kit message.
+type SlackBlock struct {
+	Type     string      `json:"type"`
+	Text     *SlackText  `json:"text,omitempty"`
+	Fields   []SlackText `json:"fields,omitempty"`
+	Elements []SlackText `json:"elements,omitempty"`
+	ImageURL string      `json:"image_url,omitempty"`
+	AltText  string      `json:"alt_text,omitempty"`
+}
+
+// SlackText is a plain_text or mrkdwn text object.
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// handleSlack handles POST /slack, the slash command /wind lomma, and
+// responds with the next 24 hours at the spot or place. The request must
+// be signed with the slack-signing-secret from the Secret Store. A unit
+// may follow the place, e.g. /wind lomma kn.
+func handleSlack(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, 64*1024))
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	if err := verifySlack(ctx, req, body, time.Now()); err != nil {
+		writeError(rw, req, fsthttp.StatusUnauthorized, err)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	name, u := parseCommand(form.Get("text"))
+	if name == "" {
+		writeSlack(rw, SlackMessage{ResponseType: "ephemeral", Text: fmt.Sprintf("Usage: %s <spot or place> [ms|kn|kmh|mph|bft], e.g. %[1]s lomma kn", form.Get("command"))})
+		return
+	}
+	l, err := findLocation(ctx, name)
+	if err != nil {
+		writeSlack(rw, SlackMessage{ResponseType: "ephemeral", Text: fmt.Sprintf("Sorry, %s.", err)})
+		return
+	}
+	forecasts, errs := fetchAll(ctx, []batchLocation{l}, 3, 24, u)
+	if errs[0] != nil {
+		logln(ctx, "slack:", errs[0])
+		writeSlack(rw, SlackMessage{ResponseType: "ephemeral", Text: "The forecast is unavailable, please try again later."})
+		return
+	}
+	writeSlack(rw, toSlack(forecasts[0], "https://"+req.Host))
+}
+
+// verifySlack verifies the signature of a Slack request, the HMAC-SHA256
+// of the version, timestamp and body keyed with the signing secret.
+func verifySlack(ctx context.Context, req *fsthttp.Request, body []byte, now time.Time) error {
+	ts := req.Header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("missing Slack request timestamp")
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > slackMaxAge || age < -slackMaxAge {
+		return errors.New("expired Slack request")
+	}
+	key, err := secret("slack-signing-secret")
+	if err != nil {
+		logln(ctx, "slack:", err)
+		return errors.New("invalid Slack signature")
+	}
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(req.Header.Get("X-Slack-Signature")), []byte(want)) {
+		return errors.New("invalid Slack signature")
+	}
+	return nil
+}
+
+// parseCommand returns the spot or place of a chat command like
+// "lomma kn" together with the unit following it, m/s without one.
+func parseCommand(text string) (string, unit) {
+	words := strings.Fields(text)
+	u := units["ms"]
+	if len(words) > 1 {
+		if un, ok := units[strings.ToLower(words[len(words)-1])]; ok {
+			u, words = un, words[:len(words)-1]
+		}
+	}
+	return strings.Join(words, " "), u
+}
+
+// findLocation returns the spot with the id or the best matching place
+// with the name.
+func findLocation(ctx context.Context, name string) (batchLocation, error) {
+	if sp, err := findSpot(ctx, name); err == nil {
+		return resolveLocation(ctx, spotLocation(sp, "", ""))
+	}
+	places, err := geocode(ctx, name, "")
+	if err != nil {
+		return batchLocation{}, errors.New("the place lookup is unavailable, please try again later")
+	}
+	if len(places) == 0 {
+		return batchLocation{}, fmt.Errorf("there is no spot or place called %s", name)
+	}
+	p, ok := pickPlace(name, places)
+	if !ok {
+		p = places[0]
+	}
+	return resolveLocation(ctx, batchLocation{Name: p.String(), Lat: p.lat, Long: p.long})
+}
+
+// toSlack returns a message with the summary and the highlights of the
+// forecast and the chart from base, e.g. https://windy.edgecompute.app.
+func toSlack(f *forecast, base string) SlackMessage {
+	p := toPreview(f, base)
+	place := f.name
+	if place == "" {
+		place = f.near
+	}
+	title := fmt.Sprintf("Wind in %s", place)
+	fields := []SlackText{}
+	if s := toStats(f); s != nil {
+		if !f.noWind {
+			fields = append(fields,
+				SlackText{"mrkdwn", fmt.Sprintf("*Max wind*\n%.1f %s", s.Speed.Max, f.unit.label)},
+				SlackText{"mrkdwn", fmt.Sprintf("*Max gust*\n%.1f %s", s.Gust.Max, f.unit.label)},
+				SlackText{"mrkdwn", fmt.Sprintf("*Windiest hour*\n%s", slackHour(s.WindiestHour))},
+			)
+		}
+		if s.CheapestHour != nil {
+			fields = append(fields, SlackText{"mrkdwn", fmt.Sprintf("*Cheapest hour*\n%s, %.2f %s", slackHour(*s.CheapestHour), s.Price.Min, f.currency.priceUnit())})
+		}
+	}
+	blocks := []SlackBlock{
+		{Type: "header", Text: &SlackText{"plain_text", title}},
+		{Type: "section", Text: &SlackText{"mrkdwn", summary(f)}},
+	}
+	if len(fields) > 0 {
+		blocks = append(blocks, SlackBlock{Type: "section", Fields: fields})
+	}
+	blocks = append(blocks,
+		SlackBlock{Type: "image", ImageURL: p.Image + "&hours=24", AltText: "Wind and price chart of the next 24 hours"},
+		SlackBlock{Type: "context", Elements: []SlackText{{"mrkdwn", fmt.Sprintf("<%s|Full forecast>", p.URL)}}},
+	)
+	return SlackMessage{ResponseType: "in_channel", Text: fmt.Sprintf("%s: %s", title, summary(f)), Blocks: blocks}
+}
+
+// slackHour formats an RFC3339 hour of the stats like Mon 15:00.
+func slackHour(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return t.Format("Mon 15:04")
+}
+
+func writeSlack(rw fsthttp.ResponseWriter, m SlackMessage) {
+	b, _ := json.Marshal(m)
+	rw.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(rw, "%s\n", b)
+}
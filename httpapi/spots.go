@@ -0,0 +1,280 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+	"github.com/fastly/compute-sdk-go/objectstore"
+)
+
+// spot is a named kitesurf or windsurf spot. The rideable wind directions
+// are the window from From clockwise to To in degrees, e.g. 200-340 for a
+// beach facing west.
+type spot struct {
+	id     string
+	name   string
+	lat    float64
+	long   float64
+	from   float64
+	to     float64
+	region string
+}
+
+// builtinSpots are always available, spots in the KV Store with the same
+// id take precedence.
+var builtinSpots = map[string]spot{
+	"lomma":   {id: "lomma", name: "Lomma", lat: 55.67, long: 13.06, from: 200, to: 340, region: "SE4"},
+	"skanor":  {id: "skanor", name: "Skanör", lat: 55.41, long: 12.83, from: 180, to: 300, region: "SE4"},
+	"habo":    {id: "habo", name: "Habo", lat: 57.91, long: 14.07, from: 20, to: 160, region: "SE3"},
+	"varberg": {id: "varberg", name: "Varberg", lat: 57.09, long: 12.25, from: 180, to: 330, region: "SE3"},
+}
+
+// spotsKey is the KV Store key of the registered spots, a JSON object of
+// spots keyed by id. The store has no listing so all spots are kept in one
+// value.
+const spotsKey = "spots"
+
+// errUnknownSpot is returned when there is no spot with the given id.
+var errUnknownSpot = errors.New("unknown spot")
+
+// errNoStore is returned when the KV Store can't be opened.
+var errNoStore = errors.New("KV Store unavailable")
+
+// validSpotID matches the ids of spots, used in URLs.
+var validSpotID = regexp.MustCompile(`^[a-z0-9-]{1,40}$`)
+
+// Spot is the JSON representation of a spot.
+type Spot struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	From      float64 `json:"from"`
+	To        float64 `json:"to"`
+	Region    string  `json:"region"`
+}
+
+func toSpot(s spot) Spot {
+	return Spot{ID: s.id, Name: s.name, Latitude: s.lat, Longitude: s.long, From: s.from, To: s.to, Region: s.region}
+}
+
+func fromSpot(s Spot) spot {
+	return spot{id: s.ID, name: s.Name, lat: s.Latitude, long: s.Longitude, from: s.From, to: s.To, region: s.Region}
+}
+
+// parseSpot reads the spot parameter, nil if none is given.
+func parseSpot(ctx context.Context, req *fsthttp.Request) (*spot, error) {
+	id := req.URL.Query().Get("spot")
+	if id == "" {
+		return nil, nil
+	}
+	return findSpot(ctx, id)
+}
+
+// findSpot returns the spot with the given id.
+func findSpot(ctx context.Context, id string) (*spot, error) {
+	all, err := loadSpots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := all[strings.ToLower(id)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errUnknownSpot, id)
+	}
+	return &s, nil
+}
+
+// loadSpots returns the built in spots together with the registered ones.
+// If the KV Store is unavailable only the built in spots are returned.
+func loadSpots(ctx context.Context) (map[string]spot, error) {
+	all := map[string]spot{}
+	for id, s := range builtinSpots {
+		all[id] = s
+	}
+	stored, err := loadStoredSpots()
+	if err != nil {
+		if errors.Is(err, errNoStore) {
+			logln(ctx, "spots:", err)
+			return all, nil
+		}
+		return nil, err
+	}
+	for id, s := range stored {
+		all[id] = fromSpot(s)
+	}
+	return all, nil
+}
+
+func loadStoredSpots() (map[string]Spot, error) {
+	store, err := openKV()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoStore, err)
+	}
+	stored := map[string]Spot{}
+	entry, err := store.Lookup(spotsKey)
+	if err == objectstore.ErrKeyNotFound {
+		return stored, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(entry).Decode(&stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// saveStoredSpots replaces the registered spots. Concurrent updates may
+// overwrite each other, the last write wins.
+func saveStoredSpots(stored map[string]Spot) error {
+	store, err := openKV()
+	if err != nil {
+		return err
+	}
+	b, _ := json.Marshal(stored)
+	return store.Insert(spotsKey, bytes.NewReader(b))
+}
+
+// listSpots handles GET /spots and lists all spots.
+func listSpots(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	all, err := loadSpots(ctx)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	list := []Spot{}
+	for _, s := range all {
+		list = append(list, toSpot(s))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	writeSpotJSON(rw, fsthttp.StatusOK, list)
+}
+
+// getSpot handles GET /spots/:id.
+func getSpot(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+	s, err := findSpot(ctx, params["id"])
+	if errors.Is(err, errUnknownSpot) {
+		writeError(rw, req, fsthttp.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	writeSpotJSON(rw, fsthttp.StatusOK, toSpot(*s))
+}
+
+// putSpot handles PUT /spots/:id with a JSON spot and registers or updates
+// it. It requires the spots-token secret of the windy secret store as a
+// bearer token.
+func putSpot(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+	id := params["id"]
+	if !checkSpotRequest(rw, req, id) {
+		return
+	}
+	s, err := parseSpotBody(id, req.Body)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	stored, err := loadStoredSpots()
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	status := fsthttp.StatusOK
+	if _, ok := stored[id]; !ok {
+		status = fsthttp.StatusCreated
+	}
+	stored[id] = s
+	if err := saveStoredSpots(stored); err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	writeSpotJSON(rw, status, s)
+}
+
+// deleteSpot handles DELETE /spots/:id and removes a registered spot, it
+// requires the token like putSpot.
+func deleteSpot(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+	id := params["id"]
+	if !checkSpotRequest(rw, req, id) {
+		return
+	}
+	stored, err := loadStoredSpots()
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	if _, ok := stored[id]; !ok {
+		if _, ok := builtinSpots[id]; ok {
+			writeError(rw, req, fsthttp.StatusForbidden, fmt.Errorf("spot %q is built in and can't be deleted", id))
+			return
+		}
+		writeError(rw, req, fsthttp.StatusNotFound, fmt.Errorf("%w %q", errUnknownSpot, id))
+		return
+	}
+	delete(stored, id)
+	if err := saveStoredSpots(stored); err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	rw.WriteHeader(fsthttp.StatusNoContent)
+}
+
+// checkSpotRequest checks the id and the token of a request changing a
+// spot, on failure the error is written and false returned.
+func checkSpotRequest(rw fsthttp.ResponseWriter, req *fsthttp.Request, id string) bool {
+	if !validSpotID.MatchString(id) {
+		writeError(rw, req, fsthttp.StatusBadRequest, fmt.Errorf("invalid spot id %q, use lower case letters, digits and dashes", id))
+		return false
+	}
+	if !hasToken(req, "spots-token") {
+		rw.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(rw, req, fsthttp.StatusUnauthorized, errors.New("invalid token"))
+		return false
+	}
+	return true
+}
+
+func writeSpotJSON(rw fsthttp.ResponseWriter, status int, v interface{}) {
+	b, _ := json.MarshalIndent(v, "", "  ")
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	fmt.Fprintf(rw, "%s\n", b)
+}
+
+// parseSpotBody parses and validates a spot to register, the name defaults
+// to the id and the region to the region of the latitude.
+func parseSpotBody(id string, body io.Reader) (Spot, error) {
+	var s Spot
+	if err := json.NewDecoder(io.LimitReader(body, 8*1024)).Decode(&s); err != nil {
+		return Spot{}, fmt.Errorf("invalid spot: %w", err)
+	}
+	s.ID = id
+	if s.Name == "" {
+		s.Name = id
+	}
+	if !(s.Latitude >= -90 && s.Latitude <= 90) || !(s.Longitude >= -180 && s.Longitude <= 180) {
+		return Spot{}, fmt.Errorf("invalid coordinates for spot %q", id)
+	}
+	if !(s.From >= 0 && s.From < 360) || !(s.To >= 0 && s.To < 360) {
+		return Spot{}, fmt.Errorf("invalid directions for spot %q, from and to must be between 0 and 360", id)
+	}
+	if s.Region == "" {
+		s.Region = regionFor(s.Latitude)
+	}
+	s.Region = strings.ToUpper(s.Region)
+	if !validRegion(s.Region) {
+		return Spot{}, fmt.Errorf("unknown region %q", s.Region)
+	}
+	return s, nil
+}
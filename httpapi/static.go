@@ -0,0 +1,58 @@
+package httpapi
+
+import (
+	"context"
+	"path"
+
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// staticTypes are the content types of the static files by extension, only
+// these are served.
+var staticTypes = map[string]string{
+	".js":          "text/javascript; charset=utf-8",
+	".png":         "image/png",
+	".ico":         "image/x-icon",
+	".txt":         "text/plain; charset=utf-8",
+	".webmanifest": "application/manifest+json",
+}
+
+// rootFiles are the static files served at the root, where browsers and
+// crawlers look for them.
+var rootFiles = []string{
+	"favicon.ico",
+	"apple-touch-icon.png",
+	"robots.txt",
+	"manifest.webmanifest",
+}
+
+// handleStatic handles /static/:name and serves the embedded files. They
+// are versioned by a query parameter so they are cached for a year.
+func handleStatic(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, params map[string]string) {
+	serveStatic(rw, req, params["name"], "public, max-age=31536000, immutable")
+}
+
+// handleRootFile serves one of the rootFiles, they have fixed URLs so they
+// are cached for a day.
+func handleRootFile(name string) handlerFunc {
+	return func(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request, _ map[string]string) {
+		serveStatic(rw, req, name, "public, max-age=86400")
+	}
+}
+
+func serveStatic(rw fsthttp.ResponseWriter, req *fsthttp.Request, name, cacheControl string) {
+	contentType, ok := staticTypes[path.Ext(name)]
+	if !ok {
+		notFound(rw, req)
+		return
+	}
+	b, err := render.Static(name)
+	if err != nil {
+		notFound(rw, req)
+		return
+	}
+	rw.Header().Set("Content-Type", contentType)
+	rw.Header().Set("Cache-Control", cacheControl)
+	rw.Write(b)
+}
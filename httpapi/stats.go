@@ -0,0 +1,101 @@
+package httpapi
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Stats is the statistical summary of a forecast.
+type Stats struct {
+	Speed        Summary       `json:"speed"`
+	Gust         Summary       `json:"gust"`
+	Price        *PriceSummary `json:"price"`
+	WindiestHour string        `json:"windiest_hour"`
+	CheapestHour *string       `json:"cheapest_hour"`
+}
+
+// Summary summarizes a series of values.
+type Summary struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+}
+
+// PriceSummary summarizes the prices with percentiles.
+type PriceSummary struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+	P10  float64 `json:"p10"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+}
+
+func toStats(f *forecast) *Stats {
+	if len(f.entries) == 0 {
+		return nil
+	}
+	speeds := mapSlice(f.entries, func(e *entry) float64 { return e.speed })
+	gusts := mapSlice(f.entries, func(e *entry) float64 { return e.gust })
+	prices := []float64{}
+	var windiest, cheapest *entry
+	for _, e := range f.entries {
+		if windiest == nil || e.speed > windiest.speed {
+			windiest = e
+		}
+		if e.hasPrice {
+			prices = append(prices, e.price)
+			if cheapest == nil || e.price < cheapest.price {
+				cheapest = e
+			}
+		}
+	}
+	s := &Stats{
+		Speed:        summarizeValues(speeds, 2),
+		Gust:         summarizeValues(gusts, 2),
+		WindiestHour: windiest.hour.In(f.timezone).Format(time.RFC3339),
+	}
+	if cheapest != nil {
+		sum := summarizeValues(prices, 4)
+		s.Price = &PriceSummary{
+			Min:  sum.Min,
+			Max:  sum.Max,
+			Mean: sum.Mean,
+			P10:  round(percentile(prices, 10), 4),
+			P50:  sum.Median,
+			P90:  round(percentile(prices, 90), 4),
+		}
+		h := cheapest.hour.In(f.timezone).Format(time.RFC3339)
+		s.CheapestHour = &h
+	}
+	return s
+}
+
+// summarizeValues summarizes the values rounded to the given number of
+// decimals.
+func summarizeValues(values []float64, decimals int) Summary {
+	s := Summary{Min: math.Inf(1), Max: math.Inf(-1)}
+	sum := 0.0
+	for _, v := range values {
+		s.Min = math.Min(s.Min, v)
+		s.Max = math.Max(s.Max, v)
+		sum += v
+	}
+	s.Min = round(s.Min, decimals)
+	s.Max = round(s.Max, decimals)
+	s.Mean = round(sum/float64(len(values)), decimals)
+	s.Median = round(percentile(values, 50), decimals)
+	return s
+}
+
+// percentile returns the p:th percentile of the values, interpolating
+// between the closest ranks.
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lo, hi := int(math.Floor(rank)), int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
@@ -0,0 +1,175 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// PublishResponse is the result of POST /wind/stream/publish.
+type PublishResponse struct {
+	Published []string          `json:"published"`
+	Errors    map[string]string `json:"errors,omitempty"`
+}
+
+// streamChannel is the Fanout channel of the forecasts of a spot in a
+// unit, e.g. wind-lomma-kn.
+func streamChannel(sp *spot, u unit) string {
+	return "wind-" + sp.id + "-" + u.name
+}
+
+// sseEvent returns the forecast as a server-sent event, the JSON of
+// /wind.json on a single data line.
+func sseEvent(f *forecast) string {
+	b, _ := json.Marshal(toResponse(f))
+	return fmt.Sprintf("event: forecast\ndata: %s\n\n", b)
+}
+
+// handleStream handles /wind/stream?spot=lomma, a server-sent event stream
+// of the forecast of a spot. Through Fanout, which adds the Grip-Sig
+// header, the connection is held open on the channel of the spot and unit
+// and gets a new forecast event on each publish. Without Fanout the
+// current forecast is sent once and the client told to reconnect at the
+// next hour.
+func handleStream(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	sp, err := parseSpot(ctx, req)
+	if errors.Is(err, errUnknownSpot) {
+		writeError(rw, req, fsthttp.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	if sp == nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, errors.New("missing spot, streams are available for spots"))
+		return
+	}
+	u, err := parseUnit(req)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, err)
+		return
+	}
+	f, err := fetchStream(ctx, sp, u)
+	if err != nil {
+		writeError(rw, req, upstreamStatus(err), err)
+		return
+	}
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-store")
+	if req.Header.Get("Grip-Sig") != "" {
+		rw.Header().Set("Grip-Hold", "stream")
+		rw.Header().Set("Grip-Channel", streamChannel(sp, u))
+		rw.Header().Set("Grip-Keep-Alive", `:\n\n; format=cstring; timeout=30`)
+		fmt.Fprint(rw, sseEvent(f))
+		return
+	}
+	now := time.Now()
+	next := now.Truncate(time.Hour).Add(time.Hour + time.Minute)
+	fmt.Fprintf(rw, "retry: %d\n\n%s", next.Sub(now).Milliseconds(), sseEvent(f))
+}
+
+func fetchStream(ctx context.Context, sp *spot, u unit) (*forecast, error) {
+	l, err := resolveLocation(ctx, spotLocation(sp, "", ""))
+	if err != nil {
+		return nil, err
+	}
+	forecasts, errs := fetchAll(ctx, []batchLocation{l}, configInt(ctx, "default_days", 3), 0, u)
+	return forecasts[0], errs[0]
+}
+
+// handlePublish handles POST /wind/stream/publish, called by a cron job
+// when the cached forecasts refresh each hour. It publishes the forecast
+// of each spot in each unit to its Fanout channel through the Fastly API.
+// It requires the admin-token secret as bearer token, the API token is
+// the fastly-api-token secret.
+func handlePublish(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	if !checkAdmin(rw, req) {
+		return
+	}
+	apiToken, err := secret("fastly-api-token")
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	all, err := loadSpots(ctx)
+	if err != nil {
+		writeError(rw, req, fsthttp.StatusInternalServerError, err)
+		return
+	}
+	spots := make([]spot, 0, len(all))
+	for _, sp := range all {
+		spots = append(spots, sp)
+	}
+	sort.Slice(spots, func(i, j int) bool { return spots[i].id < spots[j].id })
+	names := make([]string, 0, len(units))
+	for name := range units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	r := PublishResponse{Published: []string{}, Errors: map[string]string{}}
+	type item struct {
+		Channel string                       `json:"channel"`
+		Formats map[string]map[string]string `json:"formats"`
+	}
+	items := []item{}
+	for _, name := range names {
+		for start := 0; start < len(spots); start += maxBatch {
+			end := start + maxBatch
+			if end > len(spots) {
+				end = len(spots)
+			}
+			batch := []batchLocation{}
+			for i := start; i < end; i++ {
+				l, err := resolveLocation(ctx, spotLocation(&spots[i], "", ""))
+				if err != nil {
+					r.Errors[streamChannel(&spots[i], units[name])] = err.Error()
+					continue
+				}
+				batch = append(batch, l)
+			}
+			forecasts, errs := fetchAll(ctx, batch, configInt(ctx, "default_days", 3), 0, units[name])
+			for i, l := range batch {
+				sp := all[l.Spot]
+				channel := streamChannel(&sp, units[name])
+				if errs[i] != nil {
+					r.Errors[channel] = errs[i].Error()
+					continue
+				}
+				items = append(items, item{Channel: channel, Formats: map[string]map[string]string{
+					"http-stream": {"content": sseEvent(forecasts[i])},
+				}})
+				r.Published = append(r.Published, channel)
+			}
+		}
+	}
+	if len(items) > 0 {
+		b, _ := json.Marshal(map[string]interface{}{"items": items})
+		u := fmt.Sprintf("https://api.fastly.com/service/%s/publish/", os.Getenv("FASTLY_SERVICE_ID"))
+		preq, _ := newUpstreamRequest(ctx, "POST", u)
+		preq.Body = io.NopCloser(bytes.NewReader(b))
+		preq.Header.Set("Fastly-Key", string(apiToken))
+		preq.Header.Set("Content-Type", "application/json")
+		preq.CacheOptions.Pass = true
+		resp, err := sendUpstream(ctx, preq, config("backend_fastly-api", "fastly-api"))
+		if err != nil {
+			writeError(rw, req, fsthttp.StatusBadGateway, err)
+			return
+		}
+		if resp.StatusCode >= 300 {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			writeError(rw, req, fsthttp.StatusBadGateway, fmt.Errorf("publish failed with status %d: %s", resp.StatusCode, body))
+			return
+		}
+		logln(ctx, "published", len(items), "forecasts")
+	}
+	writeSpotJSON(rw, fsthttp.StatusOK, r)
+}
@@ -0,0 +1,86 @@
+package httpapi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// toRenderChart returns the wind, gusts and prices of the forecast as a
+// chart for the SVG and PNG renderers, with the past and the good hours
+// shaded.
+func toRenderChart(f *forecast, title string) render.Chart {
+	entries := f.entries
+	c := render.Chart{
+		Title:     title,
+		Labels:    mapSlice(entries, axisLabel(f.timezone, len(entries) <= 72)),
+		LeftUnit:  f.unit.label,
+		RightUnit: f.currency.priceUnit(),
+		Bands: []render.Band{
+			{Label: "Past", Color: "#eeeeee", Set: mapSlice(entries, func(e *entry) bool { return e.past })},
+			{Label: "Good time to run appliances", Color: "#e3f2e3", Set: mapSlice(entries, func(e *entry) bool {
+				return e.hasPrice && e.score >= goodScore
+			})},
+		},
+	}
+	if !f.noWind {
+		c.Series = append(c.Series,
+			render.Series{Label: "Average", Color: "#2e7d32", Values: mapSlice(entries, func(e *entry) float64 { return e.speed })},
+			render.Series{Label: "Gust", Color: "#c62828", Values: mapSlice(entries, func(e *entry) float64 { return e.gust })},
+		)
+	}
+	prices := mapSlice(entries, func(e *entry) float64 {
+		if !e.hasPrice {
+			return math.NaN()
+		}
+		return e.price
+	})
+	if !allNaN(prices) {
+		c.Series = append(c.Series, render.Series{Label: "Price", Color: "#1565c0", Values: prices, Right: true})
+	}
+	return c
+}
+
+// axisLabel returns a function labeling midnight with the day and, when
+// detailed, every sixth hour with the hour. Other hours are unlabeled.
+func axisLabel(tz *time.Location, detailed bool) func(*entry) string {
+	return func(e *entry) string {
+		t := e.hour.In(tz)
+		switch {
+		case t.Hour() == 0:
+			return t.Format("Mon 2")
+		case detailed && t.Hour()%6 == 0:
+			return t.Format("15")
+		}
+		return ""
+	}
+}
+
+// parseSize reads the size of a chart image in pixels, e.g. size=800x300.
+func parseSize(req *fsthttp.Request, defWidth, defHeight int) (int, int, error) {
+	s := req.URL.Query().Get("size")
+	if s == "" {
+		return defWidth, defHeight, nil
+	}
+	w, h, ok := strings.Cut(s, "x")
+	width, werr := strconv.Atoi(w)
+	height, herr := strconv.Atoi(h)
+	if !ok || werr != nil || herr != nil || width < 200 || width > 2000 || height < 100 || height > 1000 {
+		return 0, 0, fmt.Errorf("invalid size %q, use WIDTHxHEIGHT from 200x100 to 2000x1000", s)
+	}
+	return width, height, nil
+}
+
+func allNaN(values []float64) bool {
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// serviceWorker is the fetch handling of /sw.js, after the constants
+// generated by serviceWorkerJS. Static files are served from the cache
+// first, pages and data from the network first, falling back to the last
+// response in the cache when offline.
+const serviceWorker = `
+self.addEventListener("install", (event) => {
+	event.waitUntil(caches.open(STATIC_CACHE).then((cache) => cache.addAll(PRECACHE)).then(() => self.skipWaiting()));
+});
+
+self.addEventListener("activate", (event) => {
+	event.waitUntil(caches.keys().then((keys) => Promise.all(keys.filter((key) => key !== STATIC_CACHE && key !== DATA_CACHE).map((key) => caches.delete(key)))).then(() => self.clients.claim()));
+});
+
+self.addEventListener("fetch", (event) => {
+	const url = new URL(event.request.url);
+	if (event.request.method !== "GET" || url.origin !== self.location.origin || NO_CACHE.some((p) => url.pathname.startsWith(p))) {
+		return;
+	}
+	if (url.pathname.startsWith("/static/") || ROOT_FILES.includes(url.pathname)) {
+		event.respondWith(caches.open(STATIC_CACHE).then((cache) => cache.match(event.request).then((cached) => cached || fetch(event.request).then((res) => {
+			if (res.ok) {
+				cache.put(event.request, res.clone());
+			}
+			return res;
+		}))));
+		return;
+	}
+	event.respondWith(caches.open(DATA_CACHE).then((cache) => fetch(event.request).then((res) => {
+		if (res.ok) {
+			cache.put(event.request, res.clone());
+		}
+		return res;
+	}).catch((err) => cache.match(event.request).then((cached) => {
+		if (cached) {
+			return cached;
+		}
+		throw err;
+	}))));
+});
+`
+
+// noCache are the path prefixes never cached by the service worker, they
+// are personal, streamed or must be current.
+var noCache = []string{"/admin/", "/alerts", "/graphql", "/prefs", "/wind/stream", "/healthz", "/readyz", "/version"}
+
+// serviceWorkerJS generates /sw.js. The name of the static cache changes
+// with the build and the static files, so a new version replaces the old
+// cache.
+func serviceWorkerJS() string {
+	names := render.StaticNames()
+	h := sha256.New()
+	fmt.Fprint(h, gitSHA)
+	var precache, rootPaths []string
+	for _, name := range names {
+		b, _ := render.Static(name)
+		h.Write(b)
+	}
+	for _, name := range rootFiles {
+		rootPaths = append(rootPaths, "/"+name)
+	}
+	precache = append(precache, rootPaths...)
+	precache = append(precache, render.ChartJS())
+	for _, name := range names {
+		if strings.HasPrefix(name, "icon-") {
+			precache = append(precache, "/static/"+name)
+		}
+	}
+	version := hex.EncodeToString(h.Sum(nil))[:12]
+	var b strings.Builder
+	constant := func(name string, v any) {
+		j, _ := json.Marshal(v)
+		fmt.Fprintf(&b, "const %s = %s;\n", name, j)
+	}
+	constant("STATIC_CACHE", "windy-static-"+version)
+	constant("DATA_CACHE", "windy-data")
+	constant("PRECACHE", precache)
+	constant("ROOT_FILES", rootPaths)
+	constant("NO_CACHE", noCache)
+	b.WriteString(serviceWorker)
+	return b.String()
+}
+
+// handleServiceWorker handles /sw.js, the service worker of the pages. It
+// is served from the root to control all of them and revalidated on every
+// update check.
+func handleServiceWorker(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	rw.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	rw.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(rw, serviceWorkerJS())
+}
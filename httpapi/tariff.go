@@ -0,0 +1,135 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// Tariff is what a household pays on top of the spot price, the fees and
+// the energy tax per kWh in the currency of the zone and the VAT in
+// percent.
+type Tariff struct {
+	Currency       string  `json:"currency"`
+	VAT            float64 `json:"vat"`
+	EnergyTax      float64 `json:"energy_tax"`
+	CertificateFee float64 `json:"certificate_fee"`
+	GridFee        float64 `json:"grid_fee"`
+	// rate is the amount of the currency per SEK, the prices are in SEK.
+	rate float64
+}
+
+// zoneTariff is the currency, the VAT and the energy tax of the households
+// of a zone.
+type zoneTariff struct {
+	currency  string
+	vat       float64
+	energyTax float64
+}
+
+// zoneTariffs are the default tariffs by country, or by zone where it
+// differs from the country. Northern Norway pays no VAT on electricity.
+var zoneTariffs = map[string]zoneTariff{
+	"AT":  {"EUR", 20, 0.015},
+	"BE":  {"EUR", 6, 0.0503},
+	"DE":  {"EUR", 19, 0.0205},
+	"DK":  {"DKK", 25, 0.761},
+	"EE":  {"EUR", 22, 0.001},
+	"FI":  {"EUR", 25.5, 0.0224},
+	"FR":  {"EUR", 20, 0.021},
+	"LT":  {"EUR", 21, 0.001},
+	"LV":  {"EUR", 21, 0},
+	"NL":  {"EUR", 21, 0.1088},
+	"NO":  {"NOK", 25, 0.0951},
+	"NO4": {"NOK", 0, 0.0951},
+	"PL":  {"PLN", 23, 0.005},
+	"SE":  {"SEK", 25, 0.439},
+}
+
+// tariffKey returns the key of the zone in zoneTariffs, e.g. NO4 or DK for
+// DK1 and DE for DE-LU.
+func tariffKey(zone string) string {
+	if _, ok := zoneTariffs[zone]; ok {
+		return zone
+	}
+	country, _, _ := strings.Cut(zone, "-")
+	return strings.TrimRight(country, "0123456789")
+}
+
+// parseTariff reads the tariff of the zone when the price parameter, or
+// the price setting, is consumer. Each part is read from the query,
+// falling back to the setting of the name and the country, e.g.
+// energy_tax_dk, and the default of the zone. Spot prices have no tariff.
+func parseTariff(req *fsthttp.Request, zone string) (*Tariff, error) {
+	q := req.URL.Query()
+	mode := q.Get("price")
+	if mode == "" {
+		mode = config("price", "spot")
+	}
+	switch mode {
+	case "spot":
+		return nil, nil
+	case "consumer":
+	default:
+		return nil, fmt.Errorf("unknown price %q, use spot or consumer", mode)
+	}
+	key := tariffKey(zone)
+	zt, ok := zoneTariffs[key]
+	if !ok {
+		return nil, fmt.Errorf("no consumer prices for %s", zone)
+	}
+	t := &Tariff{Currency: zt.currency, rate: 1}
+	for _, p := range []struct {
+		name string
+		def  float64
+		v    *float64
+		max  float64
+	}{
+		{"vat", zt.vat, &t.VAT, 100},
+		{"energy_tax", zt.energyTax, &t.EnergyTax, 10},
+		{"certificate_fee", 0, &t.CertificateFee, 10},
+		{"grid_fee", 0, &t.GridFee, 10},
+	} {
+		s := q.Get(p.name)
+		if s == "" {
+			s = config(p.name+"_"+strings.ToLower(key), strconv.FormatFloat(p.def, 'f', -1, 64))
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || !(v >= 0 && v <= p.max) {
+			return nil, fmt.Errorf("invalid %s %q, must be between 0 and %g", p.name, s, p.max)
+		}
+		*p.v = v
+	}
+	return t, nil
+}
+
+// apply returns the consumer price of the spot price in SEK, the spot
+// price itself without a tariff. The fees are added in the currency of the
+// zone and the price converted back to SEK.
+func (t *Tariff) apply(price float64) float64 {
+	if t == nil {
+		return price
+	}
+	return (price*t.rate + t.EnergyTax + t.CertificateFee + t.GridFee) * (1 + t.VAT/100) / t.rate
+}
+
+// applyTariff turns the spot prices of the forecast into consumer prices,
+// it fails when the exchange rate of the currency of the zone is
+// unavailable.
+func applyTariff(ctx context.Context, f *forecast, t *Tariff) error {
+	c, err := fetchCurrency(ctx, t.Currency)
+	if err != nil {
+		return err
+	}
+	t.rate = c.rate
+	for _, e := range f.entries {
+		if e.hasPrice {
+			e.price = t.apply(e.price)
+		}
+	}
+	f.tariff = t
+	return nil
+}
@@ -0,0 +1,121 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// chargeHours is the length of the charging window suggested by the bot.
+const chargeHours = 3
+
+// telegramUpdate is the part of a Telegram update used by the bot.
+type telegramUpdate struct {
+	Message *struct {
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text     string `json:"text"`
+		Location *struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		} `json:"location"`
+	} `json:"message"`
+}
+
+// TelegramReply is a sendMessage call in the response to a webhook.
+type TelegramReply struct {
+	Method    string `json:"method"`
+	ChatID    int64  `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode"`
+}
+
+// handleTelegram handles POST /telegram, the webhook of the Telegram bot.
+// A message with a location, or a spot or place name optionally followed
+// by a unit, is answered with the next 24 hours and the cheapest hours to
+// charge. The webhook must be set with the telegram-webhook-token from the
+// Secret Store as secret_token.
+func handleTelegram(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	want, err := secret("telegram-webhook-token")
+	if err != nil {
+		logln(ctx, "telegram:", err)
+	}
+	got := req.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if err != nil || subtle.ConstantTimeCompare([]byte(got), want) != 1 {
+		writeError(rw, req, fsthttp.StatusUnauthorized, errors.New("invalid Telegram secret token"))
+		return
+	}
+	var update telegramUpdate
+	if err := json.NewDecoder(io.LimitReader(req.Body, 64*1024)).Decode(&update); err != nil {
+		writeError(rw, req, fsthttp.StatusBadRequest, fmt.Errorf("invalid Telegram update: %w", err))
+		return
+	}
+	m := update.Message
+	if m == nil {
+		// Other updates, e.g. edited messages, are acknowledged and ignored.
+		rw.WriteHeader(fsthttp.StatusOK)
+		return
+	}
+	reply := func(text string) {
+		b, _ := json.Marshal(TelegramReply{Method: "sendMessage", ChatID: m.Chat.ID, Text: text, ParseMode: "HTML"})
+		rw.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(rw, "%s\n", b)
+	}
+	var l batchLocation
+	u := units["ms"]
+	if m.Location != nil {
+		l, err = resolveLocation(ctx, batchLocation{Lat: m.Location.Latitude, Long: m.Location.Longitude})
+	} else {
+		text := m.Text
+		// Commands like /wind lomma are the same as lomma.
+		if strings.HasPrefix(text, "/") {
+			_, text, _ = strings.Cut(text, " ")
+		}
+		var name string
+		name, u = parseCommand(text)
+		if name == "" {
+			reply("Send a location, or the name of a spot or place optionally followed by a unit, e.g. <code>lomma kn</code>.")
+			return
+		}
+		l, err = findLocation(ctx, name)
+	}
+	if err != nil {
+		reply(html.EscapeString(fmt.Sprintf("Sorry, %s.", err)))
+		return
+	}
+	forecasts, errs := fetchAll(ctx, []batchLocation{l}, 3, 24, u)
+	if errs[0] != nil {
+		logln(ctx, "telegram:", errs[0])
+		reply("The forecast is unavailable, please try again later.")
+		return
+	}
+	reply(toTelegram(forecasts[0], "https://"+req.Host))
+}
+
+// toTelegram returns the forecast as a Telegram HTML message with the
+// summary, the cheapest hours to charge and a link to the forecast on
+// base, e.g. https://windy.edgecompute.app.
+func toTelegram(f *forecast, base string) string {
+	place := f.name
+	if place == "" {
+		place = f.near
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<b>Wind in %s</b>\n%s\n", html.EscapeString(place), html.EscapeString(summary(f)))
+	if w := cheapWindow(f, chargeHours); w != nil {
+		start := w[0].hour.In(f.timezone)
+		end := w[len(w)-1].hour.Add(time.Hour).In(f.timezone)
+		fmt.Fprintf(&b, "Charge %s %s-%s, %.2f %s on average.\n", start.Format("Mon"), start.Format("15:04"), end.Format("15:04"), average(w), f.currency.priceUnit())
+	}
+	fmt.Fprintf(&b, "<a href=\"%s\">Full forecast</a>", html.EscapeString(toPreview(f, base).URL))
+	return b.String()
+}
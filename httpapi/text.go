@@ -0,0 +1,166 @@
+package httpapi
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"compute-starter-kit-go/render"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// terminalClient reports whether the request is from curl or wget, which
+// get the plain text forecast by default.
+func terminalClient(req *fsthttp.Request) bool {
+	ua := strings.ToLower(req.Header.Get("User-Agent"))
+	return strings.HasPrefix(ua, "curl/") || strings.HasPrefix(ua, "wget/")
+}
+
+// Wind below calmWind m/s is green, below strongWind amber and above red.
+const (
+	calmWind   = 8
+	strongWind = 14
+)
+
+// priceShades are the ANSI 256 colors of the prices, from light blue for
+// the cheapest to dark blue for the most expensive.
+var priceShades = []int{153, 117, 81, 39, 33, 27, 21}
+
+// textColors are the colors of the wind and prices, nil without colors.
+type textColors struct {
+	wind  func(float64) int
+	price func(float64) int
+}
+
+// newTextColors returns the colors of the forecast, the wind thresholds
+// are converted to the unit and the prices shaded over their range.
+func newTextColors(f *forecast) textColors {
+	calm, strong := f.unit.convert(calmWind), f.unit.convert(strongWind)
+	prices := []float64{}
+	for _, e := range f.entries {
+		if e.hasPrice {
+			prices = append(prices, e.price)
+		}
+	}
+	lo, hi := render.MinMax(prices)
+	return textColors{
+		wind: func(v float64) int {
+			switch {
+			case v < calm:
+				return 34
+			case v < strong:
+				return 214
+			}
+			return 196
+		},
+		price: func(v float64) int {
+			if hi <= lo {
+				return priceShades[0]
+			}
+			return priceShades[int(math.Round((v-lo)/(hi-lo)*float64(len(priceShades)-1)))]
+		},
+	}
+}
+
+// colored colors the formatted value if there are colors.
+func colored(color func(float64) int, format string, v float64) string {
+	s := fmt.Sprintf(format, v)
+	if color == nil {
+		return s
+	}
+	return render.Color(s, color(v))
+}
+
+// toText returns the forecast as plain text for the terminal, the current
+// hour followed by sparklines of the wind, gusts and prices of each day.
+// With color the wind and prices have ANSI colors.
+func toText(f *forecast, color bool) string {
+	c := textColors{}
+	if color {
+		c = newTextColors(f)
+	}
+	var b strings.Builder
+	name := f.name
+	if name == "" {
+		name = f.near
+	}
+	if name == "" {
+		name = "browser location"
+	}
+	fmt.Fprintf(&b, "Wind at %s (%s, %s) in %s, prices in %s for %s\n", name, f.lat, f.long, f.unit.label, f.currency.priceUnit(), f.region)
+	for _, w := range f.warnings {
+		fmt.Fprintf(&b, "! %s\n", w)
+	}
+	e := currentEntry(f)
+	if e == nil {
+		return b.String()
+	}
+	b.WriteString("\n" + e.hour.In(f.timezone).Format("Mon 15:04") + "  ")
+	if !f.noWind {
+		fmt.Fprintf(&b, "%s %s gusting %s from %s %s", colored(c.wind, "%.1f", e.speed), f.unit.label, colored(c.wind, "%.1f", e.gust), render.Compass(e.direction), render.Arrow(e.direction))
+	}
+	if e.hasPrice {
+		if !f.noWind {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s %s", colored(c.price, "%.2f", e.price), f.currency.priceUnit())
+	}
+	b.WriteString("\n")
+	for _, day := range byDay(f) {
+		b.WriteString("\n" + day[0].hour.In(f.timezone).Format("Mon 2 Jan") + "\n")
+		b.WriteString("       " + hourAxis(f, day) + "\n")
+		speeds, gusts, prices := []float64{}, []float64{}, []float64{}
+		for _, e := range day {
+			speeds = append(speeds, e.speed)
+			gusts = append(gusts, e.gust)
+			p := math.NaN()
+			if e.hasPrice {
+				p = e.price
+			}
+			prices = append(prices, p)
+		}
+		if !f.noWind {
+			fmt.Fprintf(&b, "wind   %s  %s\n", render.ColorSparkline(speeds, c.wind), textRange(speeds, "%.1f"))
+			fmt.Fprintf(&b, "gust   %s  %s\n", render.ColorSparkline(gusts, c.wind), textRange(gusts, "%.1f"))
+		}
+		if lo, _ := render.MinMax(prices); !math.IsInf(lo, 1) {
+			fmt.Fprintf(&b, "price  %s  %s\n", render.ColorSparkline(prices, c.price), textRange(prices, "%.2f"))
+		}
+	}
+	return b.String()
+}
+
+// byDay groups the entries by the day in the timezone of the forecast.
+func byDay(f *forecast) [][]*entry {
+	days := [][]*entry{}
+	last := ""
+	for _, e := range f.entries {
+		d := e.hour.In(f.timezone).Format("2006-01-02")
+		if d != last {
+			days = append(days, nil)
+			last = d
+		}
+		days[len(days)-1] = append(days[len(days)-1], e)
+	}
+	return days
+}
+
+// hourAxis labels every sixth hour under the sparklines, where there is
+// room.
+func hourAxis(f *forecast, day []*entry) string {
+	axis := []rune(strings.Repeat(" ", len(day)+1))
+	next := 0
+	for i, e := range day {
+		h := e.hour.In(f.timezone).Hour()
+		if h%6 == 0 && i >= next {
+			copy(axis[i:], []rune(fmt.Sprintf("%02d", h)))
+			next = i + 3
+		}
+	}
+	return strings.TrimRight(string(axis), " ")
+}
+
+func textRange(values []float64, format string) string {
+	lo, hi := render.MinMax(values)
+	return fmt.Sprintf(format+"-"+format, lo, hi)
+}
@@ -0,0 +1,56 @@
+package httpapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// palette is the colors of the chart in a theme.
+type palette struct {
+	Text  string `json:"text"`
+	Grid  string `json:"grid"`
+	Past  string `json:"past"`
+	Speed string `json:"speed"`
+	Gust  string `json:"gust"`
+	Price string `json:"price"`
+	Now   string `json:"now"`
+}
+
+// palettes are the chart colors by theme, the page picks one when the
+// theme is auto.
+var palettes = map[string]palette{
+	"light": {
+		Text:  "#666",
+		Grid:  "rgba(0, 0, 0, 0.1)",
+		Past:  "rgba(255, 255, 255, 0.6)",
+		Speed: "green",
+		Gust:  "red",
+		Price: "blue",
+		Now:   "#333",
+	},
+	"dark": {
+		Text:  "#ddd",
+		Grid:  "rgba(255, 255, 255, 0.15)",
+		Past:  "rgba(18, 18, 18, 0.6)",
+		Speed: "#66bb6a",
+		Gust:  "#ef5350",
+		Price: "#64b5f6",
+		Now:   "#eee",
+	},
+}
+
+// parseTheme reads the theme parameter, light, dark or auto following the
+// prefers-color-scheme of the browser. The theme of the prefs cookie is the
+// default.
+func parseTheme(req *fsthttp.Request) (string, error) {
+	t := req.URL.Query().Get("theme")
+	if t == "" || t == "auto" {
+		return "auto", nil
+	}
+	if !contains(themes, t) {
+		return "", fmt.Errorf("unknown theme %q, use one of %s, auto", t, strings.Join(themes, ", "))
+	}
+	return t, nil
+}
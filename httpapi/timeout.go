@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// The default time budgets, they are configured by the deadline,
+// timeout_geo and timeout_<upstream> settings.
+var (
+	// deadline is the overall time budget of a request.
+	deadline = 20 * time.Second
+	// geoTimeout is the time budget of the geo lookup.
+	geoTimeout = 1 * time.Second
+	// backendTimeouts are the time budgets of the upstreams, including
+	// retries.
+	backendTimeouts = map[string]time.Duration{
+		"open-meteo": 10 * time.Second,
+		"elpris":     5 * time.Second,
+		"entsoe":     5 * time.Second,
+		"awattar-de": 5 * time.Second,
+		"awattar-at": 5 * time.Second,
+		"rates":      2 * time.Second,
+	}
+)
+
+// timeoutError is returned when a dependency doesn't respond in time.
+type timeoutError struct {
+	dependency string
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("%s did not respond in time", e.dependency)
+}
+
+// withTimeout runs f with the timeout, returning a timeoutError for the
+// dependency if it doesn't return in time. A panic in f is returned as an
+// error, it runs in its own goroutine.
+func withTimeout[T any](ctx context.Context, dependency string, timeout time.Duration, f func(context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				var zero T
+				done <- result{zero, panicError(ctx, v)}
+			}
+		}()
+		v, err := f(ctx)
+		done <- result{v, err}
+	}()
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, &timeoutError{dependency: dependency}
+	}
+}
+
+// upstreamStatus is the status of a failed upstream, 504 for timeouts.
+func upstreamStatus(err error) int {
+	var te *timeoutError
+	if errors.As(err, &te) {
+		return fsthttp.StatusGatewayTimeout
+	}
+	return fsthttp.StatusBadGateway
+}
@@ -0,0 +1,111 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// timing is the duration of a step of the request.
+type timing struct {
+	name    string
+	start   time.Time
+	dur     time.Duration
+	running bool
+}
+
+// timed starts timing the step and returns the function stopping it. Steps
+// with the same name, e.g. retries, are added up.
+func timed(ctx context.Context, name string) func() {
+	s := stateOf(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := &timing{name: name, start: time.Now(), running: true}
+	s.timings = append(s.timings, t)
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if t.running {
+			t.dur, t.running = time.Since(t.start), false
+		}
+	}
+}
+
+// stopTimers stops the steps still running, e.g. render when the header
+// is written.
+func (s *requestState) stopTimers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.timings {
+		if t.running {
+			t.dur, t.running = time.Since(t.start), false
+		}
+	}
+}
+
+// durations returns the total milliseconds of each step in order, the
+// running ones so far.
+func (s *requestState) durations() ([]string, map[string]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := []string{}
+	ms := map[string]float64{}
+	for _, t := range s.timings {
+		if _, ok := ms[t.name]; !ok {
+			names = append(names, t.name)
+		}
+		d := t.dur
+		if t.running {
+			d = time.Since(t.start)
+		}
+		ms[t.name] += round(float64(d.Microseconds())/1000, 1)
+	}
+	return names, ms
+}
+
+// serverTiming returns the Server-Timing header value of the steps.
+func (s *requestState) serverTiming() string {
+	names, ms := s.durations()
+	metrics := make([]string, len(names))
+	for i, n := range names {
+		metrics[i] = fmt.Sprintf("%s;dur=%.1f", n, ms[n])
+	}
+	return strings.Join(metrics, ", ")
+}
+
+// logTimings logs the durations of the steps as JSON.
+func (s *requestState) logTimings(req *fsthttp.Request, total time.Duration) {
+	_, ms := s.durations()
+	ms["total"] = round(float64(total.Microseconds())/1000, 1)
+	b, _ := json.Marshal(map[string]interface{}{"path": req.URL.Path, "timings_ms": ms})
+	logID(s.id, string(b))
+}
+
+// timingWriter sets the Server-Timing header when the header is written.
+type timingWriter struct {
+	fsthttp.ResponseWriter
+	state       *requestState
+	wroteHeader bool
+}
+
+func (w *timingWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.state.stopTimers()
+		if st := w.state.serverTiming(); st != "" {
+			w.Header().Set("Server-Timing", st)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timingWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(fsthttp.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,156 @@
+package httpapi
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// resolutions are the allowed bucket sizes in hours.
+var resolutions = map[string]int{"1h": 1, "2h": 2, "3h": 3, "6h": 6, "12h": 12}
+
+// parseTransform reads the resolution and smooth parameters, e.g.
+// resolution=3h&smooth=3.
+func parseTransform(req *fsthttp.Request) (int, int, error) {
+	resolution := 1
+	if r := req.URL.Query().Get("resolution"); r != "" {
+		n, ok := resolutions[strings.ToLower(r)]
+		if !ok {
+			return 0, 0, fmt.Errorf("invalid resolution %q, use one of 1h, 2h, 3h, 6h, 12h", r)
+		}
+		resolution = n
+	}
+	smooth, err := intParam(req, "smooth", 1, 1, 24)
+	if err != nil {
+		return 0, 0, err
+	}
+	return resolution, smooth, nil
+}
+
+// smooth replaces the speeds and gusts with their centered moving average
+// over n hours.
+func smooth(entries []*entry, n int) {
+	if n <= 1 {
+		return
+	}
+	speeds := mapSlice(entries, func(e *entry) float64 { return e.speed })
+	gusts := mapSlice(entries, func(e *entry) float64 { return e.gust })
+	for i, e := range entries {
+		lo, hi := i-n/2, i+(n-1)/2
+		if lo < 0 {
+			lo = 0
+		}
+		if hi >= len(entries) {
+			hi = len(entries) - 1
+		}
+		var speed, gust float64
+		for j := lo; j <= hi; j++ {
+			speed += speeds[j]
+			gust += gusts[j]
+		}
+		e.speed = speed / float64(hi-lo+1)
+		e.gust = gust / float64(hi-lo+1)
+	}
+}
+
+// resample aggregates the entries into buckets of the given number of
+// hours, aligned to midnight in the timezone. Speeds, temperatures and
+// prices are averaged, gusts are the max and precipitation is summed.
+func resample(entries []*entry, hours int, tz *time.Location) []*entry {
+	if hours <= 1 {
+		return entries
+	}
+	buckets := []*entry{}
+	var n, priced int
+	var sin, cos float64
+	for _, e := range entries {
+		local := e.hour.In(tz)
+		start := time.Date(local.Year(), local.Month(), local.Day(), local.Hour()/hours*hours, 0, 0, 0, tz).UTC()
+		if len(buckets) == 0 || !buckets[len(buckets)-1].hour.Equal(start) {
+			buckets = append(buckets, &entry{hour: start, past: true, marine: e.marine, ensemble: e.ensemble})
+			n, priced, sin, cos = 0, 0, 0, 0
+		}
+		b := buckets[len(buckets)-1]
+		b.speed = (b.speed*float64(n) + e.speed) / float64(n+1)
+		b.gust = math.Max(b.gust, e.gust)
+		b.past = b.past && e.past
+		b.temperature = (b.temperature*float64(n) + e.temperature) / float64(n+1)
+		b.apparentTemperature = (b.apparentTemperature*float64(n) + e.apparentTemperature) / float64(n+1)
+		b.session = (b.session*float64(n) + e.session) / float64(n+1)
+		b.precipitation += e.precipitation
+		b.precipitationProbability = math.Max(b.precipitationProbability, e.precipitationProbability)
+		// Average the directions as vectors, 350° and 10° average to 0°.
+		sin += math.Sin(e.direction * math.Pi / 180)
+		cos += math.Cos(e.direction * math.Pi / 180)
+		b.direction = math.Mod(math.Atan2(sin, cos)*180/math.Pi+360, 360)
+		n++
+		if e.hasPrice {
+			b.price = (b.price*float64(priced) + e.price) / float64(priced+1)
+			b.score = (b.score*float64(priced) + e.score) / float64(priced+1)
+			b.hasPrice = true
+			priced++
+		}
+	}
+	return buckets
+}
+
+// parseRange reads the from and to parameters as RFC3339 times, dates or
+// the keywords today and tomorrow. Dates and keywords are whole days in the
+// timezone, from is inclusive and to exclusive. Zero times mean no limit.
+func parseRange(req *fsthttp.Request, tz *time.Location) (time.Time, time.Time, error) {
+	q := req.URL.Query()
+	from, err := parseBound(q.Get("from"), tz, false)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from %q, use RFC3339, YYYY-MM-DD, today or tomorrow", q.Get("from"))
+	}
+	to, err := parseBound(q.Get("to"), tz, true)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to %q, use RFC3339, YYYY-MM-DD, today or tomorrow", q.Get("to"))
+	}
+	return from, to, nil
+}
+
+// parseBound parses a range bound, end bounds of whole days are the start of
+// the next day.
+func parseBound(s string, tz *time.Location, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	now := time.Now().In(tz)
+	var day time.Time
+	switch strings.ToLower(s) {
+	case "today":
+		day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, tz)
+	case "tomorrow":
+		day = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, tz)
+	default:
+		var err error
+		day, err = time.ParseInLocation("2006-01-02", s, tz)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	if end {
+		return day.AddDate(0, 0, 1), nil
+	}
+	return day, nil
+}
+
+// slice returns the entries in the range [from, to), zero times mean no
+// limit.
+func slice(entries []*entry, from, to time.Time) []*entry {
+	sliced := []*entry{}
+	for _, e := range entries {
+		if (!from.IsZero() && e.hour.Before(from)) || (!to.IsZero() && !e.hour.Before(to)) {
+			continue
+		}
+		sliced = append(sliced, e)
+	}
+	return sliced
+}
@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// unit is a wind speed unit, all speeds from Open-Meteo are in m/s.
+type unit struct {
+	name    string
+	label   string
+	convert func(float64) float64
+}
+
+var units = map[string]unit{
+	"ms":  {name: "ms", label: "m/s", convert: func(v float64) float64 { return v }},
+	"kn":  {name: "kn", label: "knots", convert: func(v float64) float64 { return v * 1.943844 }},
+	"kmh": {name: "kmh", label: "km/h", convert: func(v float64) float64 { return v * 3.6 }},
+	"mph": {name: "mph", label: "mph", convert: func(v float64) float64 { return v * 2.236936 }},
+	"bft": {name: "bft", label: "Beaufort", convert: beaufort},
+}
+
+// beaufort converts m/s to the Beaufort scale, v = 0.836 B^(3/2).
+func beaufort(v float64) float64 {
+	return math.Min(12, math.Round(math.Pow(v/0.836, 2.0/3.0)))
+}
+
+// parseUnit reads the unit from the query, falling back to the unit cookie
+// and finally m/s.
+func parseUnit(req *fsthttp.Request) (unit, error) {
+	name := req.URL.Query().Get("unit")
+	if name == "" {
+		name = cookie(req, "unit")
+	}
+	if name == "" {
+		return units["ms"], nil
+	}
+	u, ok := units[strings.ToLower(name)]
+	if !ok {
+		return unit{}, fmt.Errorf("unknown unit %q, use one of ms, kn, kmh, mph, bft", name)
+	}
+	return u, nil
+}
+
+func convertUnits(entries []*entry, u unit) {
+	for _, e := range entries {
+		e.speed = u.convert(e.speed)
+		e.gust = u.convert(e.gust)
+	}
+}
+
+func cookie(req *fsthttp.Request, name string) string {
+	for _, c := range strings.Split(req.Header.Get("Cookie"), ";") {
+		k, v, _ := strings.Cut(strings.TrimSpace(c), "=")
+		if k == name {
+			return v
+		}
+	}
+	return ""
+}
+
+func setCookie(rw fsthttp.ResponseWriter, name, value string) {
+	rw.Header().Add("Set-Cookie", fmt.Sprintf("%s=%s; Path=/; Max-Age=31536000; SameSite=Lax", name, value))
+}
@@ -0,0 +1,160 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"time"
+
+	"compute-starter-kit-go/awattar"
+	"compute-starter-kit-go/elpris"
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+const (
+	// attempts is the number of times a request is sent before giving up.
+	attempts = 3
+	// backoff is the delay before the first retry, it doubles for each
+	// retry and has up to the same amount of jitter added.
+	backoff = 100 * time.Millisecond
+	// breakerOpen is how long a failing backend is skipped.
+	breakerOpen = 30 * time.Second
+)
+
+// errCircuitOpen is returned for backends that recently failed all
+// attempts, the callers fall back to what they have.
+var errCircuitOpen = errors.New("circuit open")
+
+// send sends a GET request to the upstream, retrying errors and 5xx
+// responses with jittered exponential backoff. When all attempts fail the
+// circuit of the backend opens and requests fail immediately for a while.
+// The state of the circuit is kept in the KV Store, shared by the
+// instances. The backend, cache TTL and timeout of the upstream
+// are configured by the backend_<upstream>, ttl_<upstream> and
+// timeout_<upstream> settings.
+func send(ctx context.Context, upstream, u string) (*fsthttp.Response, error) {
+	backend := config("backend_"+upstream, upstream)
+	ttl := uint32(configDuration(ctx, "ttl_"+upstream, time.Hour).Seconds())
+	if circuitOpen(backend) {
+		return nil, fmt.Errorf("%s: %w", backend, errCircuitOpen)
+	}
+	return withTimeout(ctx, upstream, configDuration(ctx, "timeout_"+upstream, backendTimeouts[upstream]), func(ctx context.Context) (*fsthttp.Response, error) {
+		return sendWithRetries(ctx, backend, u, ttl)
+	})
+}
+
+// transport gets the URLs of the clients through send. When fresh is set
+// it is set to whether the last response came from the upstream rather
+// than the cache.
+type transport struct {
+	fresh *bool
+}
+
+func (t transport) Get(ctx context.Context, upstream, u string) (int, []byte, error) {
+	logln(ctx, redact(u))
+	resp, err := send(ctx, upstream, u)
+	if err != nil {
+		return 0, nil, err
+	}
+	if t.fresh != nil {
+		*t.fresh = !cached(resp)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+// The clients of the upstreams.
+var (
+	priceClient   = &elpris.Client{Transport: transport{}}
+	awattarClient = &awattar.Client{Transport: transport{}}
+)
+
+// secretParams are query parameters of upstream URLs holding credentials.
+var secretParams = []string{"securityToken"}
+
+// redact hides the credentials in an upstream URL before it is logged or
+// shown in the debug output.
+func redact(u string) string {
+	p, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	q := p.Query()
+	redacted := false
+	for _, name := range secretParams {
+		if q.Has(name) {
+			q.Set(name, "redacted")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u
+	}
+	p.RawQuery = q.Encode()
+	return p.String()
+}
+
+func sendWithRetries(ctx context.Context, backend, u string, ttl uint32) (*fsthttp.Response, error) {
+	jitter := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var resp *fsthttp.Response
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			d := backoff << (i - 1)
+			time.Sleep(d + time.Duration(jitter.Int63n(int64(d))))
+		}
+		req, _ := newUpstreamRequest(ctx, "GET", u)
+		req.CacheOptions.TTL = ttl
+		resp, err = sendUpstream(ctx, req, backend)
+		if err == nil && resp.StatusCode < 500 {
+			recordModified(ctx, resp)
+			return resp, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("%s responded with status %d", backend, resp.StatusCode)
+		}
+		logln(ctx, "attempt", i+1, "failed:", err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	openCircuit(ctx, backend)
+	return nil, err
+}
+
+func breakerKey(backend string) string {
+	return "breaker/" + backend
+}
+
+// circuitOpen reports whether the backend failed recently.
+func circuitOpen(backend string) bool {
+	store, err := openKV()
+	if err != nil {
+		return false
+	}
+	e, err := store.Lookup(breakerKey(backend))
+	if err != nil {
+		return false
+	}
+	until, err := strconv.ParseInt(e.String(), 10, 64)
+	return err == nil && time.Now().Unix() < until
+}
+
+func openCircuit(ctx context.Context, backend string) {
+	store, err := openKV()
+	if err != nil {
+		return
+	}
+	until := time.Now().Add(breakerOpen).Unix()
+	if err := store.Insert(breakerKey(backend), bytes.NewReader([]byte(strconv.FormatInt(until, 10)))); err != nil {
+		logln(ctx, "breaker:", err)
+	}
+}
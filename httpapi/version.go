@@ -0,0 +1,41 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// gitSHA and buildTime are set when building, see the build script in
+// fastly.toml.
+var (
+	gitSHA    = "unknown"
+	buildTime = "unknown"
+)
+
+// VersionResponse is the JSON representation of /version.
+type VersionResponse struct {
+	ServiceVersion string `json:"service_version"`
+	GitSHA         string `json:"git_sha"`
+	BuildTime      string `json:"build_time"`
+	GoVersion      string `json:"go_version"`
+}
+
+// handleVersion handles /version with the version of the Fastly service
+// and the commit and time it was built from.
+func handleVersion(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	v := VersionResponse{
+		ServiceVersion: os.Getenv("FASTLY_SERVICE_VERSION"),
+		GitSHA:         gitSHA,
+		BuildTime:      buildTime,
+		GoVersion:      runtime.Version(),
+	}
+	b, _ := json.MarshalIndent(v, "", "  ")
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "no-store")
+	fmt.Fprintf(rw, "%s\n", b)
+}
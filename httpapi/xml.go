@@ -0,0 +1,112 @@
+package httpapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/fastly/compute-sdk-go/fsthttp"
+)
+
+// windXSD is the schema of /wind.xml.
+//
+//go:embed wind.xsd
+var windXSD string
+
+// xmlNamespace is the namespace of /wind.xml.
+const xmlNamespace = "https://windy.edgecompute.app/xml/wind"
+
+// XMLForecast is the XML representation of the forecast, /wind.xml,
+// described by /wind.xsd.
+type XMLForecast struct {
+	XMLName        xml.Name    `xml:"forecast"`
+	Namespace      string      `xml:"xmlns,attr"`
+	XSI            string      `xml:"xmlns:xsi,attr"`
+	SchemaLocation string      `xml:"xsi:schemaLocation,attr"`
+	Region         string      `xml:"region,attr"`
+	Height         int         `xml:"height,attr"`
+	Timezone       string      `xml:"timezone,attr"`
+	GeneratedAt    time.Time   `xml:"generated_at,attr"`
+	Stale          bool        `xml:"stale,attr,omitempty"`
+	Location       XMLLocation `xml:"location"`
+	Units          XMLUnits    `xml:"units"`
+	Warnings       []string    `xml:"warning"`
+	Entries        []XMLEntry  `xml:"entry"`
+}
+
+// XMLLocation is the location of the forecast.
+type XMLLocation struct {
+	Name      string  `xml:"name,attr,omitempty"`
+	Latitude  float64 `xml:"latitude,attr"`
+	Longitude float64 `xml:"longitude,attr"`
+}
+
+// XMLUnits are the units of the XMLEntry values.
+type XMLUnits struct {
+	Speed                    string `xml:"speed,attr"`
+	Gust                     string `xml:"gust,attr"`
+	Direction                string `xml:"direction,attr"`
+	Temperature              string `xml:"temperature,attr"`
+	Precipitation            string `xml:"precipitation,attr"`
+	PrecipitationProbability string `xml:"precipitation_probability,attr"`
+	Price                    string `xml:"price,attr"`
+}
+
+// XMLEntry is an hour of the forecast, missing values are left out.
+type XMLEntry struct {
+	Hour                     string   `xml:"hour,attr"`
+	Past                     bool     `xml:"past,attr,omitempty"`
+	Speed                    float64  `xml:"speed"`
+	Gust                     float64  `xml:"gust"`
+	Direction                float64  `xml:"direction"`
+	Temperature              float64  `xml:"temperature"`
+	ApparentTemperature      float64  `xml:"apparent_temperature"`
+	Precipitation            float64  `xml:"precipitation"`
+	PrecipitationProbability float64  `xml:"precipitation_probability"`
+	Price                    *float64 `xml:"price"`
+	Score                    *float64 `xml:"score"`
+	Session                  *float64 `xml:"session"`
+}
+
+func toXML(f *forecast) string {
+	r := toResponse(f)
+	x := XMLForecast{
+		Namespace:      xmlNamespace,
+		XSI:            "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: xmlNamespace + " https://windy.edgecompute.app/wind.xsd",
+		Region:         r.Region,
+		Height:         r.Height,
+		Timezone:       r.Timezone,
+		GeneratedAt:    r.GeneratedAt,
+		Stale:          r.Stale,
+		Location:       XMLLocation(r.Location),
+		Units:          XMLUnits(r.Units),
+		Warnings:       r.Warnings,
+		Entries: mapSlice(r.Entries, func(e Entry) XMLEntry {
+			return XMLEntry{
+				Hour:                     e.Hour,
+				Past:                     e.Past,
+				Speed:                    e.Speed,
+				Gust:                     e.Gust,
+				Direction:                e.Direction,
+				Temperature:              e.Temperature,
+				ApparentTemperature:      e.ApparentTemperature,
+				Precipitation:            e.Precipitation,
+				PrecipitationProbability: e.PrecipitationProbability,
+				Price:                    e.Price,
+				Score:                    e.Score,
+				Session:                  e.Session,
+			}
+		}),
+	}
+	b, _ := xml.MarshalIndent(x, "", "  ")
+	return xml.Header + string(b) + "\n"
+}
+
+func handleXSD(ctx context.Context, rw fsthttp.ResponseWriter, req *fsthttp.Request) {
+	rw.Header().Set("Content-Type", "application/xml")
+	rw.Header().Set("Cache-Control", "public, max-age=86400")
+	fmt.Fprint(rw, windXSD)
+}
@@ -0,0 +1,18 @@
+// Package merge joins hourly time series from different sources.
+package merge
+
+import "time"
+
+// ByHour calls set with each item of a and the item of b at the same hour.
+// Items of a without a match in b are left alone.
+func ByHour[A, B any](a []A, b []B, hourA func(A) time.Time, hourB func(B) time.Time, set func(A, B)) {
+	byHour := make(map[int64]B, len(b))
+	for _, item := range b {
+		byHour[hourB(item).Unix()] = item
+	}
+	for _, item := range a {
+		if match, ok := byHour[hourA(item).Unix()]; ok {
+			set(item, match)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package merge
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type hour struct {
+	t     time.Time
+	match string
+}
+
+type price struct {
+	t     time.Time
+	label string
+}
+
+// day returns the hours of the day in Stockholm from midnight to midnight,
+// 23 on the spring forward day and 25 on the fall back day.
+func day(t *testing.T, date string) []time.Time {
+	t.Helper()
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, err := time.ParseInLocation("2006-01-02", date, stockholm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := start.AddDate(0, 0, 1)
+	hours := []time.Time{}
+	for h := start; h.Before(end); h = h.Add(time.Hour) {
+		hours = append(hours, h)
+	}
+	return hours
+}
+
+func TestByHour(t *testing.T) {
+	spring := day(t, "2023-03-26")
+	fall := day(t, "2023-10-29")
+	label := func(h time.Time) string {
+		return h.Format("15:04 MST")
+	}
+	if len(spring) != 23 || label(spring[2]) != "03:00 CEST" {
+		t.Fatalf("spring forward has %d hours, the third %s", len(spring), label(spring[2]))
+	}
+	if len(fall) != 25 || label(fall[2]) != "02:00 CEST" || label(fall[3]) != "02:00 CET" {
+		t.Fatalf("fall back has %d hours, the third %s", len(fall), label(fall[2]))
+	}
+	tests := []struct {
+		name   string
+		a      []time.Time
+		b      []time.Time
+		expect []string
+	}{
+		{
+			name:   "spring forward, 02:00 does not exist",
+			a:      spring,
+			b:      spring,
+			expect: mapTimes(spring, label),
+		},
+		{
+			name:   "spring forward, missing hours on both sides",
+			a:      spring[:5],
+			b:      []time.Time{spring[0].Add(-time.Hour), spring[1], spring[3], spring[4], spring[22]},
+			expect: []string{"", "01:00 CET", "", "04:00 CEST", "05:00 CEST"},
+		},
+		{
+			name:   "fall back, 02:00 twice",
+			a:      fall,
+			b:      fall,
+			expect: mapTimes(fall, label),
+		},
+		{
+			name:   "fall back, only the second 02:00 has a price",
+			a:      fall[:5],
+			b:      []time.Time{fall[0], fall[3], fall[4], fall[5]},
+			expect: []string{"00:00 CEST", "", "", "02:00 CET", "03:00 CET"},
+		},
+		{
+			name:   "fall back, prices in UTC",
+			a:      fall[1:4],
+			b:      mapTimes(fall, func(h time.Time) time.Time { return h.UTC() }),
+			expect: []string{"01:00 CEST", "02:00 CEST", "02:00 CET"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := mapTimes(tt.a, func(h time.Time) *hour { return &hour{t: h} })
+			b := mapTimes(tt.b, func(h time.Time) price {
+				return price{t: h, label: label(h.In(spring[0].Location()))}
+			})
+			ByHour(a, b, func(h *hour) time.Time { return h.t }, func(p price) time.Time { return p.t }, func(h *hour, p price) {
+				if h.match != "" {
+					t.Errorf("%s matched twice", h.t)
+				}
+				h.match = p.label
+			})
+			got := mapTimes(a, func(h *hour) string { return h.match })
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("got %q, want %q", got, tt.expect)
+			}
+		})
+	}
+}
+
+func mapTimes[T, U any](s []T, f func(T) U) []U {
+	out := make([]U, len(s))
+	for i, v := range s {
+		out[i] = f(v)
+	}
+	return out
+}
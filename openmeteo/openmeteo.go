@@ -0,0 +1,184 @@
+// Package openmeteo is a client of the Open-Meteo forecast API,
+// https://open-meteo.com/en/docs.
+package openmeteo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/buger/jsonparser"
+)
+
+// Upstream is the name of the forecast API given to the Transport.
+const Upstream = "open-meteo"
+
+// Transport gets the URL from the named upstream and returns the status
+// and body, how is up to the program, e.g. Fastly backends with retries.
+type Transport interface {
+	Get(ctx context.Context, upstream, url string) (int, []byte, error)
+}
+
+// Client fetches forecasts through the Transport.
+type Client struct {
+	Transport Transport
+}
+
+// Query is the location and window of a forecast. The coordinates are
+// strings so the caller controls the precision, which is part of the cache
+// key upstream.
+type Query struct {
+	Latitude, Longitude string
+	// Days is the number of days starting at midnight, PastDays the
+	// number of days before today.
+	Days, PastDays int
+	// Height is the height of the wind in meters, 10, 80, 120 or 180.
+	// Gusts are always at 10 m.
+	Height int
+}
+
+// Hour is the forecast of an hour. Speeds are in m/s, temperatures in °C,
+// precipitation in mm and the probability in percent.
+type Hour struct {
+	Time                     time.Time
+	Speed                    float64
+	Gust                     float64
+	Direction                float64
+	Temperature              float64
+	ApparentTemperature      float64
+	Precipitation            float64
+	PrecipitationProbability float64
+}
+
+// Forecast is the hourly forecast in the timezone of the location.
+type Forecast struct {
+	Timezone *time.Location
+	Hours    []Hour
+}
+
+// URL returns the URL of the forecast.
+func URL(q Query) string {
+	props := []string{
+		fmt.Sprintf("windspeed_%dm", q.Height),
+		"windgusts_10m",
+		fmt.Sprintf("winddirection_%dm", q.Height),
+		"temperature_2m",
+		"apparent_temperature",
+		"precipitation",
+		"precipitation_probability",
+	}
+	return fmt.Sprintf("https://api.open-meteo.com/v1/forecast?latitude=%s&longitude=%s&windspeed_unit=ms&timezone=auto&timeformat=unixtime&forecast_days=%d&past_days=%d&hourly=%s", q.Latitude, q.Longitude, q.Days, q.PastDays, strings.Join(props, ","))
+}
+
+// Forecast fetches the hourly forecast.
+func (c *Client) Forecast(ctx context.Context, q Query) (*Forecast, error) {
+	status, body, err := c.Transport.Get(ctx, Upstream, URL(q))
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		reason, _ := jsonparser.GetString(body, "reason")
+		return nil, fmt.Errorf("open-meteo responded with status %d: %s", status, reason)
+	}
+	if !json.Valid(body) {
+		return nil, errors.New("open-meteo responded with invalid JSON")
+	}
+	return ParseForecast(body, q.Height), nil
+}
+
+// ParseForecast parses a forecast response with the wind at the height.
+func ParseForecast(body []byte, height int) *Forecast {
+	times := Times(body, "hourly", "time")
+	speeds := Floats(body, "hourly", fmt.Sprintf("windspeed_%dm", height))
+	gusts := Floats(body, "hourly", "windgusts_10m")
+	directions := Floats(body, "hourly", fmt.Sprintf("winddirection_%dm", height))
+	temperatures := Floats(body, "hourly", "temperature_2m")
+	apparentTemperatures := Floats(body, "hourly", "apparent_temperature")
+	precipitations := Floats(body, "hourly", "precipitation")
+	probabilities := Floats(body, "hourly", "precipitation_probability")
+	n := minLen(times, speeds, gusts, directions, temperatures, apparentTemperatures, precipitations, probabilities)
+	f := &Forecast{Timezone: Timezone(body), Hours: make([]Hour, n)}
+	for i := 0; i < n; i++ {
+		f.Hours[i] = Hour{
+			Time:                     times[i],
+			Speed:                    speeds[i],
+			Gust:                     gusts[i],
+			Direction:                directions[i],
+			Temperature:              temperatures[i],
+			ApparentTemperature:      apparentTemperatures[i],
+			Precipitation:            precipitations[i],
+			PrecipitationProbability: probabilities[i],
+		}
+	}
+	return f
+}
+
+// minLen returns the length of the shortest of the hourly arrays, Open-Meteo
+// should return arrays of equal length but we don't want to panic if not.
+func minLen(times []time.Time, values ...[]float64) int {
+	n := len(times)
+	for _, v := range values {
+		if len(v) < n {
+			n = len(v)
+		}
+	}
+	return n
+}
+
+// Timezone returns the timezone Open-Meteo resolved for the location,
+// falling back to a fixed offset if the zone is unknown.
+func Timezone(body []byte) *time.Location {
+	name, _ := jsonparser.GetString(body, "timezone")
+	if loc, err := time.LoadLocation(name); err == nil && name != "" {
+		return loc
+	}
+	offset, _ := jsonparser.GetInt(body, "utc_offset_seconds")
+	abbr, _ := jsonparser.GetString(body, "timezone_abbreviation")
+	return time.FixedZone(abbr, int(offset))
+}
+
+// Times parses an array of unix timestamps into UTC times.
+func Times(body []byte, props ...string) []time.Time {
+	items := []time.Time{}
+	jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		i, _ := jsonparser.ParseInt(value)
+		items = append(items, time.Unix(i, 0).UTC())
+	}, props...)
+	return items
+}
+
+// Floats parses an array of numbers, null is parsed as 0.
+func Floats(body []byte, props ...string) []float64 {
+	items := []float64{}
+	jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		f, _ := jsonparser.ParseFloat(value)
+		items = append(items, f)
+	}, props...)
+	return items
+}
+
+// OptionalFloats parses an array of numbers where null is a missing value.
+func OptionalFloats(body []byte, props ...string) []*float64 {
+	items := []*float64{}
+	jsonparser.ArrayEach(body, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		if dataType != jsonparser.Number {
+			items = append(items, nil)
+			return
+		}
+		f, _ := jsonparser.ParseFloat(value)
+		items = append(items, &f)
+	}, props...)
+	return items
+}
+
+// At returns the value at i of optional values, nil if the array is too
+// short.
+func At(values []*float64, i int) *float64 {
+	if i >= len(values) {
+		return nil
+	}
+	return values[i]
+}
@@ -0,0 +1,119 @@
+package openmeteo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTransport responds with the status and body, or the error, and
+// keeps the URL requested.
+type fakeTransport struct {
+	status int
+	body   string
+	err    error
+	url    string
+}
+
+func (t *fakeTransport) Get(ctx context.Context, upstream, url string) (int, []byte, error) {
+	t.url = url
+	return t.status, []byte(t.body), t.err
+}
+
+const forecastBody = `{
+	"timezone": "Europe/Stockholm",
+	"hourly": {
+		"time": [1676415600, 1676419200],
+		"windspeed_80m": [5.5, 6.5],
+		"windgusts_10m": [9.1, 10.2],
+		"winddirection_80m": [225, 230],
+		"temperature_2m": [1.2, 0.8],
+		"apparent_temperature": [-2.5, -3.1],
+		"precipitation": [0, 0.4],
+		"precipitation_probability": [5, 40]
+	}
+}`
+
+func TestForecast(t *testing.T) {
+	tr := &fakeTransport{status: 200, body: forecastBody}
+	f, err := (&Client{Transport: tr}).Forecast(context.Background(), Query{Latitude: "55.67", Longitude: "13.06", Days: 2, Height: 80})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(tr.url, "latitude=55.67&longitude=13.06") || !strings.Contains(tr.url, "windspeed_80m") {
+		t.Errorf("got URL %s", tr.url)
+	}
+	if f.Timezone.String() != "Europe/Stockholm" {
+		t.Errorf("got timezone %s", f.Timezone)
+	}
+	want := []Hour{
+		{Time: time.Unix(1676415600, 0).UTC(), Speed: 5.5, Gust: 9.1, Direction: 225, Temperature: 1.2, ApparentTemperature: -2.5, Precipitation: 0, PrecipitationProbability: 5},
+		{Time: time.Unix(1676419200, 0).UTC(), Speed: 6.5, Gust: 10.2, Direction: 230, Temperature: 0.8, ApparentTemperature: -3.1, Precipitation: 0.4, PrecipitationProbability: 40},
+	}
+	if len(f.Hours) != len(want) {
+		t.Fatalf("got %d hours, want %d", len(f.Hours), len(want))
+	}
+	for i, h := range f.Hours {
+		if h != want[i] {
+			t.Errorf("hour %d is %+v, want %+v", i, h, want[i])
+		}
+	}
+}
+
+func TestForecastErrors(t *testing.T) {
+	failed := errors.New("connection refused")
+	tests := []struct {
+		name string
+		tr   *fakeTransport
+		want string
+	}{
+		{"transport error", &fakeTransport{err: failed}, "connection refused"},
+		{"non-2xx", &fakeTransport{status: 400, body: `{"error": true, "reason": "Latitude must be in range of -90 to 90°."}`}, "status 400: Latitude must be"},
+		{"server error without JSON", &fakeTransport{status: 502, body: "Bad Gateway"}, "status 502"},
+		{"truncated JSON", &fakeTransport{status: 200, body: forecastBody[:len(forecastBody)/2]}, "invalid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&Client{Transport: tt.tr}).Forecast(context.Background(), Query{Latitude: "55.67", Longitude: "13.06", Days: 1, Height: 80})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseForecastShortArrays(t *testing.T) {
+	body := `{
+		"timezone": "Europe/Stockholm",
+		"hourly": {
+			"time": [1676415600, 1676419200, 1676422800],
+			"windspeed_10m": [5.5, 6.5, 7.5],
+			"windgusts_10m": [9.1, 10.2],
+			"winddirection_10m": [225, 230, 235],
+			"temperature_2m": [1.2, 0.8, 0.5],
+			"apparent_temperature": [-2.5, -3.1, -3.5],
+			"precipitation": [0],
+			"precipitation_probability": [5, 40, 60]
+		}
+	}`
+	f := ParseForecast([]byte(body), 10)
+	if len(f.Hours) != 1 {
+		t.Fatalf("got %d hours, want 1, the length of the shortest array", len(f.Hours))
+	}
+	if f.Hours[0].Speed != 5.5 || f.Hours[0].Gust != 9.1 {
+		t.Errorf("got %+v", f.Hours[0])
+	}
+	if f := ParseForecast([]byte(`{"hourly": {"time": [1676415600]}}`), 10); len(f.Hours) != 0 {
+		t.Errorf("got %d hours without values, want 0", len(f.Hours))
+	}
+}
+
+func TestTimezoneFallback(t *testing.T) {
+	loc := Timezone([]byte(`{"timezone": "Nowhere/Unknown", "timezone_abbreviation": "XYZ", "utc_offset_seconds": 3600}`))
+	name, offset := time.Unix(0, 0).In(loc).Zone()
+	if name != "XYZ" || offset != 3600 {
+		t.Errorf("got %s %d, want XYZ 3600", name, offset)
+	}
+}
@@ -0,0 +1,214 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Chart is a line chart of hourly values rendered without JavaScript, as
+// SVG or PNG.
+type Chart struct {
+	Title string
+	// Labels label the x axis at the points where they are not empty.
+	Labels []string
+	Series []Series
+	Bands  []Band
+	// LeftUnit and RightUnit label the y axes.
+	LeftUnit, RightUnit string
+}
+
+// Series is a line, NaN values are missing and break the line. Series on
+// the right use the right y axis.
+type Series struct {
+	Label  string
+	Color  string
+	Values []float64
+	Right  bool
+}
+
+// Band shades the points where it is set.
+type Band struct {
+	Label string
+	Color string
+	Set   []bool
+}
+
+// scale maps values from lo to hi, with grid lines every step.
+type scale struct {
+	lo, hi, step float64
+}
+
+// plot is the layout of a chart of a size, shared by the SVG and PNG
+// renderers. The margins are multiplied by k for larger text.
+type plot struct {
+	Chart
+	width, height            float64
+	k                        float64
+	left, right, top, bottom float64
+	scales                   [2]*scale
+}
+
+func (c Chart) plot(width, height int, k float64) plot {
+	p := plot{Chart: c, width: float64(width), height: float64(height), k: k, left: 48 * k, right: float64(width) - 16*k, top: 40 * k, bottom: float64(height) - 28*k}
+	for i, right := range []bool{false, true} {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, s := range c.Series {
+			if s.Right != right {
+				continue
+			}
+			l, h := MinMax(s.Values)
+			lo, hi = math.Min(lo, l), math.Max(hi, h)
+		}
+		if !math.IsInf(lo, 1) {
+			p.scales[i] = niceScale(math.Min(lo, 0), hi)
+		}
+	}
+	if p.scales[1] != nil {
+		p.right = float64(width) - 56*k
+	}
+	return p
+}
+
+// niceScale returns a scale covering lo to hi with about four steps of 1, 2
+// or 5 times a power of ten.
+func niceScale(lo, hi float64) *scale {
+	if hi <= lo {
+		hi = lo + 1
+	}
+	raw := (hi - lo) / 4
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := 10 * magnitude
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*magnitude {
+			step = m * magnitude
+			break
+		}
+	}
+	return &scale{math.Floor(lo/step) * step, math.Ceil(hi/step) * step, step}
+}
+
+// ticks returns the values of the grid lines of the scale.
+func (s *scale) ticks() []float64 {
+	ticks := []float64{}
+	for v := s.lo; v <= s.hi+s.step/2; v += s.step {
+		ticks = append(ticks, v)
+	}
+	return ticks
+}
+
+func (p plot) x(i int) float64 {
+	if len(p.Labels) < 2 {
+		return (p.left + p.right) / 2
+	}
+	return p.left + float64(i)*(p.right-p.left)/float64(len(p.Labels)-1)
+}
+
+func (p plot) y(s *scale, v float64) float64 {
+	return p.bottom - (v-s.lo)/(s.hi-s.lo)*(p.bottom-p.top)
+}
+
+// scaleOf returns the scale of the series.
+func (p plot) scaleOf(s Series) *scale {
+	if s.Right {
+		return p.scales[1]
+	}
+	return p.scales[0]
+}
+
+// segments returns the points of the series split where values are
+// missing.
+func (p plot) segments(s Series) [][][2]float64 {
+	segments := [][][2]float64{}
+	var current [][2]float64
+	for i, v := range s.Values {
+		if math.IsNaN(v) {
+			if len(current) > 0 {
+				segments = append(segments, current)
+			}
+			current = nil
+			continue
+		}
+		current = append(current, [2]float64{p.x(i), p.y(p.scaleOf(s), v)})
+	}
+	if len(current) > 0 {
+		segments = append(segments, current)
+	}
+	return segments
+}
+
+// bandRect returns the horizontal extent of the point i of a band, half
+// way to the neighbouring points.
+func (p plot) bandRect(i int) (x0, x1 float64) {
+	half := 0.0
+	if len(p.Labels) > 1 {
+		half = (p.right - p.left) / float64(len(p.Labels)-1) / 2
+	}
+	return math.Max(p.x(i)-half, p.left), math.Min(p.x(i)+half, p.right)
+}
+
+// formatTick formats the value of a grid line.
+func formatTick(v float64) string {
+	return fmt.Sprintf("%g", math.Round(v*1000)/1000)
+}
+
+// SVG renders the chart as an SVG image of the size in pixels.
+func (c Chart) SVG(width, height int) string {
+	p := c.plot(width, height, 1)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %[1]d %[2]d" font-family="sans-serif" font-size="12">`+"\n", width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	fmt.Fprintf(&b, `<text x="%g" y="16" font-size="14" font-weight="bold">%s</text>`+"\n", p.left, escape(c.Title))
+	legendX := p.left
+	for _, s := range c.Series {
+		fmt.Fprintf(&b, `<rect x="%g" y="24" width="12" height="4" fill="%s"/><text x="%g" y="30">%s</text>`+"\n", legendX, s.Color, legendX+16, escape(s.Label))
+		legendX += 24 + 7*float64(len([]rune(s.Label)))
+	}
+	for _, band := range c.Bands {
+		fmt.Fprintf(&b, `<rect x="%g" y="20" width="12" height="12" fill="%s"/><text x="%g" y="30">%s</text>`+"\n", legendX, band.Color, legendX+16, escape(band.Label))
+		legendX += 24 + 7*float64(len([]rune(band.Label)))
+	}
+	for _, band := range c.Bands {
+		for i, set := range band.Set {
+			if set {
+				x0, x1 := p.bandRect(i)
+				fmt.Fprintf(&b, `<rect x="%.1f" y="%g" width="%.1f" height="%g" fill="%s"/>`+"\n", x0, p.top, x1-x0, p.bottom-p.top, band.Color)
+			}
+		}
+	}
+	if s := p.scales[0]; s != nil {
+		for _, v := range s.ticks() {
+			y := p.y(s, v)
+			fmt.Fprintf(&b, `<line x1="%g" y1="%.1f" x2="%g" y2="%.1f" stroke="#ddd"/>`+"\n", p.left, y, p.right, y)
+			fmt.Fprintf(&b, `<text x="%g" y="%.1f" text-anchor="end">%s</text>`+"\n", p.left-4, y+4, formatTick(v))
+		}
+		fmt.Fprintf(&b, `<text x="4" y="%g">%s</text>`+"\n", p.top-4, escape(c.LeftUnit))
+	}
+	if s := p.scales[1]; s != nil {
+		for _, v := range s.ticks() {
+			fmt.Fprintf(&b, `<text x="%g" y="%.1f">%s</text>`+"\n", p.right+4, p.y(s, v)+4, formatTick(v))
+		}
+		fmt.Fprintf(&b, `<text x="%g" y="%g" text-anchor="end">%s</text>`+"\n", p.width-4, p.top-4, escape(c.RightUnit))
+	}
+	fmt.Fprintf(&b, `<line x1="%g" y1="%g" x2="%g" y2="%g" stroke="#888"/>`+"\n", p.left, p.bottom, p.right, p.bottom)
+	for i, l := range c.Labels {
+		if l != "" {
+			x := p.x(i)
+			fmt.Fprintf(&b, `<line x1="%.1f" y1="%g" x2="%.1f" y2="%g" stroke="#888"/>`+"\n", x, p.bottom, x, p.bottom+4)
+			fmt.Fprintf(&b, `<text x="%.1f" y="%g" text-anchor="middle">%s</text>`+"\n", x, p.bottom+18, escape(l))
+		}
+	}
+	for _, s := range c.Series {
+		for _, seg := range p.segments(s) {
+			points := make([]string, len(seg))
+			for i, pt := range seg {
+				points[i] = fmt.Sprintf("%.1f,%.1f", pt[0], pt[1])
+			}
+			fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", strings.Join(points, " "), s.Color)
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+var escape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace
@@ -0,0 +1,126 @@
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Languages are the languages of the pages, the messages are written in
+// English, the default, and translated from it.
+var Languages = []string{"en", "sv"}
+
+// catalog are the translations of the messages by language.
+var catalog = map[string]map[string]string{
+	"sv": {
+		// Titles
+		"Winds in %s (lat: %s, long: %s)":               "Vind i %s (lat: %s, long: %s)",
+		"Winds near %s (lat: %s, long: %s)":             "Vind nära %s (lat: %s, long: %s)",
+		"Winds at browser location (lat: %s, long: %s)": "Vind vid webbläsarens plats (lat: %s, long: %s)",
+		"Winds in %s, %s (lat: %.2f, long: %.2f)":       "Vind i %s, %s (lat: %.2f, long: %.2f)",
+		"%s at %d m":        "%s på %d m",
+		"%s, prices for %s": "%s, elpriser för %s",
+		"Chart of the forecast, the same data is in the table below": "Diagram över prognosen, samma data finns i tabellen nedan",
+		// Ranges and table
+		"Show":                "Visa",
+		"All":                 "Allt",
+		"Forecast table":      "Prognostabell",
+		"Time (%s)":           "Tid (%s)",
+		"Average (%s)":        "Medelvind (%s)",
+		"Gust (%s)":           "Byvind (%s)",
+		"Direction":           "Riktning",
+		"Precipitation (mm)":  "Nederbörd (mm)",
+		"Price (%s)":          "Pris (%s)",
+		"Consumer price (%s)": "Konsumentpris (%s)",
+		"Temperature (°C)":    "Temperatur (°C)",
+		"Feels like (°C)":     "Känns som (°C)",
+		"Name":                "Namn",
+		"Save to my spots":    "Spara i mina platser",
+		"Min":                 "Min",
+		"Max":                 "Max",
+		"Mean":                "Medel",
+		"Median":              "Median",
+		"Price":               "Pris",
+		"Windiest hour: %s":   "Blåsigaste timmen: %s",
+		", cheapest hour: %s": ", billigaste timmen: %s",
+		// Chart
+		"Wind (%s)":                             "Vind (%s)",
+		"Good time to run appliances":           "Bra tid att köra apparater",
+		"Past":                                  "Passerat",
+		"Gusts above %s %s":                     "Byar över %s %s",
+		"Cheapest %d hours":                     "Billigaste %d timmarna",
+		"Ensemble p90 (%s)":                     "Ensemble p90 (%s)",
+		"Ensemble p10 (%s)":                     "Ensemble p10 (%s)",
+		"%s, cheap up to %s, expensive from %s": "%s, billigt upp till %s, dyrt från %s",
+		// Units
+		"knots":    "knop",
+		"Beaufort": "Beaufort",
+		// Errors
+		"Bad Request":           "Felaktig begäran",
+		"Unauthorized":          "Ej inloggad",
+		"Forbidden":             "Förbjuden",
+		"Not Found":             "Hittades inte",
+		"Method Not Allowed":    "Metoden är inte tillåten",
+		"Not Acceptable":        "Formatet stöds inte",
+		"Too Many Requests":     "För många förfrågningar",
+		"Internal Server Error": "Internt serverfel",
+		"Not Implemented":       "Inte implementerat",
+		"Bad Gateway":           "Felaktig gateway",
+		"Gateway Timeout":       "Gatewayens tidsgräns överskreds",
+		"Something went wrong, please try again later.":       "Något gick fel, försök igen senare.",
+		"An upstream service failed, please try again later.": "En bakomliggande tjänst misslyckades, försök igen senare.",
+		"Try the":    "Prova",
+		"start page": "startsidan",
+		"If the problem remains, mention request %s.": "Om problemet kvarstår, ange begäran %s.",
+	},
+}
+
+// names are the names of the days and months by language, the long names
+// first so they are replaced before the abbreviations.
+var names = map[string]*strings.Replacer{
+	"sv": strings.NewReplacer(
+		"Monday", "måndag", "Tuesday", "tisdag", "Wednesday", "onsdag", "Thursday", "torsdag",
+		"Friday", "fredag", "Saturday", "lördag", "Sunday", "söndag",
+		"January", "januari", "February", "februari", "March", "mars", "April", "april",
+		"May", "maj", "June", "juni", "July", "juli", "August", "augusti",
+		"September", "september", "October", "oktober", "November", "november", "December", "december",
+		"Mon", "mån", "Tue", "tis", "Wed", "ons", "Thu", "tors", "Fri", "fre", "Sat", "lör", "Sun", "sön",
+		"Jan", "jan", "Feb", "feb", "Mar", "mars", "Apr", "apr", "Jun", "juni", "Jul", "juli",
+		"Aug", "aug", "Sep", "sep", "Oct", "okt", "Nov", "nov", "Dec", "dec",
+	),
+}
+
+// T translates the message to the language, messages without a
+// translation are returned as they are.
+func T(lang, msg string) string {
+	if s, ok := catalog[lang][msg]; ok {
+		return s
+	}
+	return msg
+}
+
+// Tf formats the arguments with the translated format.
+func Tf(lang, format string, args ...any) string {
+	return fmt.Sprintf(T(lang, format), args...)
+}
+
+// Number formats v with prec decimals, -1 for as many as needed, with a
+// decimal comma in Swedish.
+func Number(lang string, v float64, prec int) string {
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	if lang == "sv" {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
+
+// Date formats t with the layout, with the names of the days and months in
+// the language.
+func Date(lang string, t time.Time, layout string) string {
+	s := t.Format(layout)
+	if r, ok := names[lang]; ok {
+		s = r.Replace(s)
+	}
+	return s
+}
@@ -0,0 +1,201 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+	"unicode"
+)
+
+var (
+	white     = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	black     = color.RGBA{0x22, 0x22, 0x22, 0xff}
+	gridColor = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+	axisColor = color.RGBA{0x88, 0x88, 0x88, 0xff}
+)
+
+// PNG renders the chart as a PNG image of the size in pixels. The text is
+// drawn in a built-in bitmap font, scaled up with the height.
+func (c Chart) PNG(width, height int) ([]byte, error) {
+	k := math.Max(1, math.Floor(float64(height)/300))
+	p := c.plot(width, height, k)
+	s := int(k)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fillRect(img, 0, 0, p.width, p.height, white)
+	drawText(img, p.left, 16*k, c.Title, black, s+1, -1)
+	legendX := p.left
+	for _, series := range c.Series {
+		fillRect(img, legendX, 24*k, legendX+12*k, 28*k, parseColor(series.Color))
+		drawText(img, legendX+16*k, 30*k, series.Label, black, s, -1)
+		legendX += 24*k + 6*k*float64(len([]rune(series.Label)))
+	}
+	for _, band := range c.Bands {
+		fillRect(img, legendX, 20*k, legendX+12*k, 32*k, parseColor(band.Color))
+		drawText(img, legendX+16*k, 30*k, band.Label, black, s, -1)
+		legendX += 24*k + 6*k*float64(len([]rune(band.Label)))
+	}
+	for _, band := range c.Bands {
+		for i, set := range band.Set {
+			if set {
+				x0, x1 := p.bandRect(i)
+				fillRect(img, x0, p.top, x1, p.bottom, parseColor(band.Color))
+			}
+		}
+	}
+	if sc := p.scales[0]; sc != nil {
+		for _, v := range sc.ticks() {
+			y := p.y(sc, v)
+			fillRect(img, p.left, y, p.right, y+k, gridColor)
+			drawText(img, p.left-4*k, y+4*k, formatTick(v), black, s, 1)
+		}
+		drawText(img, 4*k, p.top-4*k, c.LeftUnit, black, s, -1)
+	}
+	if sc := p.scales[1]; sc != nil {
+		for _, v := range sc.ticks() {
+			drawText(img, p.right+4*k, p.y(sc, v)+4*k, formatTick(v), black, s, -1)
+		}
+		drawText(img, p.width-4*k, p.top-4*k, c.RightUnit, black, s, 1)
+	}
+	fillRect(img, p.left, p.bottom, p.right, p.bottom+k, axisColor)
+	for i, l := range c.Labels {
+		if l != "" {
+			x := p.x(i)
+			fillRect(img, x, p.bottom, x+k, p.bottom+4*k, axisColor)
+			drawText(img, x, p.bottom+18*k, l, black, s, 0)
+		}
+	}
+	for _, series := range c.Series {
+		col := parseColor(series.Color)
+		for _, seg := range p.segments(series) {
+			for i := 1; i < len(seg); i++ {
+				drawLine(img, seg[i-1], seg[i], 2*k, col)
+			}
+			if len(seg) == 1 {
+				drawLine(img, seg[0], seg[0], 2*k, col)
+			}
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// fillRect fills the rectangle from x0, y0 to x1, y1, clipped to the image.
+func fillRect(img *image.RGBA, x0, y0, x1, y1 float64, c color.Color) {
+	r := image.Rect(int(math.Round(x0)), int(math.Round(y0)), int(math.Round(x1)), int(math.Round(y1)))
+	draw.Draw(img, r, &image.Uniform{c}, image.Point{}, draw.Src)
+}
+
+// drawLine draws a line of the thickness by stamping squares along it.
+func drawLine(img *image.RGBA, from, to [2]float64, thickness float64, c color.Color) {
+	dx, dy := to[0]-from[0], to[1]-from[1]
+	steps := math.Max(1, math.Ceil(math.Max(math.Abs(dx), math.Abs(dy))))
+	for i := 0.0; i <= steps; i++ {
+		x, y := from[0]+dx*i/steps, from[1]+dy*i/steps
+		fillRect(img, x-thickness/2, y-thickness/2, x+thickness/2, y+thickness/2, c)
+	}
+}
+
+// parseColor parses colors like #1565c0 and #ddd, other colors are black.
+func parseColor(s string) color.RGBA {
+	if len(s) == 4 && s[0] == '#' {
+		s = string([]byte{'#', s[1], s[1], s[2], s[2], s[3], s[3]})
+	}
+	if len(s) != 7 || s[0] != '#' {
+		return black
+	}
+	n, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return black
+	}
+	return color.RGBA{uint8(n >> 16), uint8(n >> 8), uint8(n), 0xff}
+}
+
+// drawText draws the text with its baseline at y, starting at x when
+// anchor is -1, centered on x when 0 and ending at x when 1. Letters are
+// drawn in upper case, characters without a glyph as spaces.
+func drawText(img *image.RGBA, x, y float64, text string, c color.Color, scale, anchor int) {
+	runes := []rune(text)
+	width := float64(len(runes)*6*scale - scale)
+	x -= float64(anchor+1) * width / 2
+	top := int(math.Round(y)) - 7*scale
+	for i, r := range runes {
+		r = unicode.ToUpper(r)
+		if f, ok := folds[r]; ok {
+			r = f
+		}
+		g, ok := glyphs[r]
+		if !ok {
+			continue
+		}
+		left := int(math.Round(x)) + i*6*scale
+		for row, bits := range g {
+			for col, bit := range bits {
+				if bit == '1' {
+					rect := image.Rect(left+col*scale, top+row*scale, left+(col+1)*scale, top+(row+1)*scale)
+					draw.Draw(img, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+				}
+			}
+		}
+	}
+}
+
+// folds maps letters without a glyph to a similar one.
+var folds = map[rune]rune{'Å': 'A', 'Ä': 'A', 'Á': 'A', 'À': 'A', 'Æ': 'A', 'Ö': 'O', 'Ø': 'O', 'Ó': 'O', 'É': 'E', 'È': 'E', 'Ü': 'U'}
+
+// glyphs is a 5x7 bitmap font of digits, upper case letters and some
+// punctuation.
+var glyphs = map[rune][7]string{
+	'0': {"01110", "10001", "10011", "10101", "11001", "10001", "01110"},
+	'1': {"00100", "01100", "00100", "00100", "00100", "00100", "01110"},
+	'2': {"01110", "10001", "00001", "00010", "00100", "01000", "11111"},
+	'3': {"11111", "00010", "00100", "00010", "00001", "10001", "01110"},
+	'4': {"00010", "00110", "01010", "10010", "11111", "00010", "00010"},
+	'5': {"11111", "10000", "11110", "00001", "00001", "10001", "01110"},
+	'6': {"00110", "01000", "10000", "11110", "10001", "10001", "01110"},
+	'7': {"11111", "00001", "00010", "00100", "01000", "01000", "01000"},
+	'8': {"01110", "10001", "10001", "01110", "10001", "10001", "01110"},
+	'9': {"01110", "10001", "10001", "01111", "00001", "00010", "01100"},
+	'A': {"01110", "10001", "10001", "11111", "10001", "10001", "10001"},
+	'B': {"11110", "10001", "10001", "11110", "10001", "10001", "11110"},
+	'C': {"01110", "10001", "10000", "10000", "10000", "10001", "01110"},
+	'D': {"11100", "10010", "10001", "10001", "10001", "10010", "11100"},
+	'E': {"11111", "10000", "10000", "11110", "10000", "10000", "11111"},
+	'F': {"11111", "10000", "10000", "11110", "10000", "10000", "10000"},
+	'G': {"01110", "10001", "10000", "10111", "10001", "10001", "01111"},
+	'H': {"10001", "10001", "10001", "11111", "10001", "10001", "10001"},
+	'I': {"01110", "00100", "00100", "00100", "00100", "00100", "01110"},
+	'J': {"00111", "00010", "00010", "00010", "00010", "10010", "01100"},
+	'K': {"10001", "10010", "10100", "11000", "10100", "10010", "10001"},
+	'L': {"10000", "10000", "10000", "10000", "10000", "10000", "11111"},
+	'M': {"10001", "11011", "10101", "10101", "10001", "10001", "10001"},
+	'N': {"10001", "10001", "11001", "10101", "10011", "10001", "10001"},
+	'O': {"01110", "10001", "10001", "10001", "10001", "10001", "01110"},
+	'P': {"11110", "10001", "10001", "11110", "10000", "10000", "10000"},
+	'Q': {"01110", "10001", "10001", "10001", "10101", "10010", "01101"},
+	'R': {"11110", "10001", "10001", "11110", "10100", "10010", "10001"},
+	'S': {"01111", "10000", "10000", "01110", "00001", "00001", "11110"},
+	'T': {"11111", "00100", "00100", "00100", "00100", "00100", "00100"},
+	'U': {"10001", "10001", "10001", "10001", "10001", "10001", "01110"},
+	'V': {"10001", "10001", "10001", "10001", "10001", "01010", "00100"},
+	'W': {"10001", "10001", "10001", "10101", "10101", "10101", "01010"},
+	'X': {"10001", "10001", "01010", "00100", "01010", "10001", "10001"},
+	'Y': {"10001", "10001", "10001", "01010", "00100", "00100", "00100"},
+	'Z': {"11111", "00001", "00010", "00100", "01000", "10000", "11111"},
+	'.': {"00000", "00000", "00000", "00000", "00000", "01100", "01100"},
+	',': {"00000", "00000", "00000", "00000", "01100", "00100", "01000"},
+	':': {"00000", "01100", "01100", "00000", "01100", "01100", "00000"},
+	'-': {"00000", "00000", "00000", "11111", "00000", "00000", "00000"},
+	'+': {"00000", "00100", "00100", "11111", "00100", "00100", "00000"},
+	'(': {"00010", "00100", "01000", "01000", "01000", "00100", "00010"},
+	')': {"01000", "00100", "00010", "00010", "00010", "00100", "01000"},
+	'/': {"00000", "00001", "00010", "00100", "01000", "10000", "00000"},
+	'%': {"11000", "11001", "00010", "00100", "01000", "10011", "00011"},
+	'°': {"01100", "10010", "10010", "01100", "00000", "00000", "00000"},
+}
@@ -0,0 +1,40 @@
+// Package render renders the HTML pages of windy from the embedded
+// templates, and the sparklines and arrows of the text views.
+package render
+
+import (
+	"embed"
+	"html/template"
+	"strings"
+	"time"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"chartJS": ChartJS,
+	"static":  StaticURL,
+	"t":       T,
+	"tf":      Tf,
+	"num":     Number,
+	"date":    dateFunc,
+}).ParseFS(templateFS, "templates/*.html"))
+
+// Render renders the named template, e.g. wind.html, with the data.
+func Render(name string, data any) (string, error) {
+	var b strings.Builder
+	if err := templates.ExecuteTemplate(&b, name, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// dateFunc formats an RFC 3339 time in the templates with Date.
+func dateFunc(lang, t, layout string) string {
+	parsed, err := time.Parse(time.RFC3339, t)
+	if err != nil {
+		return t
+	}
+	return Date(lang, parsed, layout)
+}
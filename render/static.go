@@ -0,0 +1,53 @@
+package render
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+)
+
+// staticFS holds the static files served under /static/, see
+// static/README.md. chart.js is named so the build fails when it hasn't
+// been fetched with make chartjs, rather than serving blank charts.
+//
+//go:embed static static/chart.js
+var staticFS embed.FS
+
+// ChartJSVersion is the pinned version of Chart.js, the templates use the
+// v4 API.
+const ChartJSVersion = "4.4.1"
+
+// Static returns the embedded static file with the name, e.g. chart.js.
+func Static(name string) ([]byte, error) {
+	return fs.ReadFile(staticFS, "static/"+name)
+}
+
+// StaticURL returns the URL of the static file with the name, versioned by
+// its content so it can be cached forever.
+func StaticURL(name string) string {
+	b, err := Static(name)
+	if err != nil {
+		return "/static/" + name
+	}
+	sum := sha256.Sum256(b)
+	return "/static/" + name + "?v=" + hex.EncodeToString(sum[:4])
+}
+
+// ChartJS returns the URL of the embedded Chart.js, the version in the
+// query lets it be cached forever.
+func ChartJS() string {
+	return "/static/chart.js?v=" + ChartJSVersion
+}
+
+// StaticNames returns the names of the embedded static files.
+func StaticNames() []string {
+	entries, _ := fs.ReadDir(staticFS, "static")
+	var names []string
+	for _, e := range entries {
+		if e.Name() != "README.md" {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
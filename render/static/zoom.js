@@ -0,0 +1,102 @@
+// Zoom and pan for the category x axis of a Chart.js 4 chart with unique
+// labels. The wheel and pinching zoom, dragging pans and windyZoom.range
+// shows a number of hours from the current one. The view is kept as the
+// min and max of the axis.
+var windyZoom = (function () {
+	function show(chart, min, max) {
+		var labels = chart.data.labels;
+		var n = labels.length;
+		var size = Math.max(2, Math.min(n, Math.round(max - min) + 1));
+		min = Math.max(0, Math.min(n - size, Math.round(min)));
+		var x = chart.options.scales.x;
+		x.min = labels[min];
+		x.max = labels[min + size - 1];
+		chart.update("none");
+	}
+
+	function current(chart) {
+		var scale = chart.scales.x;
+		return { min: scale.min, max: scale.max };
+	}
+
+	// zoom scales the view v by factor around the pixel x.
+	function zoom(chart, v, factor, x) {
+		var area = chart.chartArea;
+		var at = v.min + (v.max - v.min) * Math.max(0, Math.min(1, (x - area.left) / (area.right - area.left)));
+		show(chart, at - (at - v.min) * factor, at + (v.max - at) * factor);
+	}
+
+	// pan moves the view v by dx pixels.
+	function pan(chart, v, dx) {
+		var area = chart.chartArea;
+		var shift = -dx * (v.max - v.min + 1) / (area.right - area.left);
+		show(chart, v.min + shift, v.max + shift);
+	}
+
+	// range shows the hours from the index now, all with hours 0.
+	function range(chart, hours, now) {
+		var labels = chart.data.labels;
+		var x = chart.options.scales.x;
+		if (!hours) {
+			delete x.min;
+			delete x.max;
+			chart.update("none");
+			return;
+		}
+		var start = Math.max(0, now);
+		var end = Date.parse(labels[start]) + hours * 3600000;
+		var last = start;
+		while (last + 1 < labels.length && Date.parse(labels[last + 1]) < end) {
+			last++;
+		}
+		show(chart, start, last);
+	}
+
+	var plugin = {
+		id: "windyZoom",
+		afterInit: function (chart) {
+			var canvas = chart.canvas;
+			// The positions of the pointers and the view when the gesture
+			// started, it restarts when a finger is added or lifted.
+			var pointers = {};
+			var start = null;
+			var restart = function () {
+				start = { view: current(chart), pointers: Object.assign({}, pointers) };
+			};
+			canvas.style.touchAction = "pan-y";
+			canvas.addEventListener("wheel", function (e) {
+				e.preventDefault();
+				zoom(chart, current(chart), e.deltaY > 0 ? 1.25 : 0.8, e.offsetX);
+			}, { passive: false });
+			canvas.addEventListener("pointerdown", function (e) {
+				pointers[e.pointerId] = e.offsetX;
+				canvas.setPointerCapture(e.pointerId);
+				restart();
+			});
+			canvas.addEventListener("pointermove", function (e) {
+				if (!(e.pointerId in pointers)) {
+					return;
+				}
+				pointers[e.pointerId] = e.offsetX;
+				var ids = Object.keys(pointers);
+				if (ids.length == 1) {
+					pan(chart, start.view, e.offsetX - start.pointers[e.pointerId]);
+				} else if (ids.length == 2) {
+					var before = Math.abs(start.pointers[ids[0]] - start.pointers[ids[1]]);
+					var after = Math.abs(pointers[ids[0]] - pointers[ids[1]]);
+					if (before > 0 && after > 0) {
+						zoom(chart, start.view, before / after, (start.pointers[ids[0]] + start.pointers[ids[1]]) / 2);
+					}
+				}
+			});
+			var up = function (e) {
+				delete pointers[e.pointerId];
+				restart();
+			};
+			canvas.addEventListener("pointerup", up);
+			canvas.addEventListener("pointercancel", up);
+		}
+	};
+
+	return { plugin: plugin, range: range };
+})();
@@ -0,0 +1,66 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+var bars = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws the values as bars scaled from the minimum to the
+// maximum, one character each. NaN values are missing and drawn as spaces.
+func Sparkline(values []float64) string {
+	return ColorSparkline(values, nil)
+}
+
+// ColorSparkline is Sparkline with each bar in the ANSI 256 color returned
+// by color for the value, without colors if color is nil.
+func ColorSparkline(values []float64, color func(float64) int) string {
+	lo, hi := MinMax(values)
+	var b strings.Builder
+	for _, v := range values {
+		if math.IsNaN(v) {
+			b.WriteRune(' ')
+			continue
+		}
+		i := 0
+		if hi > lo {
+			i = int(math.Round((v - lo) / (hi - lo) * float64(len(bars)-1)))
+		}
+		if color != nil {
+			b.WriteString(Color(string(bars[i]), color(v)))
+		} else {
+			b.WriteRune(bars[i])
+		}
+	}
+	return b.String()
+}
+
+// Color wraps the text in the ANSI escape codes of the 256 color.
+func Color(text string, color int) string {
+	return fmt.Sprintf("\x1b[38;5;%dm%s\x1b[0m", color, text)
+}
+
+// MinMax returns the minimum and maximum of the values, ignoring NaN.
+func MinMax(values []float64) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	return lo, hi
+}
+
+// Compass returns the point of the compass the wind comes from, e.g. SW.
+func Compass(degrees float64) string {
+	points := []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+	return points[int(math.Round(degrees/45))%8]
+}
+
+// Arrow returns an arrow pointing where the wind blows.
+func Arrow(degrees float64) string {
+	arrows := []string{"↓", "↙", "←", "↖", "↑", "↗", "→", "↘"}
+	return arrows[int(math.Round(degrees/45))%8]
+}